@@ -11,9 +11,14 @@ import (
 	"admin-panel/internal/config"
 	"admin-panel/internal/database"
 	"admin-panel/internal/handlers"
+	"admin-panel/internal/mailer"
 	"admin-panel/internal/middleware"
+	"admin-panel/internal/ratelimit"
 	"admin-panel/internal/repository"
+	"admin-panel/internal/scheduler"
 	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+	"admin-panel/migrations"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -32,6 +37,13 @@ func main() {
 
 	logger.Info().Str("environment", cfg.App.Environment).Msg("Starting admin panel server")
 
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("Invalid configuration")
+	}
+	if err := services.ConfigurePasswordHasher(cfg.Password); err != nil {
+		logger.Fatal().Err(err).Msg("Invalid password hashing configuration")
+	}
+
 	db, err := database.NewPostgresPool(&cfg.Database)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to connect to database")
@@ -40,28 +52,131 @@ func main() {
 
 	logger.Info().Msg("Connected to database")
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrate(db, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
+	if cfg.Database.AutoMigrate {
+		migrator, err := database.NewMigrator(db, migrations.FS)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to load migrations")
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to apply migrations")
+		}
+		for _, m := range applied {
+			logger.Info().Int64("version", m.Version).Str("name", m.Name).Msg("Applied migration")
+		}
+	}
+
 	userRepo := repository.NewUserRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	auditRepo := repository.NewAuditLogRepository(db)
 	adminAuthRepo := repository.NewAdminAuthRepository(db)
 	featureFlagRepo := repository.NewFeatureFlagRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	tenantRepo := repository.NewTenantRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	webauthnRepo := repository.NewWebAuthnRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	rateLimitRepo := repository.NewRateLimitRepository(db)
+	jobRunRepo := repository.NewJobRunRepository(db)
+	auditArchiveRepo := repository.NewAuditArchiveRepository(db)
+	auditCheckpointRepo := repository.NewAuditCheckpointRepository(db)
+	auditExportRepo := repository.NewAuditExportRepository(db)
+	auditSinkRepo := repository.NewAuditSinkRepository(db)
+	auditOutboxRepo := repository.NewAuditOutboxRepository(db)
+
+	mailSender, err := mailer.New(cfg.Mail, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to configure mailer")
+	}
+
+	var limiter ratelimit.Limiter
+	if cfg.RateLimit.Backend == "postgres" {
+		pgLimiter := ratelimit.NewPostgresLimiter(rateLimitRepo, cfg.RateLimit.CleanupInterval, logger)
+		defer pgLimiter.Close()
+		limiter = pgLimiter
+	} else {
+		memLimiter, err := ratelimit.NewMemoryLimiter(cfg.RateLimit.Algorithm, cfg.RateLimit.CleanupInterval)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to configure rate limiter")
+		}
+		defer memLimiter.Close()
+		limiter = memLimiter
+	}
+	authLimit := ratelimit.Limit{Requests: cfg.RateLimit.AuthRequests, Window: cfg.RateLimit.AuthWindow}
+	apiLimit := ratelimit.Limit{Requests: cfg.RateLimit.APIRequests, Window: cfg.RateLimit.APIWindow}
+
+	checkpointKey, err := services.AuditCheckpointKey(cfg.Audit, cfg.JWT.Secret)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to load audit checkpoint key")
+	}
 
-	authService := services.NewAuthService(userRepo, roleRepo, sessionRepo, auditRepo, cfg.JWT, logger)
-	userService := services.NewUserService(userRepo, roleRepo, auditRepo)
-	roleService := services.NewRoleService(roleRepo, auditRepo)
+	auditRecorder := services.NewAuditRecorder(auditRepo, logger)
+	settingsService := services.NewSettingsService(settingsRepo, auditRecorder)
+	tenantService := services.NewTenantService(tenantRepo, roleRepo, auditRecorder)
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, userRepo, auditRecorder, settingsService)
+	webauthnService := services.NewWebAuthnService(webauthnRepo, userRepo, auditRecorder, cfg.WebAuthn)
+	passwordPolicyService := services.NewPasswordPolicyService(settingsService, passwordHistoryRepo, userRepo, utils.NewBreachedPasswords(cfg.Password.BreachedListDir), logger)
+	authService := services.NewAuthService(userRepo, roleRepo, sessionRepo, auditRecorder, adminAuthRepo, settingsService, tenantService, twoFactorService, webauthnService, passwordPolicyService, limiter, cfg.JWT, cfg.Login, logger)
+	passwordResetService := services.NewPasswordResetService(passwordResetRepo, userRepo, sessionRepo, auditRecorder, tenantService, settingsService, passwordPolicyService, mailSender, cfg.Reset, logger)
+	userService := services.NewUserService(userRepo, roleRepo, auditRecorder, passwordPolicyService)
+	invitationService := services.NewInvitationService(invitationRepo, userRepo, auditRecorder, userService, tenantService, settingsService, passwordPolicyService, mailSender, cfg.Invite, cfg.JWT, logger)
+	roleService := services.NewRoleService(roleRepo, auditRecorder)
 	auditService := services.NewAuditService(auditRepo)
 	dashboardService := services.NewDashboardService(userRepo, roleRepo, auditRepo)
+	sessionService := services.NewSessionService(sessionRepo, userRepo, auditRecorder)
+	auditRetentionService := services.NewAuditRetentionService(auditRepo, auditRecorder, auditArchiveRepo, tenantRepo, settingsService, cfg.Audit, logger)
+	auditChainService := services.NewAuditChainService(auditRepo, auditCheckpointRepo, auditArchiveRepo, tenantRepo, checkpointKey, logger)
+	auditExportService := services.NewAuditExportService(auditRepo, auditExportRepo, auditRecorder, cfg.Audit, logger)
+	auditSinkService := services.NewAuditSinkService(auditSinkRepo, auditOutboxRepo, auditRecorder)
+	auditForwardingService := services.NewAuditForwardingService(auditSinkRepo, auditOutboxRepo, cfg.Audit, logger)
+	maintenanceService := services.NewMaintenanceService(sessionRepo, webauthnRepo, passwordResetRepo, auditRetentionService, auditChainService, auditExportService, auditForwardingService, cfg.Scheduler)
+
+	jobScheduler := scheduler.New(jobRunRepo, cfg.Scheduler.JobTimeout, cfg.Scheduler.HistoryRetention, logger)
+	for _, job := range maintenanceService.Jobs() {
+		if err := jobScheduler.Register(job); err != nil {
+			logger.Fatal().Err(err).Msg("Invalid job schedule")
+		}
+	}
+
+	if cfg.App.DemoMode {
+		demoService := services.NewDemoService(tenantService, userService, userRepo, roleRepo)
+		tenant, user, err := demoService.Seed(context.Background(), cfg.Demo)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to seed demo data")
+		}
+		logger.Warn().Str("tenant", tenant.Slug).Str("email", user.Email).Msg("Demo mode enabled")
+	}
 
 	validate := validator.New()
 
 	authHandler := handlers.NewAuthHandler(authService, validate)
-	userHandler := handlers.NewUserHandler(userService, validate)
+	userHandler := handlers.NewUserHandler(userService, invitationService, validate)
 	roleHandler := handlers.NewRoleHandler(roleService, validate)
 	auditHandler := handlers.NewAuditHandler(auditService)
+	auditArchiveHandler := handlers.NewAuditArchiveHandler(auditRetentionService)
+	auditChainHandler := handlers.NewAuditChainHandler(auditChainService)
+	auditExportHandler := handlers.NewAuditExportHandler(auditExportService, jobScheduler)
+	auditSinkHandler := handlers.NewAuditSinkHandler(auditSinkService, validate)
 	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
-	adminHandler := handlers.NewAdminHandler(adminAuthRepo, auditRepo, validate)
-	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagRepo, auditRepo, validate)
+	adminHandler := handlers.NewAdminHandler(adminAuthRepo, userRepo, auditRecorder, validate)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagRepo, auditRecorder, validate)
+	settingsHandler := handlers.NewSettingsHandler(settingsService, validate)
+	tenantHandler := handlers.NewTenantHandler(tenantService, validate)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, validate)
+	webauthnHandler := handlers.NewWebAuthnHandler(authService, webauthnService, validate)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService, validate)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, validate)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	jobHandler := handlers.NewJobHandler(jobScheduler)
 
 	authMiddleware := middleware.NewAuthMiddleware(authService, logger)
 
@@ -75,8 +190,8 @@ func main() {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.Server.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID", "Set-Cookie"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "X-Tenant"},
+		ExposedHeaders:   []string{"X-Request-ID", "Set-Cookie", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -89,19 +204,59 @@ func main() {
 
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
-			r.Use(middleware.RateLimiter(10, time.Minute))
-			r.With(middleware.LoginRateLimiter(5, 5*time.Minute, 15*time.Minute)).Post("/login", authHandler.Login)
+			if authLimit.Requests > 0 {
+				r.Use(middleware.RateLimiter("auth", limiter, authLimit, middleware.KeyByIP, logger))
+			}
+			r.Use(middleware.TenantResolver(cfg.Server.TenantBaseDomain, cfg.Server.DefaultTenantSlug))
+			r.Post("/login", authHandler.Login)
+			r.Post("/login/2fa", authHandler.LoginTwoFactor)
+			r.Post("/webauthn/login/begin", webauthnHandler.BeginLogin)
+			r.Post("/webauthn/login/finish", webauthnHandler.FinishLogin)
+			r.Post("/webauthn/passwordless/begin", webauthnHandler.BeginPasswordless)
+			r.Post("/webauthn/passwordless/finish", webauthnHandler.FinishPasswordless)
 			r.Post("/refresh", authHandler.RefreshToken)
+			r.Post("/password/forgot", passwordResetHandler.Forgot)
+			r.Post("/password/reset", passwordResetHandler.Reset)
+			r.Post("/password/expired", authHandler.ChangeExpiredPassword)
+			r.Post("/invitations/accept", invitationHandler.Accept)
 
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.Authenticate)
 				r.Post("/logout", authHandler.Logout)
+				r.Post("/step-up", authHandler.StepUp)
+				r.Post("/reauthenticate", authHandler.Reauthenticate)
+				r.Post("/password/change", authHandler.ChangePassword)
+				r.Get("/password/policy", authHandler.PasswordPolicy)
 				r.Get("/me", authHandler.Me)
+
+				r.Route("/sessions", func(r chi.Router) {
+					r.Get("/", sessionHandler.List)
+					r.Post("/revoke-others", sessionHandler.RevokeOthers)
+					r.Delete("/{sessionId}", sessionHandler.Revoke)
+				})
+
+				r.Route("/2fa", func(r chi.Router) {
+					r.Get("/", twoFactorHandler.Status)
+					r.Post("/enroll", twoFactorHandler.Enroll)
+					r.Post("/confirm", twoFactorHandler.Confirm)
+					r.Post("/disable", twoFactorHandler.Disable)
+					r.Post("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+				})
+
+				r.Route("/webauthn", func(r chi.Router) {
+					r.With(authMiddleware.RequireRecentAuth).Post("/register/begin", webauthnHandler.BeginRegistration)
+					r.With(authMiddleware.RequireRecentAuth).Post("/register/finish", webauthnHandler.FinishRegistration)
+					r.Get("/credentials", webauthnHandler.ListCredentials)
+					r.Delete("/credentials/{credentialId}", webauthnHandler.RevokeCredential)
+				})
 			})
 		})
 
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
+			if apiLimit.Requests > 0 {
+				r.Use(middleware.RateLimiter("api", limiter, apiLimit, middleware.KeyByUser, logger))
+			}
 
 			r.Route("/dashboard", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("dashboard", "read")).Get("/stats", dashboardHandler.GetStats)
@@ -109,14 +264,25 @@ func main() {
 
 			r.Route("/users", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("users", "read")).Get("/", userHandler.List)
+				r.With(authMiddleware.RequirePermission("users", "read")).Get("/password-hashes", userHandler.PasswordHashReport)
 				r.With(authMiddleware.RequirePermission("users", "create")).Post("/", userHandler.Create)
 				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}", userHandler.Get)
 				r.With(authMiddleware.RequirePermission("users", "update")).Put("/{id}", userHandler.Update)
 				r.With(authMiddleware.RequirePermission("users", "delete")).Delete("/{id}", userHandler.Delete)
 				r.With(authMiddleware.RequirePermission("users", "update")).Post("/{id}/reset-password", userHandler.ResetPassword)
+				r.With(authMiddleware.RequirePermission("users", "update")).Post("/{id}/unlock", userHandler.Unlock)
+				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}/sessions", sessionHandler.ListForUser)
+				r.With(authMiddleware.RequirePermission("users", "update")).Delete("/{id}/sessions", sessionHandler.RevokeAllForUser)
+				r.With(authMiddleware.RequirePermission("users", "update")).Delete("/{id}/sessions/{sessionId}", sessionHandler.RevokeForUser)
 				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}/roles", userHandler.GetRoles)
-				r.With(authMiddleware.RequirePermission("admin", "manage")).Post("/{id}/set-admin", adminHandler.SetAdmin)
+				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}/invitation", invitationHandler.Get)
+				r.With(authMiddleware.RequirePermission("users", "create")).Post("/{id}/invitation/resend", invitationHandler.Resend)
+				r.With(authMiddleware.RequirePermission("users", "create")).Delete("/{id}/invitation", invitationHandler.Revoke)
+				r.With(authMiddleware.RequirePermission("admin", "manage"), authMiddleware.RequireElevation).Post("/{id}/set-admin", adminHandler.SetAdmin)
 				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}/admin-status", adminHandler.GetAdminStatus)
+				r.With(authMiddleware.RequirePermission("users", "update"), authMiddleware.RequireElevation).Post("/{id}/reset-2fa", twoFactorHandler.Reset)
+				r.With(authMiddleware.RequirePermission("users", "read")).Get("/{id}/webauthn-credentials", webauthnHandler.ListUserCredentials)
+				r.With(authMiddleware.RequirePermission("users", "update"), authMiddleware.RequireElevation).Delete("/{id}/webauthn-credentials/{credentialId}", webauthnHandler.RevokeUserCredential)
 			})
 
 			r.Route("/feature-flags", func(r chi.Router) {
@@ -128,12 +294,19 @@ func main() {
 				r.With(authMiddleware.RequirePermission("feature_flags", "update")).Post("/{id}/toggle", featureFlagHandler.Toggle)
 			})
 
+			r.Route("/settings", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("settings", "read")).Get("/", settingsHandler.List)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Put("/", settingsHandler.BulkUpdate)
+				r.With(authMiddleware.RequirePermission("settings", "read")).Get("/{key}", settingsHandler.Get)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Put("/{key}", settingsHandler.Put)
+			})
+
 			r.Route("/roles", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("roles", "read")).Get("/", roleHandler.List)
 				r.With(authMiddleware.RequirePermission("roles", "create")).Post("/", roleHandler.Create)
 				r.With(authMiddleware.RequirePermission("roles", "read")).Get("/{id}", roleHandler.Get)
 				r.With(authMiddleware.RequirePermission("roles", "update")).Put("/{id}", roleHandler.Update)
-				r.With(authMiddleware.RequirePermission("roles", "delete")).Delete("/{id}", roleHandler.Delete)
+				r.With(authMiddleware.RequirePermission("roles", "delete"), authMiddleware.RequireElevation).Delete("/{id}", roleHandler.Delete)
 				r.With(authMiddleware.RequirePermission("roles", "read")).Get("/{id}/permissions", roleHandler.GetPermissions)
 			})
 
@@ -141,9 +314,45 @@ func main() {
 				r.With(authMiddleware.RequirePermission("roles", "read")).Get("/", roleHandler.GetAllPermissions)
 			})
 
+			r.Route("/tenants", func(r chi.Router) {
+				r.Use(authMiddleware.RequirePlatformAdmin("tenants", "manage"))
+				r.Get("/", tenantHandler.List)
+				r.Post("/", tenantHandler.Create)
+				r.Get("/{id}", tenantHandler.Get)
+				r.Put("/{id}", tenantHandler.Update)
+				r.Post("/{id}/suspend", tenantHandler.Suspend)
+				r.Post("/{id}/activate", tenantHandler.Activate)
+			})
+
+			r.Route("/jobs", func(r chi.Router) {
+				r.Use(authMiddleware.RequirePlatformAdmin("jobs", "manage"))
+				r.Get("/", jobHandler.List)
+				r.Get("/{name}/runs", jobHandler.Runs)
+				r.Post("/{name}/run", jobHandler.Run)
+			})
+
 			r.Route("/audit-logs", func(r chi.Router) {
 				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/", auditHandler.List)
-				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/export", auditHandler.Export)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/export", auditExportHandler.Export)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Post("/exports", auditExportHandler.Create)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/exports", auditExportHandler.List)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/exports/{id}", auditExportHandler.Get)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/exports/{id}/download", auditExportHandler.Download)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/retention", auditArchiveHandler.Retention)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/archives", auditArchiveHandler.List)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/archives/{id}/download", auditArchiveHandler.Download)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/verify", auditChainHandler.Verify)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/checkpoints", auditChainHandler.Checkpoints)
+				r.With(authMiddleware.RequirePermission("audit_logs", "read")).Get("/{id}", auditHandler.Get)
+			})
+
+			r.Route("/audit-sinks", func(r chi.Router) {
+				r.With(authMiddleware.RequirePermission("settings", "read")).Get("/", auditSinkHandler.List)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Post("/", auditSinkHandler.Create)
+				r.With(authMiddleware.RequirePermission("settings", "read")).Get("/{id}", auditSinkHandler.Get)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Put("/{id}", auditSinkHandler.Update)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Delete("/{id}", auditSinkHandler.Delete)
+				r.With(authMiddleware.RequirePermission("settings", "update"), authMiddleware.RequireElevation).Post("/{id}/retry", auditSinkHandler.Retry)
 			})
 		})
 	})
@@ -163,6 +372,14 @@ func main() {
 		}
 	}()
 
+	if cfg.Scheduler.Enabled {
+		jobScheduler.Start()
+	}
+	if cfg.Audit.RetentionDays > 0 {
+		logger.Warn().Int("days", cfg.Audit.RetentionDays).Bool("scheduler_enabled", cfg.Scheduler.Enabled).
+			Msg("Audit log retention is enabled: entries older than the retention period are archived and deleted")
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -176,5 +393,7 @@ func main() {
 		logger.Fatal().Err(err).Msg("Server forced to shutdown")
 	}
 
+	jobScheduler.Stop()
+
 	logger.Info().Msg("Server exited properly")
 }
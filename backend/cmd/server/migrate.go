@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"admin-panel/internal/database"
+	"admin-panel/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up                 apply all pending migrations (default)
+  down [steps]       revert the last applied migration, or the last <steps>
+  status             list migrations and when they were applied
+  baseline <version> record migrations up to <version> as applied without running them`
+
+// runMigrate implements the migrate subcommand and returns the process exit
+// code.
+func runMigrate(db *pgxpool.Pool, args []string) int {
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "steps must be a positive integer")
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%03d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	case "baseline":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "version must be an integer")
+			return 2
+		}
+		marked, err := migrator.Baseline(ctx, version)
+		for _, m := range marked {
+			fmt.Printf("marked %03d_%s as applied\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
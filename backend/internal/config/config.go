@@ -1,6 +1,8 @@
 package config
 
 import (
+        "encoding/base64"
+        "errors"
         "log"
         "os"
         "strconv"
@@ -9,18 +11,30 @@ import (
 )
 
 type Config struct {
-        Server   ServerConfig
-        Database DatabaseConfig
-        JWT      JWTConfig
-        App      AppConfig
+        Server    ServerConfig
+        Database  DatabaseConfig
+        JWT       JWTConfig
+        Login     LoginConfig
+        App       AppConfig
+        Demo      DemoConfig
+        WebAuthn  WebAuthnConfig
+        Mail      MailConfig
+        Reset     PasswordResetConfig
+        Invite    InvitationConfig
+        Password  PasswordConfig
+        RateLimit RateLimitConfig
+        Scheduler SchedulerConfig
+        Audit     AuditConfig
 }
 
 type ServerConfig struct {
-        Port           string
-        ReadTimeout    time.Duration
-        WriteTimeout   time.Duration
-        IdleTimeout    time.Duration
-        AllowedOrigins []string
+        Port              string
+        ReadTimeout       time.Duration
+        WriteTimeout      time.Duration
+        IdleTimeout       time.Duration
+        AllowedOrigins    []string
+        TenantBaseDomain  string
+        DefaultTenantSlug string
 }
 
 type DatabaseConfig struct {
@@ -29,17 +43,163 @@ type DatabaseConfig struct {
         MinConns        int32
         MaxConnLifetime time.Duration
         MaxConnIdleTime time.Duration
+        AutoMigrate     bool
 }
 
 type JWTConfig struct {
         Secret          string
         AccessTokenTTL  time.Duration
         RefreshTokenTTL time.Duration
+        StepUpTTL       time.Duration
+}
+
+// LoginConfig holds the process-wide login lockout defaults. Tenants may
+// override MaxAttempts and Lockout at runtime through their settings.
+// Repeated lockouts double in length up to MaxLockout.
+type LoginConfig struct {
+        MaxAttempts int
+        Window      time.Duration
+        Lockout     time.Duration
+        MaxLockout  time.Duration
 }
 
 type AppConfig struct {
         Environment string
         LogLevel    string
+        DemoMode    bool
+}
+
+// DemoConfig describes the tenant and account seeded when APP_DEMO_MODE is
+// enabled.
+type DemoConfig struct {
+        TenantSlug string
+        TenantName string
+        Email      string
+        Password   string
+}
+
+// WebAuthnConfig identifies this server as a WebAuthn relying party. RPID
+// must be the site's registrable domain and Origins the exact origins the
+// frontend is served from.
+type WebAuthnConfig struct {
+        RPID    string
+        RPName  string
+        Origins []string
+        Timeout time.Duration
+}
+
+// MailConfig selects how outgoing mail is delivered. Driver is "smtp",
+// "file" (one .eml file per message in FileDir) or "log". Both file and log
+// keep reset and invitation links readable on the server, so production
+// requires smtp, which is also its default there.
+type MailConfig struct {
+        Driver       string
+        From         string
+        SMTPHost     string
+        SMTPPort     int
+        SMTPUsername string
+        SMTPPassword string
+        FileDir      string
+}
+
+// PasswordResetConfig controls self-service password reset. URL is the
+// frontend page that receives the token as a "token" query parameter.
+type PasswordResetConfig struct {
+        URL      string
+        TokenTTL time.Duration
+}
+
+// InvitationConfig controls user invitations. URL is the frontend page that
+// receives the invitation token as a "token" query parameter.
+type InvitationConfig struct {
+        URL      string
+        TokenTTL time.Duration
+}
+
+// PasswordConfig holds process-wide password settings. Tenants configure
+// their password policy through settings. BreachedListDir points at a local
+// k-anonymity range corpus of breached password hashes; the breached check
+// is skipped when it is empty. HashAlgorithm ("argon2id" or "bcrypt") and
+// the cost parameters apply to newly hashed passwords; older hashes are
+// upgraded when their owner next signs in.
+type PasswordConfig struct {
+        BreachedListDir   string
+        HashAlgorithm     string
+        BcryptCost        int
+        Argon2Memory      int
+        Argon2Iterations  int
+        Argon2Parallelism int
+}
+
+// RateLimitConfig selects the rate limiter backend. "memory" keeps counters
+// per process; "postgres" shares them between replicas and always uses the
+// sliding window algorithm. Auth limits each client IP on the /auth routes
+// and API each signed-in user on the rest of the API; a zero request count
+// disables a limit.
+type RateLimitConfig struct {
+        Backend         string
+        Algorithm       string
+        CleanupInterval time.Duration
+        AuthRequests    int
+        AuthWindow      time.Duration
+        APIRequests     int
+        APIWindow       time.Duration
+}
+
+// SchedulerConfig controls the background maintenance jobs. Schedules are
+// five-field cron expressions evaluated in UTC, shorthands such as @daily,
+// or "@every <duration>". Refresh tokens that were rotated or revoked more
+// than RotatedSessionRetention ago are deleted, after which presenting one
+// is no longer reported as token reuse. The token purge deletes expired
+// single-use login state: WebAuthn ceremonies and password reset tokens.
+type SchedulerConfig struct {
+        Enabled                 bool
+        JobTimeout              time.Duration
+        HistoryRetention        time.Duration
+        SessionPurgeSchedule    string
+        TokenPurgeSchedule      string
+        RotatedSessionSchedule  string
+        RotatedSessionRetention time.Duration
+        AuditRetentionSchedule  string
+        AuditCheckpointSchedule string
+        AuditExportSchedule     string
+        AuditForwardSchedule    string
+}
+
+// AuditConfig controls audit log retention. Entries older than a tenant's
+// retention period are archived to compressed JSONL files in ArchiveDir,
+// at most ArchiveBatchSize entries per file, and then deleted.
+// RetentionDays is the default for tenants without their own setting; zero,
+// the default, keeps entries forever, so deleting audit history is opt-in.
+// ArchiveDir must be shared between replicas so any of them can serve
+// downloads.
+//
+// CheckpointKey is the base64-encoded 32-byte Ed25519 seed audit chain
+// checkpoints are signed with. When it is empty a key is derived from
+// SESSION_SECRET, so changing that secret invalidates earlier checkpoints.
+//
+// Background exports are written to ExportDir, which must be shared between
+// replicas like ArchiveDir, and deleted ExportTTL after they complete. An
+// export still running after ExportTimeout is failed.
+//
+// Entries are forwarded to each tenant's sinks by the audit forwarding job.
+// A delivery that fails is retried after ForwardRetryBase, doubling up to
+// ForwardRetryMax, and given up after ForwardMaxAttempts; each delivery may
+// take up to ForwardTimeout. File sinks write below ForwardFileDir.
+type AuditConfig struct {
+        RetentionDays    int
+        ArchiveDir       string
+        ArchiveBatchSize int
+        CheckpointKey    string
+        ExportDir        string
+        ExportTTL        time.Duration
+        ExportTimeout    time.Duration
+
+        ForwardFileDir     string
+        ForwardTimeout     time.Duration
+        ForwardMaxAttempts int
+        ForwardRetryBase   time.Duration
+        ForwardRetryMax    time.Duration
 }
 
 func Load() *Config {
@@ -51,11 +211,13 @@ func Load() *Config {
         
         return &Config{
                 Server: ServerConfig{
-                        Port:           getEnv("PORT", "8080"),
-                        ReadTimeout:    getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
-                        WriteTimeout:   getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
-                        IdleTimeout:    getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
-                        AllowedOrigins: allowedOrigins,
+                        Port:              getEnv("PORT", "8080"),
+                        ReadTimeout:       getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
+                        WriteTimeout:      getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+                        IdleTimeout:       getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+                        AllowedOrigins:    allowedOrigins,
+                        TenantBaseDomain:  getEnv("TENANT_BASE_DOMAIN", ""),
+                        DefaultTenantSlug: getEnv("DEFAULT_TENANT_SLUG", "default"),
                 },
                 Database: DatabaseConfig{
                         URL:             getEnv("DATABASE_URL", ""),
@@ -63,17 +225,170 @@ func Load() *Config {
                         MinConns:        int32(getIntEnv("DB_MIN_CONNS", 10)),
                         MaxConnLifetime: getDurationEnv("DB_MAX_CONN_LIFETIME", 30*time.Minute),
                         MaxConnIdleTime: getDurationEnv("DB_MAX_CONN_IDLE_TIME", 10*time.Minute),
+                        AutoMigrate:     getBoolEnv("DB_AUTO_MIGRATE", false),
                 },
                 JWT: JWTConfig{
                         Secret:          getEnv("SESSION_SECRET", "default-secret-change-me"),
                         AccessTokenTTL:  getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute),
                         RefreshTokenTTL: getDurationEnv("JWT_REFRESH_TTL", 7*24*time.Hour),
+                        StepUpTTL:       getDurationEnv("JWT_STEP_UP_TTL", 5*time.Minute),
+                },
+                Login: LoginConfig{
+                        MaxAttempts: getIntEnv("LOGIN_MAX_ATTEMPTS", 5),
+                        Window:      getDurationEnv("LOGIN_ATTEMPT_WINDOW", 5*time.Minute),
+                        Lockout:     getDurationEnv("LOGIN_LOCKOUT", 15*time.Minute),
+                        MaxLockout:  getDurationEnv("LOGIN_MAX_LOCKOUT", 24*time.Hour),
                 },
                 App: AppConfig{
                         Environment: getEnv("APP_ENV", "development"),
                         LogLevel:    getEnv("LOG_LEVEL", "debug"),
+                        DemoMode:    getBoolEnv("APP_DEMO_MODE", false),
+                },
+                Demo: DemoConfig{
+                        TenantSlug: getEnv("DEMO_TENANT_SLUG", "demo"),
+                        TenantName: getEnv("DEMO_TENANT_NAME", "Demo Organization"),
+                        Email:      getEnv("DEMO_EMAIL", "admin@example.com"),
+                        Password:   getEnv("DEMO_PASSWORD", "password123"),
+                },
+                WebAuthn: WebAuthnConfig{
+                        RPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+                        RPName:  getEnv("WEBAUTHN_RP_NAME", "Admin Panel"),
+                        Origins: getStringSliceEnv("WEBAUTHN_ORIGINS", allowedOrigins),
+                        Timeout: getDurationEnv("WEBAUTHN_TIMEOUT", 5*time.Minute),
+                },
+                Mail: MailConfig{
+                        Driver:       getEnv("MAIL_DRIVER", defaultMailDriver()),
+                        From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+                        SMTPHost:     getEnv("SMTP_HOST", ""),
+                        SMTPPort:     getIntEnv("SMTP_PORT", 587),
+                        SMTPUsername: getEnv("SMTP_USERNAME", ""),
+                        SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+                        FileDir:      getEnv("MAIL_FILE_DIR", "mail"),
+                },
+                Reset: PasswordResetConfig{
+                        URL:      getEnv("PASSWORD_RESET_URL", allowedOrigins[0]+"/reset-password"),
+                        TokenTTL: getDurationEnv("PASSWORD_RESET_TTL", time.Hour),
+                },
+                Invite: InvitationConfig{
+                        URL:      getEnv("INVITE_URL", allowedOrigins[0]+"/accept-invite"),
+                        TokenTTL: getDurationEnv("INVITE_TTL", 72*time.Hour),
+                },
+                Password: PasswordConfig{
+                        BreachedListDir:   getEnv("BREACHED_PASSWORDS_DIR", ""),
+                        HashAlgorithm:     getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+                        BcryptCost:        getIntEnv("PASSWORD_BCRYPT_COST", 12),
+                        Argon2Memory:      getIntEnv("PASSWORD_ARGON2_MEMORY", 64*1024),
+                        Argon2Iterations:  getIntEnv("PASSWORD_ARGON2_ITERATIONS", 3),
+                        Argon2Parallelism: getIntEnv("PASSWORD_ARGON2_PARALLELISM", 2),
+                },
+                RateLimit: RateLimitConfig{
+                        Backend:         getEnv("RATE_LIMIT_BACKEND", "memory"),
+                        Algorithm:       getEnv("RATE_LIMIT_ALGORITHM", "sliding_window"),
+                        CleanupInterval: getDurationEnv("RATE_LIMIT_CLEANUP_INTERVAL", time.Minute),
+                        AuthRequests:    getIntEnv("RATE_LIMIT_AUTH_REQUESTS", 10),
+                        AuthWindow:      getDurationEnv("RATE_LIMIT_AUTH_WINDOW", time.Minute),
+                        APIRequests:     getIntEnv("RATE_LIMIT_API_REQUESTS", 300),
+                        APIWindow:       getDurationEnv("RATE_LIMIT_API_WINDOW", time.Minute),
+                },
+                Scheduler: SchedulerConfig{
+                        Enabled:                 getBoolEnv("SCHEDULER_ENABLED", true),
+                        JobTimeout:              getDurationEnv("SCHEDULER_JOB_TIMEOUT", 10*time.Minute),
+                        HistoryRetention:        getDurationEnv("SCHEDULER_HISTORY_RETENTION", 30*24*time.Hour),
+                        SessionPurgeSchedule:    getEnv("JOB_SESSION_PURGE_SCHEDULE", "*/15 * * * *"),
+                        TokenPurgeSchedule:      getEnv("JOB_TOKEN_PURGE_SCHEDULE", "10,40 * * * *"),
+                        RotatedSessionSchedule:  getEnv("JOB_ROTATED_SESSION_CLEANUP_SCHEDULE", "5 * * * *"),
+                        RotatedSessionRetention: getDurationEnv("SESSION_ROTATED_RETENTION", 24*time.Hour),
+                        AuditRetentionSchedule:  getEnv("JOB_AUDIT_RETENTION_SCHEDULE", "30 3 * * *"),
+                        AuditCheckpointSchedule: getEnv("JOB_AUDIT_CHECKPOINT_SCHEDULE", "0 * * * *"),
+                        AuditExportSchedule:     getEnv("JOB_AUDIT_EXPORT_SCHEDULE", "@every 1m"),
+                        AuditForwardSchedule:    getEnv("JOB_AUDIT_FORWARD_SCHEDULE", "@every 30s"),
                 },
+                Audit: AuditConfig{
+                        RetentionDays:    getIntEnv("AUDIT_RETENTION_DAYS", 0),
+                        ArchiveDir:       getEnv("AUDIT_ARCHIVE_DIR", "audit-archives"),
+                        ArchiveBatchSize: getIntEnv("AUDIT_ARCHIVE_BATCH_SIZE", 100000),
+                        CheckpointKey:    getEnv("AUDIT_CHECKPOINT_KEY", ""),
+                        ExportDir:        getEnv("AUDIT_EXPORT_DIR", "audit-exports"),
+                        ExportTTL:        getDurationEnv("AUDIT_EXPORT_TTL", 24*time.Hour),
+                        ExportTimeout:    getDurationEnv("AUDIT_EXPORT_TIMEOUT", time.Hour),
+
+                        ForwardFileDir:     getEnv("AUDIT_FORWARD_FILE_DIR", "audit-forward"),
+                        ForwardTimeout:     getDurationEnv("AUDIT_FORWARD_TIMEOUT", 10*time.Second),
+                        ForwardMaxAttempts: getIntEnv("AUDIT_FORWARD_MAX_ATTEMPTS", 15),
+                        ForwardRetryBase:   getDurationEnv("AUDIT_FORWARD_RETRY_BASE", 30*time.Second),
+                        ForwardRetryMax:    getDurationEnv("AUDIT_FORWARD_RETRY_MAX", time.Hour),
+                },
+        }
+}
+
+// defaultMailDriver delivers mail over SMTP in production and to the log
+// everywhere else.
+func defaultMailDriver() string {
+        if getEnv("APP_ENV", "development") == "production" {
+                return "smtp"
         }
+        return "log"
+}
+
+// Validate rejects configurations the server must refuse to start with.
+func (c *Config) Validate() error {
+        if c.App.DemoMode && c.App.Environment == "production" {
+                return errors.New("APP_DEMO_MODE must not be enabled when APP_ENV=production")
+        }
+        switch c.Mail.Driver {
+        case "smtp":
+                if c.Mail.SMTPHost == "" {
+                        return errors.New("SMTP_HOST is required when MAIL_DRIVER=smtp")
+                }
+        case "file", "log":
+                if c.App.Environment == "production" {
+                        return errors.New("MAIL_DRIVER must be smtp when APP_ENV=production")
+                }
+        default:
+                return errors.New("MAIL_DRIVER must be one of smtp, file or log")
+        }
+        switch c.Password.HashAlgorithm {
+        case "argon2id", "bcrypt":
+        default:
+                return errors.New("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt")
+        }
+        switch c.RateLimit.Backend {
+        case "memory", "postgres":
+        default:
+                return errors.New("RATE_LIMIT_BACKEND must be memory or postgres")
+        }
+        switch c.RateLimit.Algorithm {
+        case "sliding_window", "token_bucket":
+        default:
+                return errors.New("RATE_LIMIT_ALGORITHM must be sliding_window or token_bucket")
+        }
+        if c.RateLimit.AuthWindow <= 0 || c.RateLimit.APIWindow <= 0 || c.RateLimit.CleanupInterval <= 0 {
+                return errors.New("RATE_LIMIT_AUTH_WINDOW, RATE_LIMIT_API_WINDOW and RATE_LIMIT_CLEANUP_INTERVAL must be positive")
+        }
+        if c.Scheduler.JobTimeout <= 0 || c.Scheduler.RotatedSessionRetention <= 0 || c.Scheduler.HistoryRetention < 0 {
+                return errors.New("SCHEDULER_JOB_TIMEOUT and SESSION_ROTATED_RETENTION must be positive and SCHEDULER_HISTORY_RETENTION must not be negative")
+        }
+        if c.Audit.RetentionDays < 0 {
+                return errors.New("AUDIT_RETENTION_DAYS must not be negative")
+        }
+        if c.Audit.ArchiveBatchSize <= 0 {
+                return errors.New("AUDIT_ARCHIVE_BATCH_SIZE must be positive")
+        }
+        if c.Audit.ExportTTL <= 0 || c.Audit.ExportTimeout <= 0 {
+                return errors.New("AUDIT_EXPORT_TTL and AUDIT_EXPORT_TIMEOUT must be positive")
+        }
+        if c.Audit.ForwardTimeout <= 0 || c.Audit.ForwardMaxAttempts <= 0 || c.Audit.ForwardRetryBase <= 0 || c.Audit.ForwardRetryMax < c.Audit.ForwardRetryBase {
+                return errors.New("AUDIT_FORWARD_TIMEOUT, AUDIT_FORWARD_MAX_ATTEMPTS and AUDIT_FORWARD_RETRY_BASE must be positive and AUDIT_FORWARD_RETRY_MAX must not be less than AUDIT_FORWARD_RETRY_BASE")
+        }
+        if c.Audit.CheckpointKey != "" {
+                if seed, err := base64.StdEncoding.DecodeString(c.Audit.CheckpointKey); err != nil || len(seed) != 32 {
+                        return errors.New("AUDIT_CHECKPOINT_KEY must be a base64-encoded 32-byte key")
+                }
+        }
+        if c.Password.Argon2Memory <= 0 || c.Password.Argon2Iterations <= 0 || c.Password.Argon2Parallelism <= 0 || c.Password.Argon2Parallelism > 255 {
+                return errors.New("PASSWORD_ARGON2_MEMORY, PASSWORD_ARGON2_ITERATIONS and PASSWORD_ARGON2_PARALLELISM must be positive")
+        }
+        return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -92,6 +407,15 @@ func getIntEnv(key string, defaultValue int) int {
         return defaultValue
 }
 
+func getBoolEnv(key string, defaultValue bool) bool {
+        if value := os.Getenv(key); value != "" {
+                if boolVal, err := strconv.ParseBool(value); err == nil {
+                        return boolVal
+                }
+        }
+        return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
         if value := os.Getenv(key); value != "" {
                 if duration, err := time.ParseDuration(value); err == nil {
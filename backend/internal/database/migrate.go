@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the advisory lock key held while migrations run, so
+// replicas starting at the same time apply them one at a time.
+const migrationLockID int64 = 7_203_481_562
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(\.down)?\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []*Migration
+}
+
+// NewMigrator loads NNN_name.sql and NNN_name.down.sql files from fsys.
+// Any other .sql file is an error rather than being skipped, so a misnamed
+// migration cannot go unapplied unnoticed.
+func NewMigrator(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s must be named NNN_name.sql or NNN_name.down.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] != "" {
+			m.Down = string(body)
+		} else {
+			m.Up = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var applied []*Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx,
+					"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+					migration.Version, migration.Name, time.Now(),
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations, up to steps of them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+			if err := runMigration(ctx, conn, migration.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("reverting %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it, for databases whose schema was created by hand.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]*Migration, error) {
+	var marked []*Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			_, err := conn.Exec(ctx,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, time.Now(),
+			)
+			if err != nil {
+				return err
+			}
+			marked = append(marked, migration)
+		}
+		return nil
+	})
+
+	return marked, err
+}
+
+// Status lists every known migration with the time it was applied, if it
+// has been.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock. Session-level advisory locks belong to a connection, so every
+// statement must go through conn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// runMigration executes sql and record in one transaction, so a failing
+// migration leaves neither partial schema changes nor a version row behind.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, sql string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
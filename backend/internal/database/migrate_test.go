@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"admin-panel/migrations"
+)
+
+func TestNewMigratorLoadsEmbeddedMigrations(t *testing.T) {
+	m, err := NewMigrator(nil, migrations.FS)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	for i, migration := range m.migrations {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		if i > 0 && migration.Version <= m.migrations[i-1].Version {
+			t.Errorf("migration %d is out of order", migration.Version)
+		}
+	}
+}
+
+func TestNewMigratorFileNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr bool
+	}{
+		{"up and down", []string{"001_init.sql", "001_init.down.sql"}, false},
+		{"other files ignored", []string{"001_init.sql", "embed.go", "README.md"}, false},
+		{"missing version", []string{"001_init.sql", "add_index.sql"}, true},
+		{"upper case name", []string{"001_Init.sql"}, true},
+		{"dash separator", []string{"001-init.sql"}, true},
+		{"misspelled down suffix", []string{"001_init.sql", "001_init.dwn.sql"}, true},
+		{"version without up file", []string{"001_init.down.sql"}, true},
+		{"conflicting names", []string{"001_init.sql", "001_other.sql"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, name := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+			}
+			_, err := NewMigrator(nil, fsys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMigrator(%v): got error %v, want error %v", tt.files, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,13 +1,13 @@
 package handlers
 
 import (
+        "context"
         "encoding/json"
         "net/http"
-        "time"
 
         "admin-panel/internal/middleware"
-        "admin-panel/internal/models"
         "admin-panel/internal/repository"
+        "admin-panel/internal/services"
         "admin-panel/internal/utils"
 
         "github.com/go-chi/chi/v5"
@@ -17,14 +17,16 @@ import (
 
 type AdminHandler struct {
         adminAuthRepo *repository.AdminAuthRepository
-        auditRepo     *repository.AuditLogRepository
+        userRepo      *repository.UserRepository
+        audit         *services.AuditRecorder
         validate      *validator.Validate
 }
 
-func NewAdminHandler(adminAuthRepo *repository.AdminAuthRepository, auditRepo *repository.AuditLogRepository, validate *validator.Validate) *AdminHandler {
+func NewAdminHandler(adminAuthRepo *repository.AdminAuthRepository, userRepo *repository.UserRepository, audit *services.AuditRecorder, validate *validator.Validate) *AdminHandler {
         return &AdminHandler{
                 adminAuthRepo: adminAuthRepo,
-                auditRepo:     auditRepo,
+                userRepo:      userRepo,
+                audit:         audit,
                 validate:      validate,
         }
 }
@@ -35,6 +37,12 @@ type SetAdminRequest struct {
 }
 
 func (h *AdminHandler) SetAdmin(w http.ResponseWriter, r *http.Request) {
+        claims := middleware.GetUserFromContext(r.Context())
+        if claims == nil {
+                utils.Unauthorized(w, "Not authenticated")
+                return
+        }
+
         userIDStr := chi.URLParam(r, "id")
         userID, err := uuid.Parse(userIDStr)
         if err != nil {
@@ -42,68 +50,61 @@ func (h *AdminHandler) SetAdmin(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        if _, err := h.userRepo.GetByID(r.Context(), claims.TenantID, userID); err != nil {
+                utils.NotFound(w, "User not found")
+                return
+        }
+
         var req SetAdminRequest
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
                 utils.BadRequest(w, "Invalid request body", nil)
                 return
         }
 
+        before := h.adminStatus(r.Context(), claims.TenantID, userID)
+
         if req.Enabled {
                 if len(req.Password) < 8 {
                         utils.BadRequest(w, "Password must be at least 8 characters", map[string]string{"password": "min=8"})
                         return
                 }
 
-                passwordHash, err := utils.HashPasswordArgon2id(req.Password)
+                passwordHash, err := utils.HashPassword(req.Password)
                 if err != nil {
                         utils.InternalError(w, "Failed to hash password")
                         return
                 }
 
-                if err := h.adminAuthRepo.SetAdmin(r.Context(), userID, passwordHash); err != nil {
+                if err := h.adminAuthRepo.SetAdmin(r.Context(), claims.TenantID, userID, passwordHash); err != nil {
                         utils.InternalError(w, "Failed to set admin")
                         return
                 }
 
-                claims := middleware.GetUserFromContext(r.Context())
-                if claims != nil {
-                        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                                ID:         uuid.New(),
-                                TenantID:   claims.TenantID,
-                                UserID:     &claims.UserID,
-                                Action:     "set_admin",
-                                Resource:   "user",
-                                ResourceID: &userID,
-                                IPAddress:  r.RemoteAddr,
-                                UserAgent:  r.UserAgent(),
-                                CreatedAt:  time.Now(),
-                        })
-                }
+                h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
+                        Action:     "set_admin",
+                        Resource:   "user",
+                        ResourceID: &userID,
+                        Before:     before,
+                        After:      h.adminStatus(r.Context(), claims.TenantID, userID),
+                })
 
                 utils.JSON(w, http.StatusOK, map[string]interface{}{
                         "message": "Admin access granted",
                         "user_id": userID,
                 })
         } else {
-                if err := h.adminAuthRepo.UnsetAdmin(r.Context(), userID); err != nil {
+                if err := h.adminAuthRepo.UnsetAdmin(r.Context(), claims.TenantID, userID); err != nil {
                         utils.InternalError(w, "Failed to revoke admin")
                         return
                 }
 
-                claims := middleware.GetUserFromContext(r.Context())
-                if claims != nil {
-                        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                                ID:         uuid.New(),
-                                TenantID:   claims.TenantID,
-                                UserID:     &claims.UserID,
-                                Action:     "unset_admin",
-                                Resource:   "user",
-                                ResourceID: &userID,
-                                IPAddress:  r.RemoteAddr,
-                                UserAgent:  r.UserAgent(),
-                                CreatedAt:  time.Now(),
-                        })
-                }
+                h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
+                        Action:     "unset_admin",
+                        Resource:   "user",
+                        ResourceID: &userID,
+                        Before:     before,
+                        After:      h.adminStatus(r.Context(), claims.TenantID, userID),
+                })
 
                 utils.JSON(w, http.StatusOK, map[string]interface{}{
                         "message": "Admin access revoked",
@@ -113,6 +114,12 @@ func (h *AdminHandler) SetAdmin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdminHandler) GetAdminStatus(w http.ResponseWriter, r *http.Request) {
+        claims := middleware.GetUserFromContext(r.Context())
+        if claims == nil {
+                utils.Unauthorized(w, "Not authenticated")
+                return
+        }
+
         userIDStr := chi.URLParam(r, "id")
         userID, err := uuid.Parse(userIDStr)
         if err != nil {
@@ -120,7 +127,12 @@ func (h *AdminHandler) GetAdminStatus(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        adminAuth, err := h.adminAuthRepo.GetByUserID(r.Context(), userID)
+        if _, err := h.userRepo.GetByID(r.Context(), claims.TenantID, userID); err != nil {
+                utils.NotFound(w, "User not found")
+                return
+        }
+
+        adminAuth, err := h.adminAuthRepo.GetByUserID(r.Context(), claims.TenantID, userID)
         if err != nil {
                 utils.JSON(w, http.StatusOK, map[string]interface{}{
                         "user_id":  userID,
@@ -135,3 +147,15 @@ func (h *AdminHandler) GetAdminStatus(w http.ResponseWriter, r *http.Request) {
                 "enabled_at": adminAuth.EnabledAt,
         })
 }
+
+// adminStatus is the audit snapshot of a user's admin access.
+func (h *AdminHandler) adminStatus(ctx context.Context, tenantID, userID uuid.UUID) map[string]interface{} {
+        adminAuth, err := h.adminAuthRepo.GetByUserID(ctx, tenantID, userID)
+        if err != nil {
+                return map[string]interface{}{"is_admin": false}
+        }
+        return map[string]interface{}{
+                "is_admin":   adminAuth.IsAdmin,
+                "enabled_at": adminAuth.EnabledAt,
+        }
+}
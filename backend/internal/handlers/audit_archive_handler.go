@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AuditArchiveHandler struct {
+	retentionService *services.AuditRetentionService
+}
+
+func NewAuditArchiveHandler(retentionService *services.AuditRetentionService) *AuditArchiveHandler {
+	return &AuditArchiveHandler{
+		retentionService: retentionService,
+	}
+}
+
+// Retention returns the tenant's effective audit log retention period.
+func (h *AuditArchiveHandler) Retention(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, h.retentionService.Policy(r.Context(), claims.TenantID))
+}
+
+func (h *AuditArchiveHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	archives, err := h.retentionService.ListArchives(r.Context(), claims.TenantID)
+	if err != nil {
+		utils.InternalError(w, "Failed to list audit archives")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, archives)
+}
+
+// Download streams an archive as gzip-compressed JSONL, one audit log entry
+// per line.
+func (h *AuditArchiveHandler) Download(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid archive ID", nil)
+		return
+	}
+
+	archive, file, err := h.retentionService.OpenArchive(r.Context(), auditContext(r, claims), id)
+	if err != nil {
+		switch err {
+		case services.ErrAuditArchiveNotFound:
+			utils.NotFound(w, "Audit archive not found")
+		case services.ErrAuditArchiveUnavailable:
+			utils.InternalError(w, "Audit archive file is unavailable")
+		default:
+			utils.InternalError(w, "Failed to open audit archive")
+		}
+		return
+	}
+	defer file.Close()
+
+	filename := fmt.Sprintf("audit_logs_%s_%s.jsonl.gz", archive.From.Format("2006-01-02"), archive.To.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(archive.SizeBytes, 10))
+	w.Header().Set("X-Checksum-SHA256", archive.SHA256)
+
+	io.Copy(w, file)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+)
+
+type AuditChainHandler struct {
+	chainService *services.AuditChainService
+}
+
+func NewAuditChainHandler(chainService *services.AuditChainService) *AuditChainHandler {
+	return &AuditChainHandler{
+		chainService: chainService,
+	}
+}
+
+// Verify walks the tenant's audit chain and reports the first broken link.
+func (h *AuditChainHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	report, err := h.chainService.Verify(r.Context(), claims.TenantID)
+	if err != nil {
+		utils.InternalError(w, "Failed to verify audit log")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, report)
+}
+
+// Checkpoints returns the tenant's latest signed checkpoints and the public
+// key they can be checked against.
+func (h *AuditChainHandler) Checkpoints(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	checkpoints, err := h.chainService.ListCheckpoints(r.Context(), claims.TenantID, limit)
+	if err != nil {
+		utils.InternalError(w, "Failed to list audit checkpoints")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, checkpoints)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/models"
+	"admin-panel/internal/scheduler"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AuditExportHandler struct {
+	exportService *services.AuditExportService
+	scheduler     *scheduler.Scheduler
+}
+
+func NewAuditExportHandler(exportService *services.AuditExportService, scheduler *scheduler.Scheduler) *AuditExportHandler {
+	return &AuditExportHandler{
+		exportService: exportService,
+		scheduler:     scheduler,
+	}
+}
+
+// parseAuditExportFormat reads the format parameter, which defaults to CSV.
+func parseAuditExportFormat(r *http.Request) (string, string, string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.AuditExportCSV
+	}
+	contentType, extension, err := services.AuditExportContentType(format)
+	if err != nil {
+		return "", "", "", false
+	}
+	return format, contentType, extension, true
+}
+
+// Export streams the entries matching the filters parsed by
+// parseAuditLogFilter as CSV, JSONL or gzip-compressed NDJSON, chosen by the
+// format parameter. Ranges too large for one request should be exported
+// with Create instead.
+func (h *AuditExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	filter, errs := parseAuditLogFilter(r, claims.TenantID)
+	format, contentType, extension, ok := parseAuditExportFormat(r)
+	if !ok {
+		if errs == nil {
+			errs = map[string]string{}
+		}
+		errs["format"] = "must be csv, jsonl or ndjson.gz"
+	}
+	if len(errs) > 0 {
+		utils.BadRequest(w, "Invalid filter", errs)
+		return
+	}
+
+	filename := fmt.Sprintf("audit_logs_%s.%s", time.Now().Format("2006-01-02"), extension)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	// Once rows have been written the status can no longer change, and a
+	// failure can only cut the download short.
+	count, err := h.exportService.Export(r.Context(), auditContext(r, claims), filter, format, w)
+	if err != nil && count == 0 {
+		w.Header().Del("Content-Disposition")
+		writeAuditQueryError(w, err, "Failed to export audit logs")
+	}
+}
+
+// Create queues a background export of the entries matching the filters
+// and starts the exports job. If the job is already running, or cannot be
+// started, the export is picked up by its next scheduled run.
+func (h *AuditExportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	filter, errs := parseAuditLogFilter(r, claims.TenantID)
+	format, _, _, ok := parseAuditExportFormat(r)
+	if !ok {
+		if errs == nil {
+			errs = map[string]string{}
+		}
+		errs["format"] = "must be csv, jsonl or ndjson.gz"
+	}
+	if len(errs) > 0 {
+		utils.BadRequest(w, "Invalid filter", errs)
+		return
+	}
+
+	export, err := h.exportService.Create(r.Context(), auditContext(r, claims), filter, format)
+	if err != nil {
+		utils.InternalError(w, "Failed to create audit export")
+		return
+	}
+
+	h.scheduler.RunNow(r.Context(), services.JobAuditExports, claims.UserID)
+
+	utils.JSON(w, http.StatusAccepted, withDownloadURL(export))
+}
+
+// List returns the tenant's latest exports, newest first.
+func (h *AuditExportHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	exports, err := h.exportService.List(r.Context(), claims.TenantID, limit)
+	if err != nil {
+		utils.InternalError(w, "Failed to list audit exports")
+		return
+	}
+	for _, export := range exports {
+		withDownloadURL(export)
+	}
+
+	utils.JSON(w, http.StatusOK, exports)
+}
+
+// Get returns an export's status, with its download URL once complete.
+func (h *AuditExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid export ID", nil)
+		return
+	}
+
+	export, err := h.exportService.Get(r.Context(), claims.TenantID, id)
+	if err != nil {
+		if err == services.ErrAuditExportNotFound {
+			utils.NotFound(w, "Audit export not found")
+			return
+		}
+		utils.InternalError(w, "Failed to get audit export")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, withDownloadURL(export))
+}
+
+// Download streams the file of a completed export.
+func (h *AuditExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid export ID", nil)
+		return
+	}
+
+	export, file, err := h.exportService.Open(r.Context(), auditContext(r, claims), id)
+	if err != nil {
+		switch err {
+		case services.ErrAuditExportNotFound:
+			utils.NotFound(w, "Audit export not found")
+		case services.ErrAuditExportNotReady:
+			utils.Conflict(w, "Audit export is not ready")
+		case services.ErrAuditExportUnavailable:
+			utils.InternalError(w, "Audit export file is unavailable")
+		default:
+			utils.InternalError(w, "Failed to open audit export")
+		}
+		return
+	}
+	defer file.Close()
+
+	contentType, extension, _ := services.AuditExportContentType(export.Format)
+	filename := fmt.Sprintf("audit_logs_%s.%s", export.CreatedAt.Format("2006-01-02"), extension)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(export.SizeBytes, 10))
+
+	io.Copy(w, file)
+}
+
+// withDownloadURL sets the download URL of a completed export.
+func withDownloadURL(export *models.AuditExport) *models.AuditExport {
+	if export.Status == services.AuditExportCompleted {
+		export.DownloadURL = fmt.Sprintf("/api/v1/audit-logs/exports/%s/download", export.ID)
+	}
+	return export
+}
@@ -1,16 +1,20 @@
 package handlers
 
 import (
-        "encoding/csv"
-        "fmt"
+        "encoding/json"
+        "net"
         "net/http"
         "strconv"
+        "strings"
         "time"
 
         "admin-panel/internal/middleware"
         "admin-panel/internal/models"
         "admin-panel/internal/services"
         "admin-panel/internal/utils"
+
+        "github.com/go-chi/chi/v5"
+        "github.com/google/uuid"
 )
 
 type AuditHandler struct {
@@ -23,6 +27,9 @@ func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
         }
 }
 
+// List returns audit log entries matching the filters parsed by
+// parseAuditLogFilter. With a cursor parameter, empty for the first page,
+// it paginates by keyset and returns next_cursor instead of counting pages.
 func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
         claims := middleware.GetUserFromContext(r.Context())
         if claims == nil {
@@ -30,9 +37,10 @@ func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-        if page < 1 {
-                page = 1
+        filter, errs := parseAuditLogFilter(r, claims.TenantID)
+        if len(errs) > 0 {
+                utils.BadRequest(w, "Invalid filter", errs)
+                return
         }
 
         perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
@@ -40,88 +48,172 @@ func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
                 perPage = 20
         }
 
-        params := &models.ListParams{
-                Page:     page,
-                PerPage:  perPage,
-                Sort:     r.URL.Query().Get("sort"),
-                Order:    r.URL.Query().Get("order"),
-                Search:   r.URL.Query().Get("search"),
-                Filters:  make(map[string]interface{}),
-                TenantID: claims.TenantID,
-        }
-
-        if action := r.URL.Query().Get("action"); action != "" {
-                params.Filters["action"] = action
-        }
-        if resource := r.URL.Query().Get("resource"); resource != "" {
-                params.Filters["resource"] = resource
+        var result interface{}
+        var err error
+        if r.URL.Query().Has("cursor") {
+                result, err = h.auditService.ListPage(r.Context(), filter, r.URL.Query().Get("cursor"), perPage)
+        } else {
+                page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+                if page < 1 {
+                        page = 1
+                }
+                result, err = h.auditService.List(r.Context(), filter, page, perPage)
         }
-
-        result, err := h.auditService.List(r.Context(), params)
         if err != nil {
-                utils.InternalError(w, "Failed to list audit logs")
+                writeAuditQueryError(w, err, "Failed to list audit logs")
                 return
         }
 
         utils.JSON(w, http.StatusOK, result)
 }
 
-func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
-        claims := middleware.GetUserFromContext(r.Context())
-        if claims == nil {
-                utils.Unauthorized(w, "Not authenticated")
-                return
+// parseAuditLogFilter reads the audit log filters from the query string:
+//
+//	search               action or resource containing the text
+//	action               one or more actions, repeated or comma-separated
+//	resource             resource type
+//	user_id, resource_id acting user and affected record
+//	ip                   client IP address
+//	from, to             RFC 3339 times or dates; to is exclusive for times
+//	                     and includes the whole day for dates
+//	old_path, new_path   SQL/JSON path predicates on the snapshots, such as
+//	                     $.status ? (@ == "active")
+//	old.<field>          snapshot field equal to, or list containing, the
+//	new.<field>          value; nested fields are separated by dots and
+//	                     values are JSON, or text when not valid JSON
+//	sort, order          created_at, action or resource; asc or desc
+//
+// It returns the problems with malformed parameters by name.
+func parseAuditLogFilter(r *http.Request, tenantID uuid.UUID) (*models.AuditLogFilter, map[string]string) {
+        query := r.URL.Query()
+        errs := make(map[string]string)
+
+        filter := &models.AuditLogFilter{
+                TenantID:     tenantID,
+                Search:       query.Get("search"),
+                Resource:     query.Get("resource"),
+                OldValuePath: query.Get("old_path"),
+                NewValuePath: query.Get("new_path"),
+                Sort:         query.Get("sort"),
+                Order:        query.Get("order"),
         }
 
-        params := &models.ListParams{
-                Sort:     "created_at",
-                Order:    "desc",
-                Search:   r.URL.Query().Get("search"),
-                Filters:  make(map[string]interface{}),
-                TenantID: claims.TenantID,
+        for _, value := range query["action"] {
+                for _, action := range strings.Split(value, ",") {
+                        if action = strings.TrimSpace(action); action != "" {
+                                filter.Actions = append(filter.Actions, action)
+                        }
+                }
         }
 
-        if action := r.URL.Query().Get("action"); action != "" {
-                params.Filters["action"] = action
+        parseID := func(name string) *uuid.UUID {
+                value := query.Get(name)
+                if value == "" {
+                        return nil
+                }
+                id, err := uuid.Parse(value)
+                if err != nil {
+                        errs[name] = "must be a UUID"
+                        return nil
+                }
+                return &id
         }
-        if resource := r.URL.Query().Get("resource"); resource != "" {
-                params.Filters["resource"] = resource
+        filter.UserID = parseID("user_id")
+        filter.ResourceID = parseID("resource_id")
+
+        if ip := query.Get("ip"); ip != "" {
+                if net.ParseIP(ip) == nil {
+                        errs["ip"] = "must be an IP address"
+                } else {
+                        filter.IPAddress = ip
+                }
         }
 
-        logs, err := h.auditService.ListAll(r.Context(), params)
-        if err != nil {
-                utils.InternalError(w, "Failed to export audit logs")
-                return
+        parseTime := func(name string, endOfDay bool) *time.Time {
+                value := query.Get(name)
+                if value == "" {
+                        return nil
+                }
+                if t, err := time.Parse(time.RFC3339, value); err == nil {
+                        return &t
+                }
+                t, err := time.Parse("2006-01-02", value)
+                if err != nil {
+                        errs[name] = "must be an RFC 3339 time or a date"
+                        return nil
+                }
+                if endOfDay {
+                        t = t.AddDate(0, 0, 1)
+                }
+                return &t
+        }
+        filter.From = parseTime("from", false)
+        filter.To = parseTime("to", true)
+
+        for name, values := range query {
+                var fields *map[string]interface{}
+                var path string
+                switch {
+                case strings.HasPrefix(name, "old."):
+                        fields, path = &filter.OldValue, strings.TrimPrefix(name, "old.")
+                case strings.HasPrefix(name, "new."):
+                        fields, path = &filter.NewValue, strings.TrimPrefix(name, "new.")
+                default:
+                        continue
+                }
+                if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+                        errs[name] = "must name a snapshot field"
+                        continue
+                }
+
+                var value interface{}
+                if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+                        value = values[0]
+                }
+                if *fields == nil {
+                        *fields = make(map[string]interface{})
+                }
+                (*fields)[path] = value
         }
 
-        filename := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("2006-01-02"))
-        w.Header().Set("Content-Type", "text/csv")
-        w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+        return filter, errs
+}
 
-        writer := csv.NewWriter(w)
-        defer writer.Flush()
+func writeAuditQueryError(w http.ResponseWriter, err error, fallback string) {
+        switch err {
+        case services.ErrInvalidAuditCursor:
+                utils.BadRequest(w, "Invalid cursor", nil)
+        case services.ErrInvalidAuditJSONPath:
+                utils.BadRequest(w, "Invalid JSON path", nil)
+        default:
+                utils.InternalError(w, fallback)
+        }
+}
+
+// Get returns one audit log entry with the field-level diff of its before
+// and after snapshots.
+func (h *AuditHandler) Get(w http.ResponseWriter, r *http.Request) {
+        claims := middleware.GetUserFromContext(r.Context())
+        if claims == nil {
+                utils.Unauthorized(w, "Not authenticated")
+                return
+        }
 
-        writer.Write([]string{"ID", "User ID", "Action", "Resource", "Resource ID", "IP Address", "User Agent", "Created At"})
+        id, err := uuid.Parse(chi.URLParam(r, "id"))
+        if err != nil {
+                utils.BadRequest(w, "Invalid audit log ID", nil)
+                return
+        }
 
-        for _, log := range logs {
-                userID := ""
-                if log.UserID != nil {
-                        userID = log.UserID.String()
-                }
-                resourceID := ""
-                if log.ResourceID != nil {
-                        resourceID = log.ResourceID.String()
+        entry, err := h.auditService.Get(r.Context(), claims.TenantID, id)
+        if err != nil {
+                if err == services.ErrAuditLogNotFound {
+                        utils.NotFound(w, "Audit log not found")
+                        return
                 }
-
-                writer.Write([]string{
-                        log.ID.String(),
-                        userID,
-                        log.Action,
-                        log.Resource,
-                        resourceID,
-                        log.IPAddress,
-                        log.UserAgent,
-                        log.CreatedAt.Format(time.RFC3339),
-                })
+                utils.InternalError(w, "Failed to get audit log")
+                return
         }
+
+        utils.JSON(w, http.StatusOK, entry)
 }
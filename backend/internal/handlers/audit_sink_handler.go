@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type AuditSinkHandler struct {
+	sinkService *services.AuditSinkService
+	validate    *validator.Validate
+}
+
+func NewAuditSinkHandler(sinkService *services.AuditSinkService, validate *validator.Validate) *AuditSinkHandler {
+	return &AuditSinkHandler{
+		sinkService: sinkService,
+		validate:    validate,
+	}
+}
+
+// List returns the tenant's sinks with their delivery backlog and health.
+func (h *AuditSinkHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	sinks, err := h.sinkService.List(r.Context(), claims.TenantID)
+	if err != nil {
+		utils.InternalError(w, "Failed to list audit sinks")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, sinks)
+}
+
+func (h *AuditSinkHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid sink ID", nil)
+		return
+	}
+
+	sink, err := h.sinkService.Get(r.Context(), claims.TenantID, id)
+	if err != nil {
+		writeAuditSinkError(w, err, "Failed to get audit sink")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, sink)
+}
+
+func (h *AuditSinkHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.CreateAuditSinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	sink, err := h.sinkService.Create(r.Context(), auditContext(r, claims), &req)
+	if err != nil {
+		writeAuditSinkError(w, err, "Failed to create audit sink")
+		return
+	}
+
+	utils.JSON(w, http.StatusCreated, sink)
+}
+
+func (h *AuditSinkHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid sink ID", nil)
+		return
+	}
+
+	var req services.UpdateAuditSinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	sink, err := h.sinkService.Update(r.Context(), auditContext(r, claims), id, &req)
+	if err != nil {
+		writeAuditSinkError(w, err, "Failed to update audit sink")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, sink)
+}
+
+func (h *AuditSinkHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid sink ID", nil)
+		return
+	}
+
+	if err := h.sinkService.Delete(r.Context(), auditContext(r, claims), id); err != nil {
+		writeAuditSinkError(w, err, "Failed to delete audit sink")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Audit sink deleted successfully"})
+}
+
+// Retry queues the sink's entries that exhausted their retries again.
+func (h *AuditSinkHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid sink ID", nil)
+		return
+	}
+
+	count, err := h.sinkService.Retry(r.Context(), auditContext(r, claims), id)
+	if err != nil {
+		writeAuditSinkError(w, err, "Failed to retry audit sink deliveries")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]int64{"requeued": count})
+}
+
+func writeAuditSinkError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrAuditSinkNotFound):
+		utils.NotFound(w, "Audit sink not found")
+	case errors.Is(err, services.ErrAuditSinkNameExists):
+		utils.Conflict(w, "Audit sink name already exists")
+	case errors.Is(err, services.ErrInvalidAuditSink):
+		utils.BadRequest(w, err.Error(), nil)
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
@@ -58,6 +59,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Tenant == "" {
+		req.Tenant = middleware.GetTenantSlug(r.Context())
+	}
+
 	ipAddress := r.RemoteAddr
 	userAgent := r.UserAgent()
 
@@ -66,42 +71,170 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		switch err {
 		case services.ErrInvalidCredentials:
 			utils.Unauthorized(w, "Invalid email or password")
+		case services.ErrAccountLocked:
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
 		case services.ErrUserInactive:
 			utils.Forbidden(w, "Account is inactive")
+		case services.ErrTenantInactive:
+			utils.Forbidden(w, "Organization is not active")
 		default:
 			utils.InternalError(w, "Login failed")
 		}
 		return
 	}
 
-	secure := isSecureRequest(r)
+	if resp.Tokens != nil {
+		setAuthCookies(w, r, resp.Tokens)
+	}
 
-	accessCookie := &http.Cookie{
-		Name:     "access_token",
-		Value:    resp.Tokens.AccessToken,
-		Path:     "/",
-		MaxAge:   900,
-		HttpOnly: true,
-		Secure:   secure,
-		SameSite: http.SameSiteLaxMode,
+	utils.JSON(w, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req services.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
 	}
 
-	refreshCookie := &http.Cookie{
-		Name:     "refresh_token",
-		Value:    resp.Tokens.RefreshToken,
-		Path:     "/",
-		MaxAge:   604800,
-		HttpOnly: true,
-		Secure:   secure,
-		SameSite: http.SameSiteLaxMode,
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
 	}
 
-	http.SetCookie(w, accessCookie)
-	http.SetCookie(w, refreshCookie)
+	resp, err := h.authService.LoginTwoFactor(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		switch err {
+		case services.ErrInvalidToken:
+			utils.Unauthorized(w, "Login challenge is invalid or has expired")
+		case services.ErrAccountLocked:
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
+		case services.ErrInvalidTwoFactorCode:
+			utils.Unauthorized(w, "Invalid authentication code")
+		case services.ErrUserInactive:
+			utils.Forbidden(w, "Account is inactive")
+		case services.ErrTenantInactive:
+			utils.Forbidden(w, "Organization is not active")
+		default:
+			utils.InternalError(w, "Login failed")
+		}
+		return
+	}
+
+	setAuthCookies(w, r, resp.Tokens)
 
 	utils.JSON(w, http.StatusOK, resp)
 }
 
+func (h *AuthHandler) ChangeExpiredPassword(w http.ResponseWriter, r *http.Request) {
+	var req services.ExpiredPasswordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	resp, err := h.authService.ChangeExpiredPassword(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		switch err {
+		case services.ErrInvalidToken:
+			utils.Unauthorized(w, "Login challenge is invalid or has expired")
+		case services.ErrAccountLocked:
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
+		case services.ErrUserInactive:
+			utils.Forbidden(w, "Account is inactive")
+		case services.ErrTenantInactive:
+			utils.Forbidden(w, "Organization is not active")
+		default:
+			utils.InternalError(w, "Failed to change password")
+		}
+		return
+	}
+
+	if resp.Tokens != nil {
+		setAuthCookies(w, r, resp.Tokens)
+	}
+
+	utils.JSON(w, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	if err := h.authService.ChangePassword(r.Context(), claims, &req, r.RemoteAddr, r.UserAgent()); err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		switch err {
+		case services.ErrInvalidCredentials:
+			utils.Unauthorized(w, "Current password is incorrect")
+		case services.ErrUserNotFound:
+			utils.NotFound(w, "User not found")
+		default:
+			utils.InternalError(w, "Failed to change password")
+		}
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Password changed successfully"})
+}
+
+func (h *AuthHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, h.authService.PasswordPolicy(r.Context(), claims.TenantID))
+}
+
+// writePasswordPolicyError reports a password rejected by the tenant's
+// password policy, listing the rules it broke. It returns false for any
+// other error.
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	utils.BadRequest(w, "Password does not meet the password policy", policyErr.Violations)
+	return true
+}
+
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
@@ -112,7 +245,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	ipAddress := r.RemoteAddr
 	userAgent := r.UserAgent()
 
-	if err := h.authService.Logout(r.Context(), claims.UserID, ipAddress, userAgent); err != nil {
+	if err := h.authService.Logout(r.Context(), claims, ipAddress, userAgent); err != nil {
 		utils.InternalError(w, "Logout failed")
 		return
 	}
@@ -174,19 +307,27 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 			utils.Unauthorized(w, "Refresh token has expired")
 		case services.ErrInvalidToken:
 			utils.Unauthorized(w, "Invalid refresh token")
+		case services.ErrTenantInactive:
+			utils.Forbidden(w, "Organization is not active")
 		default:
 			utils.InternalError(w, "Token refresh failed")
 		}
 		return
 	}
 
+	setAuthCookies(w, r, tokens)
+
+	utils.JSON(w, http.StatusOK, tokens)
+}
+
+func setAuthCookies(w http.ResponseWriter, r *http.Request, tokens *services.AuthTokens) {
 	secure := isSecureRequest(r)
 
 	accessCookie := &http.Cookie{
 		Name:     "access_token",
 		Value:    tokens.AccessToken,
 		Path:     "/",
-		MaxAge:   900,
+		MaxAge:   int(tokens.ExpiresIn),
 		HttpOnly: true,
 		Secure:   secure,
 		SameSite: http.SameSiteLaxMode,
@@ -196,7 +337,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		Name:     "refresh_token",
 		Value:    tokens.RefreshToken,
 		Path:     "/",
-		MaxAge:   604800,
+		MaxAge:   int(tokens.RefreshExpiresIn),
 		HttpOnly: true,
 		Secure:   secure,
 		SameSite: http.SameSiteLaxMode,
@@ -204,8 +345,105 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 	http.SetCookie(w, accessCookie)
 	http.SetCookie(w, refreshCookie)
+}
 
-	utils.JSON(w, http.StatusOK, tokens)
+type StepUpRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *AuthHandler) StepUp(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req StepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		utils.BadRequest(w, "Password is required", map[string]string{"password": "required"})
+		return
+	}
+
+	resp, err := h.authService.StepUp(r.Context(), claims, req.Password, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		switch err {
+		case services.ErrAdminNotEnabled:
+			utils.Forbidden(w, "Admin access is not enabled for this account")
+		case services.ErrInvalidCredentials:
+			utils.Unauthorized(w, "Invalid admin password")
+		case services.ErrStepUpLocked:
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many attempts. Please try again later.", nil)
+		default:
+			utils.InternalError(w, "Step-up failed")
+		}
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "access_token",
+		Value:    resp.AccessToken,
+		Path:     "/",
+		MaxAge:   int(resp.ExpiresIn),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	utils.JSON(w, http.StatusOK, resp)
+}
+
+// Reauthenticate confirms the caller's own password or second-factor code
+// and replaces their access token with one that allows changes guarded by
+// recent authentication for the step-up window.
+func (h *AuthHandler) Reauthenticate(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.ReauthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil || (req.Password == "") == (req.Code == "") {
+		utils.BadRequest(w, "Provide either your password or a two-factor code", nil)
+		return
+	}
+
+	resp, err := h.authService.Reauthenticate(r.Context(), claims, &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		switch err {
+		case services.ErrInvalidCredentials:
+			utils.Unauthorized(w, "Invalid password or code")
+		case services.ErrStepUpLocked:
+			utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many attempts. Please try again later.", nil)
+		case services.ErrUserNotFound:
+			utils.Unauthorized(w, "Not authenticated")
+		default:
+			utils.InternalError(w, "Re-authentication failed")
+		}
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "access_token",
+		Value:    resp.AccessToken,
+		Path:     "/",
+		MaxAge:   int(resp.ExpiresIn),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	utils.JSON(w, http.StatusOK, resp)
 }
 
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
@@ -216,8 +454,10 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.JSON(w, http.StatusOK, map[string]interface{}{
-		"user_id":   claims.UserID,
-		"tenant_id": claims.TenantID,
-		"email":     claims.Email,
+		"user_id":         claims.UserID,
+		"tenant_id":       claims.TenantID,
+		"email":           claims.Email,
+		"elevated":        claims.IsElevated(),
+		"reauthenticated": claims.IsReauthenticated(),
 	})
 }
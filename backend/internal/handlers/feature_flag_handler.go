@@ -3,11 +3,11 @@ package handlers
 import (
         "encoding/json"
         "net/http"
-        "time"
 
         "admin-panel/internal/middleware"
         "admin-panel/internal/models"
         "admin-panel/internal/repository"
+        "admin-panel/internal/services"
         "admin-panel/internal/utils"
 
         "github.com/go-chi/chi/v5"
@@ -16,16 +16,16 @@ import (
 )
 
 type FeatureFlagHandler struct {
-        flagRepo  *repository.FeatureFlagRepository
-        auditRepo *repository.AuditLogRepository
-        validate  *validator.Validate
+        flagRepo *repository.FeatureFlagRepository
+        audit    *services.AuditRecorder
+        validate *validator.Validate
 }
 
-func NewFeatureFlagHandler(flagRepo *repository.FeatureFlagRepository, auditRepo *repository.AuditLogRepository, validate *validator.Validate) *FeatureFlagHandler {
+func NewFeatureFlagHandler(flagRepo *repository.FeatureFlagRepository, audit *services.AuditRecorder, validate *validator.Validate) *FeatureFlagHandler {
         return &FeatureFlagHandler{
-                flagRepo:  flagRepo,
-                auditRepo: auditRepo,
-                validate:  validate,
+                flagRepo: flagRepo,
+                audit:    audit,
+                validate: validate,
         }
 }
 
@@ -50,6 +50,12 @@ func (h *FeatureFlagHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FeatureFlagHandler) Get(w http.ResponseWriter, r *http.Request) {
+        claims := middleware.GetUserFromContext(r.Context())
+        if claims == nil {
+                utils.Unauthorized(w, "Not authenticated")
+                return
+        }
+
         idStr := chi.URLParam(r, "id")
         id, err := uuid.Parse(idStr)
         if err != nil {
@@ -57,7 +63,7 @@ func (h *FeatureFlagHandler) Get(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        flag, err := h.flagRepo.GetByID(r.Context(), id)
+        flag, err := h.flagRepo.GetByID(r.Context(), claims.TenantID, id)
         if err != nil {
                 utils.NotFound(w, "Feature flag not found")
                 return
@@ -110,16 +116,11 @@ func (h *FeatureFlagHandler) Create(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                ID:         uuid.New(),
-                TenantID:   claims.TenantID,
-                UserID:     &claims.UserID,
+        h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
                 Action:     "create",
                 Resource:   "feature_flag",
                 ResourceID: &flag.ID,
-                IPAddress:  r.RemoteAddr,
-                UserAgent:  r.UserAgent(),
-                CreatedAt:  time.Now(),
+                After:      flag,
         })
 
         utils.JSON(w, http.StatusCreated, flag)
@@ -146,7 +147,7 @@ func (h *FeatureFlagHandler) Update(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        flag, err := h.flagRepo.GetByID(r.Context(), id)
+        flag, err := h.flagRepo.GetByID(r.Context(), claims.TenantID, id)
         if err != nil {
                 utils.NotFound(w, "Feature flag not found")
                 return
@@ -167,6 +168,7 @@ func (h *FeatureFlagHandler) Update(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
+        before := *flag
         flag.Name = req.Name
         flag.Description = req.Description
         flag.Enabled = req.Enabled
@@ -177,16 +179,12 @@ func (h *FeatureFlagHandler) Update(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                ID:         uuid.New(),
-                TenantID:   claims.TenantID,
-                UserID:     &claims.UserID,
+        h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
                 Action:     "update",
                 Resource:   "feature_flag",
                 ResourceID: &flag.ID,
-                IPAddress:  r.RemoteAddr,
-                UserAgent:  r.UserAgent(),
-                CreatedAt:  time.Now(),
+                Before:     &before,
+                After:      flag,
         })
 
         utils.JSON(w, http.StatusOK, flag)
@@ -206,21 +204,22 @@ func (h *FeatureFlagHandler) Delete(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        if err := h.flagRepo.Delete(r.Context(), id); err != nil {
+        flag, err := h.flagRepo.GetByID(r.Context(), claims.TenantID, id)
+        if err != nil {
+                utils.NotFound(w, "Feature flag not found")
+                return
+        }
+
+        if err := h.flagRepo.Delete(r.Context(), claims.TenantID, id); err != nil {
                 utils.InternalError(w, "Failed to delete feature flag")
                 return
         }
 
-        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                ID:         uuid.New(),
-                TenantID:   claims.TenantID,
-                UserID:     &claims.UserID,
+        h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
                 Action:     "delete",
                 Resource:   "feature_flag",
                 ResourceID: &id,
-                IPAddress:  r.RemoteAddr,
-                UserAgent:  r.UserAgent(),
-                CreatedAt:  time.Now(),
+                Before:     flag,
         })
 
         utils.JSON(w, http.StatusOK, map[string]string{"message": "Feature flag deleted"})
@@ -240,12 +239,13 @@ func (h *FeatureFlagHandler) Toggle(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        flag, err := h.flagRepo.GetByID(r.Context(), id)
+        flag, err := h.flagRepo.GetByID(r.Context(), claims.TenantID, id)
         if err != nil {
                 utils.NotFound(w, "Feature flag not found")
                 return
         }
 
+        before := *flag
         flag.Enabled = !flag.Enabled
 
         if err := h.flagRepo.Update(r.Context(), flag); err != nil {
@@ -253,16 +253,12 @@ func (h *FeatureFlagHandler) Toggle(w http.ResponseWriter, r *http.Request) {
                 return
         }
 
-        h.auditRepo.Log(r.Context(), &models.AuditLog{
-                ID:         uuid.New(),
-                TenantID:   claims.TenantID,
-                UserID:     &claims.UserID,
+        h.audit.Record(r.Context(), auditContext(r, claims), services.AuditChange{
                 Action:     "toggle",
                 Resource:   "feature_flag",
                 ResourceID: &flag.ID,
-                IPAddress:  r.RemoteAddr,
-                UserAgent:  r.UserAgent(),
-                CreatedAt:  time.Now(),
+                Before:     &before,
+                After:      flag,
         })
 
         utils.JSON(w, http.StatusOK, flag)
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/models"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+	validate          *validator.Validate
+}
+
+func NewInvitationHandler(invitationService *services.InvitationService, validate *validator.Validate) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		validate:          validate,
+	}
+}
+
+func (h *InvitationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	invitation, err := h.invitationService.Get(r.Context(), claims.TenantID, id)
+	if err != nil {
+		writeInvitationError(w, err, "Failed to get invitation")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, invitation)
+}
+
+func (h *InvitationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	invitation, err := h.invitationService.Resend(r.Context(), auditContext(r, claims), id)
+	if err != nil {
+		writeInvitationError(w, err, "Failed to resend invitation")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, invitation)
+}
+
+func (h *InvitationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.invitationService.Revoke(r.Context(), auditContext(r, claims), id); err != nil {
+		writeInvitationError(w, err, "Failed to revoke invitation")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Invitation revoked"})
+}
+
+// Accept is called by the invitee, who is not signed in yet.
+func (h *InvitationHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	var req services.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	user, err := h.invitationService.Accept(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		writeInvitationError(w, err, "Failed to accept invitation")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, user)
+}
+
+func writeInvitationError(w http.ResponseWriter, err error, fallback string) {
+	if writePasswordPolicyError(w, err) {
+		return
+	}
+	switch err {
+	case services.ErrUserNotFound:
+		utils.NotFound(w, "User not found")
+	case services.ErrNoInvitation:
+		utils.NotFound(w, "No outstanding invitation")
+	case services.ErrUserNotPending:
+		utils.Conflict(w, "User has already accepted their invitation")
+	case services.ErrInvalidInvitation:
+		utils.BadRequest(w, "Invitation is invalid or has expired", nil)
+	case services.ErrTenantInactive:
+		utils.Forbidden(w, "Organization is not active")
+	case services.ErrInvitationNotSent:
+		writeInvitationNotSent(w, nil)
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
+
+// writeInvitationNotSent reports an invitation that was recorded but could
+// not be emailed. user is the invited user when they were just created.
+func writeInvitationNotSent(w http.ResponseWriter, user *models.User) {
+	var details map[string]string
+	if user != nil {
+		details = map[string]string{"user_id": user.ID.String()}
+	}
+	utils.ErrorResponse(w, http.StatusBadGateway, "INVITATION_NOT_SENT",
+		"The invitation was saved but could not be emailed. Try resending it.", details)
+}
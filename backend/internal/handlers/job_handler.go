@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/scheduler"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewJobHandler(s *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: s}
+}
+
+// List returns the background jobs with their schedules and latest runs.
+func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.scheduler.Jobs(r.Context())
+	if err != nil {
+		utils.InternalError(w, "Failed to list jobs")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, jobs)
+}
+
+// Runs returns a job's run history, newest first.
+func (h *JobHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	runs, err := h.scheduler.Runs(r.Context(), chi.URLParam(r, "name"), limit)
+	if err != nil {
+		writeJobError(w, err, "Failed to list job runs")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, runs)
+}
+
+// Run starts a job immediately. The run continues in the background; poll
+// the run history for its outcome.
+func (h *JobHandler) Run(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	run, err := h.scheduler.RunNow(r.Context(), chi.URLParam(r, "name"), claims.UserID)
+	if err != nil {
+		writeJobError(w, err, "Failed to start job")
+		return
+	}
+
+	utils.JSON(w, http.StatusAccepted, run)
+}
+
+func writeJobError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case scheduler.ErrUnknownJob:
+		utils.NotFound(w, "Job not found")
+	case scheduler.ErrJobBusy:
+		utils.Conflict(w, "Job is already running")
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type PasswordResetHandler struct {
+	resetService *services.PasswordResetService
+	validate     *validator.Validate
+}
+
+func NewPasswordResetHandler(resetService *services.PasswordResetService, validate *validator.Validate) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		resetService: resetService,
+		validate:     validate,
+	}
+}
+
+// Forgot always answers with the same message so the response does not
+// reveal whether the email address has an account.
+func (h *PasswordResetHandler) Forgot(w http.ResponseWriter, r *http.Request) {
+	var req services.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	if req.Tenant == "" {
+		req.Tenant = middleware.GetTenantSlug(r.Context())
+	}
+
+	h.resetService.RequestReset(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+
+	utils.JSON(w, http.StatusOK, map[string]string{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+func (h *PasswordResetHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	var req services.ConfirmPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	if err := h.resetService.ConfirmReset(r.Context(), &req, r.RemoteAddr, r.UserAgent()); err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		switch err {
+		case services.ErrInvalidResetToken:
+			utils.BadRequest(w, "Reset link is invalid or has expired", nil)
+		case services.ErrTenantInactive:
+			utils.Forbidden(w, "Organization is not active")
+		default:
+			utils.InternalError(w, "Failed to reset password")
+		}
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
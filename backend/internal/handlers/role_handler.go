@@ -49,7 +49,7 @@ func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.roleService.Create(r.Context(), &req, claims.TenantID)
+	role, err := h.roleService.Create(r.Context(), auditContext(r, claims), &req)
 	if err != nil {
 		if err == services.ErrRoleNameExists {
 			utils.Conflict(w, "Role name already exists")
@@ -63,6 +63,12 @@ func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *RoleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -70,7 +76,7 @@ func (h *RoleHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.roleService.GetByID(r.Context(), id)
+	role, err := h.roleService.GetByID(r.Context(), claims.TenantID, id)
 	if err != nil {
 		utils.NotFound(w, "Role not found")
 		return
@@ -116,6 +122,12 @@ func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -138,9 +150,11 @@ func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.roleService.Update(r.Context(), id, &req)
+	role, err := h.roleService.Update(r.Context(), auditContext(r, claims), id, &req)
 	if err != nil {
 		switch err {
+		case services.ErrRoleNotFound:
+			utils.NotFound(w, "Role not found")
 		case services.ErrRoleNameExists:
 			utils.Conflict(w, "Role name already exists")
 		case services.ErrSystemRole:
@@ -155,6 +169,12 @@ func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -162,12 +182,15 @@ func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.roleService.Delete(r.Context(), id); err != nil {
-		if err == services.ErrSystemRole {
+	if err := h.roleService.Delete(r.Context(), auditContext(r, claims), id); err != nil {
+		switch err {
+		case services.ErrRoleNotFound:
+			utils.NotFound(w, "Role not found")
+		case services.ErrSystemRole:
 			utils.Forbidden(w, "Cannot delete system role")
-			return
+		default:
+			utils.InternalError(w, "Failed to delete role")
 		}
-		utils.InternalError(w, "Failed to delete role")
 		return
 	}
 
@@ -175,6 +198,12 @@ func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -182,8 +211,12 @@ func (h *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	permissions, err := h.roleService.GetRolePermissions(r.Context(), id)
+	permissions, err := h.roleService.GetRolePermissions(r.Context(), claims.TenantID, id)
 	if err != nil {
+		if err == services.ErrRoleNotFound {
+			utils.NotFound(w, "Role not found")
+			return
+		}
 		utils.InternalError(w, "Failed to get role permissions")
 		return
 	}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// List returns the caller's own active sessions.
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	sessions, err := h.sessionService.List(r.Context(), claims.TenantID, claims.UserID, claims.SessionID)
+	if err != nil {
+		writeSessionError(w, err, "Failed to list sessions")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, sessions)
+}
+
+// Revoke signs the caller out of one of their sessions.
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid session ID", nil)
+		return
+	}
+
+	if err := h.sessionService.Revoke(r.Context(), auditContext(r, claims), claims.UserID, sessionID); err != nil {
+		writeSessionError(w, err, "Failed to revoke session")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Session revoked successfully"})
+}
+
+// RevokeOthers signs the caller out everywhere except the current session.
+func (h *SessionHandler) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	if claims.SessionID == uuid.Nil {
+		utils.BadRequest(w, "Current session is unknown; sign in again first", nil)
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeOthers(r.Context(), auditContext(r, claims), claims.SessionID)
+	if err != nil {
+		writeSessionError(w, err, "Failed to revoke sessions")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]interface{}{"revoked": revoked})
+}
+
+// ListForUser returns another user's active sessions.
+func (h *SessionHandler) ListForUser(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	sessions, err := h.sessionService.List(r.Context(), claims.TenantID, userID, claims.SessionID)
+	if err != nil {
+		writeSessionError(w, err, "Failed to list sessions")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeForUser signs another user out of one session.
+func (h *SessionHandler) RevokeForUser(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid session ID", nil)
+		return
+	}
+
+	if err := h.sessionService.Revoke(r.Context(), auditContext(r, claims), userID, sessionID); err != nil {
+		writeSessionError(w, err, "Failed to revoke session")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Session revoked successfully"})
+}
+
+// RevokeAllForUser signs another user out of every session.
+func (h *SessionHandler) RevokeAllForUser(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.sessionService.RevokeAll(r.Context(), auditContext(r, claims), userID); err != nil {
+		writeSessionError(w, err, "Failed to revoke sessions")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Sessions revoked successfully"})
+}
+
+func writeSessionError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case services.ErrUserNotFound:
+		utils.NotFound(w, "User not found")
+	case services.ErrSessionNotFound:
+		utils.NotFound(w, "Session not found")
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+type SettingsHandler struct {
+	settingsService *services.SettingsService
+	validate        *validator.Validate
+}
+
+func NewSettingsHandler(settingsService *services.SettingsService, validate *validator.Validate) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService: settingsService,
+		validate:        validate,
+	}
+}
+
+func (h *SettingsHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	settings, err := h.settingsService.List(r.Context(), claims.TenantID)
+	if err != nil {
+		utils.InternalError(w, "Failed to list settings")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, settings)
+}
+
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	setting, err := h.settingsService.Get(r.Context(), claims.TenantID, chi.URLParam(r, "key"))
+	if err != nil {
+		utils.NotFound(w, "Setting not found")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, setting)
+}
+
+func (h *SettingsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.PutSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+	req.Key = chi.URLParam(r, "key")
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	setting, err := h.settingsService.Put(r.Context(), &req, auditContext(r, claims))
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, setting)
+}
+
+func (h *SettingsHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.BulkUpdateSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	settings, err := h.settingsService.BulkUpdate(r.Context(), &req, auditContext(r, claims))
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, settings)
+}
+
+func writeSettingsError(w http.ResponseWriter, err error) {
+	switch err {
+	case services.ErrInvalidSettingType:
+		utils.BadRequest(w, "Invalid setting type", map[string]string{"type": "invalid"})
+	case services.ErrInvalidSettingValue:
+		utils.BadRequest(w, "Setting value does not match its type", map[string]string{"value": "invalid"})
+	default:
+		utils.InternalError(w, "Failed to update settings")
+	}
+}
+
+func auditContext(r *http.Request, claims *services.TokenClaims) services.AuditContext {
+	return services.AuditContext{
+		TenantID:  claims.TenantID,
+		UserID:    claims.UserID,
+		IPAddress: r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/models"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type TenantHandler struct {
+	tenantService *services.TenantService
+	validate      *validator.Validate
+}
+
+func NewTenantHandler(tenantService *services.TenantService, validate *validator.Validate) *TenantHandler {
+	return &TenantHandler{
+		tenantService: tenantService,
+		validate:      validate,
+	}
+}
+
+func (h *TenantHandler) List(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	params := &models.ListParams{
+		Page:    page,
+		PerPage: perPage,
+		Sort:    r.URL.Query().Get("sort"),
+		Order:   r.URL.Query().Get("order"),
+		Search:  r.URL.Query().Get("search"),
+		Filters: make(map[string]interface{}),
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		params.Filters["status"] = status
+	}
+
+	result, err := h.tenantService.List(r.Context(), params)
+	if err != nil {
+		utils.InternalError(w, "Failed to list tenants")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, result)
+}
+
+func (h *TenantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.CreateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	tenant, err := h.tenantService.Create(r.Context(), &req, auditContext(r, claims))
+	if err != nil {
+		writeTenantError(w, err, "Failed to create tenant")
+		return
+	}
+
+	utils.JSON(w, http.StatusCreated, tenant)
+}
+
+func (h *TenantHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid tenant ID", nil)
+		return
+	}
+
+	tenant, err := h.tenantService.GetByID(r.Context(), id)
+	if err != nil {
+		utils.NotFound(w, "Tenant not found")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid tenant ID", nil)
+		return
+	}
+
+	var req services.UpdateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return
+	}
+
+	tenant, err := h.tenantService.Update(r.Context(), id, &req, auditContext(r, claims))
+	if err != nil {
+		writeTenantError(w, err, "Failed to update tenant")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid tenant ID", nil)
+		return
+	}
+
+	tenant, err := h.tenantService.Suspend(r.Context(), id, auditContext(r, claims))
+	if err != nil {
+		writeTenantError(w, err, "Failed to suspend tenant")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid tenant ID", nil)
+		return
+	}
+
+	tenant, err := h.tenantService.Activate(r.Context(), id, auditContext(r, claims))
+	if err != nil {
+		writeTenantError(w, err, "Failed to activate tenant")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, tenant)
+}
+
+func writeTenantError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case services.ErrTenantNotFound:
+		utils.NotFound(w, "Tenant not found")
+	case services.ErrTenantSlugExists:
+		utils.Conflict(w, "Tenant slug already exists")
+	case services.ErrInvalidSlug:
+		utils.BadRequest(w, "Slug may only contain lowercase letters, digits and single hyphens", map[string]string{"slug": "slug"})
+	case services.ErrPlatformTenant:
+		utils.Forbidden(w, "The platform tenant cannot be deactivated")
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/services"
+	"admin-panel/internal/testdb"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// TestTenantIsolation requests one tenant's users, roles, settings, feature
+// flags, admin access and audit log entries as an administrator of another
+// tenant. Every request
+// must answer 404 and leave the records untouched.
+func TestTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	auditRepo := repository.NewAuditLogRepository(db)
+	flagRepo := repository.NewFeatureFlagRepository(db)
+	adminAuthRepo := repository.NewAdminAuthRepository(db)
+
+	recorder := services.NewAuditRecorder(auditRepo, zerolog.Nop())
+	settingsService := services.NewSettingsService(settingsRepo, recorder)
+	policyService := services.NewPasswordPolicyService(settingsService, repository.NewPasswordHistoryRepository(db), userRepo, nil, zerolog.Nop())
+	userService := services.NewUserService(userRepo, roleRepo, recorder, policyService)
+	roleService := services.NewRoleService(roleRepo, recorder)
+	auditService := services.NewAuditService(auditRepo)
+
+	validate := validator.New()
+	userHandler := NewUserHandler(userService, nil, validate)
+	roleHandler := NewRoleHandler(roleService, validate)
+	settingsHandler := NewSettingsHandler(settingsService, validate)
+	auditHandler := NewAuditHandler(auditService)
+	flagHandler := NewFeatureFlagHandler(flagRepo, recorder, validate)
+	adminHandler := NewAdminHandler(adminAuthRepo, userRepo, recorder, validate)
+
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	user := &models.User{
+		ID:           uuid.New(),
+		TenantID:     owner.ID,
+		Email:        "isolation@example.com",
+		PasswordHash: "hash",
+		FirstName:    "Isolation",
+		LastName:     "Test",
+		Status:       "active",
+	}
+	if err := userRepo.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := adminAuthRepo.SetAdmin(ctx, owner.ID, user.ID, "owner-hash"); err != nil {
+		t.Fatalf("set admin: %v", err)
+	}
+	role := &models.Role{ID: uuid.New(), TenantID: owner.ID, Name: "Isolation"}
+	if err := roleRepo.Create(ctx, role); err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	setting := &models.Setting{ID: uuid.New(), TenantID: owner.ID, Key: "isolation_probe", Value: "owner", Type: services.SettingTypeString}
+	if err := settingsRepo.Upsert(ctx, setting); err != nil {
+		t.Fatalf("create setting: %v", err)
+	}
+	flag := &models.FeatureFlag{TenantID: owner.ID, Key: "isolation_probe", Name: "Isolation", Enabled: true}
+	if err := flagRepo.Create(ctx, flag); err != nil {
+		t.Fatalf("create feature flag: %v", err)
+	}
+	entry := &models.AuditLog{
+		ID:        uuid.New(),
+		TenantID:  owner.ID,
+		Action:    "create",
+		Resource:  "isolation_probe",
+		IPAddress: "203.0.113.10",
+		UserAgent: "test",
+		CreatedAt: time.Now(),
+	}
+	if err := auditRepo.Log(ctx, entry); err != nil {
+		t.Fatalf("create audit log: %v", err)
+	}
+
+	claims := &services.TokenClaims{UserID: uuid.New(), TenantID: other.ID, Email: "admin@other.example.com"}
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, claims)))
+		})
+	})
+	r.Get("/users/{id}", userHandler.Get)
+	r.Put("/users/{id}", userHandler.Update)
+	r.Delete("/users/{id}", userHandler.Delete)
+	r.Post("/users/{id}/unlock", userHandler.Unlock)
+	r.Post("/users/{id}/set-admin", adminHandler.SetAdmin)
+	r.Get("/users/{id}/admin-status", adminHandler.GetAdminStatus)
+	r.Get("/roles/{id}", roleHandler.Get)
+	r.Put("/roles/{id}", roleHandler.Update)
+	r.Delete("/roles/{id}", roleHandler.Delete)
+	r.Get("/settings/{key}", settingsHandler.Get)
+	r.Get("/feature-flags/{id}", flagHandler.Get)
+	r.Put("/feature-flags/{id}", flagHandler.Update)
+	r.Delete("/feature-flags/{id}", flagHandler.Delete)
+	r.Post("/feature-flags/{id}/toggle", flagHandler.Toggle)
+	r.Get("/audit-logs/{id}", auditHandler.Get)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"get user", http.MethodGet, "/users/" + user.ID.String(), ""},
+		{"update user", http.MethodPut, "/users/" + user.ID.String(), `{"first_name":"Changed"}`},
+		{"delete user", http.MethodDelete, "/users/" + user.ID.String(), ""},
+		{"unlock user", http.MethodPost, "/users/" + user.ID.String() + "/unlock", ""},
+		{"grant admin", http.MethodPost, "/users/" + user.ID.String() + "/set-admin", `{"enabled":true,"password":"changed-password"}`},
+		{"revoke admin", http.MethodPost, "/users/" + user.ID.String() + "/set-admin", `{"enabled":false}`},
+		{"get admin status", http.MethodGet, "/users/" + user.ID.String() + "/admin-status", ""},
+		{"get role", http.MethodGet, "/roles/" + role.ID.String(), ""},
+		{"update role", http.MethodPut, "/roles/" + role.ID.String(), `{"name":"Changed"}`},
+		{"delete role", http.MethodDelete, "/roles/" + role.ID.String(), ""},
+		{"get setting", http.MethodGet, "/settings/" + setting.Key, ""},
+		{"get feature flag", http.MethodGet, "/feature-flags/" + flag.ID.String(), ""},
+		{"update feature flag", http.MethodPut, "/feature-flags/" + flag.ID.String(), `{"name":"Changed","enabled":false}`},
+		{"delete feature flag", http.MethodDelete, "/feature-flags/" + flag.ID.String(), ""},
+		{"toggle feature flag", http.MethodPost, "/feature-flags/" + flag.ID.String() + "/toggle", ""},
+		{"get audit log", http.MethodGet, "/audit-logs/" + entry.ID.String(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s %s: got status %d, want %d: %s", tt.method, tt.path, rec.Code, http.StatusNotFound, rec.Body)
+			}
+		})
+	}
+
+	if got, err := userRepo.GetByID(ctx, owner.ID, user.ID); err != nil || got.FirstName != user.FirstName {
+		t.Errorf("user was changed from another tenant: %+v, %v", got, err)
+	}
+	if got, err := roleRepo.GetByID(ctx, owner.ID, role.ID); err != nil || got.Name != role.Name {
+		t.Errorf("role was changed from another tenant: %+v, %v", got, err)
+	}
+	if got, err := flagRepo.GetByID(ctx, owner.ID, flag.ID); err != nil || got.Name != flag.Name || !got.Enabled {
+		t.Errorf("feature flag was changed from another tenant: %+v, %v", got, err)
+	}
+	if got, err := adminAuthRepo.GetByUserID(ctx, owner.ID, user.ID); err != nil || !got.IsAdmin || got.AdminPasswordHash != "owner-hash" {
+		t.Errorf("admin access was changed from another tenant: %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *services.TwoFactorService
+	validate         *validator.Validate
+}
+
+func NewTwoFactorHandler(twoFactorService *services.TwoFactorService, validate *validator.Validate) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		validate:         validate,
+	}
+}
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	status, err := h.twoFactorService.Status(r.Context(), claims.UserID)
+	if err != nil {
+		utils.InternalError(w, "Failed to get two-factor status")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, status)
+}
+
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(r.Context(), auditContext(r, claims))
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to start two-factor enrollment")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, enrollment)
+}
+
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorService.Confirm(r.Context(), auditContext(r, claims), req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), auditContext(r, claims), req.Code); err != nil {
+		writeTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	req, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), auditContext(r, claims), req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// Reset clears another user's two-factor enrollment.
+func (h *TwoFactorHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.twoFactorService.Reset(r.Context(), auditContext(r, claims), id); err != nil {
+		writeTwoFactorError(w, err, "Failed to reset two-factor authentication")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Two-factor authentication reset",
+		"user_id": id,
+	})
+}
+
+func (h *TwoFactorHandler) decodeCode(w http.ResponseWriter, r *http.Request) (*services.TwoFactorCodeRequest, bool) {
+	var req services.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return nil, false
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case services.ErrUserNotFound:
+		utils.NotFound(w, "User not found")
+	case services.ErrTwoFactorAlreadyEnabled:
+		utils.Conflict(w, "Two-factor authentication is already enabled")
+	case services.ErrTwoFactorNotEnrolled:
+		utils.BadRequest(w, "Start two-factor enrollment first", nil)
+	case services.ErrTwoFactorNotEnabled:
+		utils.BadRequest(w, "Two-factor authentication is not enabled", nil)
+	case services.ErrInvalidTwoFactorCode:
+		utils.BadRequest(w, "Invalid authentication code", map[string]string{"code": "invalid"})
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
@@ -16,14 +16,16 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
-	validate    *validator.Validate
+	userService       *services.UserService
+	invitationService *services.InvitationService
+	validate          *validator.Validate
 }
 
-func NewUserHandler(userService *services.UserService, validate *validator.Validate) *UserHandler {
+func NewUserHandler(userService *services.UserService, invitationService *services.InvitationService, validate *validator.Validate) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		validate:    validate,
+		userService:       userService,
+		invitationService: invitationService,
+		validate:          validate,
 	}
 }
 
@@ -49,12 +51,25 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.Create(r.Context(), &req, claims.TenantID)
+	var user *models.User
+	var err error
+	if req.Invite {
+		user, err = h.invitationService.Invite(r.Context(), auditContext(r, claims), &req)
+	} else {
+		user, err = h.userService.Create(r.Context(), auditContext(r, claims), &req)
+	}
 	if err != nil {
 		if err == services.ErrEmailExists {
 			utils.Conflict(w, "Email already exists")
 			return
 		}
+		if err == services.ErrInvitationNotSent {
+			writeInvitationNotSent(w, user)
+			return
+		}
+		if writePasswordPolicyError(w, err) {
+			return
+		}
 		utils.InternalError(w, "Failed to create user")
 		return
 	}
@@ -63,6 +78,12 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -70,7 +91,7 @@ func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetByID(r.Context(), id)
+	user, err := h.userService.GetByID(r.Context(), claims.TenantID, id)
 	if err != nil {
 		utils.NotFound(w, "User not found")
 		return
@@ -120,6 +141,12 @@ func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -142,13 +169,16 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.Update(r.Context(), id, &req)
+	user, err := h.userService.Update(r.Context(), auditContext(r, claims), id, &req)
 	if err != nil {
-		if err == services.ErrEmailExists {
+		switch err {
+		case services.ErrUserNotFound:
+			utils.NotFound(w, "User not found")
+		case services.ErrEmailExists:
 			utils.Conflict(w, "Email already exists")
-			return
+		default:
+			utils.InternalError(w, "Failed to update user")
 		}
-		utils.InternalError(w, "Failed to update user")
 		return
 	}
 
@@ -156,6 +186,12 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -163,7 +199,11 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.Delete(r.Context(), id); err != nil {
+	if err := h.userService.Delete(r.Context(), auditContext(r, claims), id); err != nil {
+		if err == services.ErrUserNotFound {
+			utils.NotFound(w, "User not found")
+			return
+		}
 		utils.InternalError(w, "Failed to delete user")
 		return
 	}
@@ -172,6 +212,12 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -190,7 +236,14 @@ func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.ResetPassword(r.Context(), id, &req); err != nil {
+	if err := h.userService.ResetPassword(r.Context(), auditContext(r, claims), id, &req); err != nil {
+		if err == services.ErrUserNotFound {
+			utils.NotFound(w, "User not found")
+			return
+		}
+		if writePasswordPolicyError(w, err) {
+			return
+		}
 		utils.InternalError(w, "Failed to reset password")
 		return
 	}
@@ -198,7 +251,54 @@ func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	utils.JSON(w, http.StatusOK, map[string]string{"message": "Password reset successfully"})
 }
 
+func (h *UserHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.userService.Unlock(r.Context(), auditContext(r, claims), id); err != nil {
+		if err == services.ErrUserNotFound {
+			utils.NotFound(w, "User not found")
+			return
+		}
+		utils.InternalError(w, "Failed to unlock user")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "User unlocked successfully"})
+}
+
+func (h *UserHandler) PasswordHashReport(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	report, err := h.userService.PasswordHashReport(r.Context(), claims.TenantID)
+	if err != nil {
+		utils.InternalError(w, "Failed to build password hash report")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, report)
+}
+
 func (h *UserHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -206,8 +306,12 @@ func (h *UserHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roles, err := h.userService.GetUserRoles(r.Context(), id)
+	roles, err := h.userService.GetUserRoles(r.Context(), claims.TenantID, id)
 	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.NotFound(w, "User not found")
+			return
+		}
 		utils.InternalError(w, "Failed to get user roles")
 		return
 	}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"admin-panel/internal/middleware"
+	"admin-panel/internal/services"
+	"admin-panel/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type WebAuthnHandler struct {
+	authService     *services.AuthService
+	webauthnService *services.WebAuthnService
+	validate        *validator.Validate
+}
+
+func NewWebAuthnHandler(authService *services.AuthService, webauthnService *services.WebAuthnService, validate *validator.Validate) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		authService:     authService,
+		webauthnService: webauthnService,
+		validate:        validate,
+	}
+}
+
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	options, err := h.webauthnService.BeginRegistration(r.Context(), auditContext(r, claims))
+	if err != nil {
+		writeWebAuthnError(w, err, "Failed to start passkey registration")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, options)
+}
+
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	var req services.WebAuthnRegistrationRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	cred, err := h.webauthnService.FinishRegistration(r.Context(), auditContext(r, claims), &req)
+	if err != nil {
+		writeWebAuthnError(w, err, "Failed to register passkey")
+		return
+	}
+
+	utils.JSON(w, http.StatusCreated, cred)
+}
+
+func (h *WebAuthnHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	creds, err := h.webauthnService.ListCredentials(r.Context(), claims.TenantID, claims.UserID)
+	if err != nil {
+		utils.InternalError(w, "Failed to list passkeys")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, creds)
+}
+
+func (h *WebAuthnHandler) RevokeCredential(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	credentialID, err := uuid.Parse(chi.URLParam(r, "credentialId"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid passkey ID", nil)
+		return
+	}
+
+	if err := h.webauthnService.RevokeCredential(r.Context(), auditContext(r, claims), claims.UserID, credentialID); err != nil {
+		writeWebAuthnError(w, err, "Failed to revoke passkey")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Passkey revoked"})
+}
+
+// ListUserCredentials lists another user's passkeys for administrators.
+func (h *WebAuthnHandler) ListUserCredentials(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	creds, err := h.webauthnService.ListCredentials(r.Context(), claims.TenantID, id)
+	if err != nil {
+		utils.InternalError(w, "Failed to list passkeys")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, creds)
+}
+
+// RevokeUserCredential revokes another user's passkey, for example one on
+// a lost device.
+func (h *WebAuthnHandler) RevokeUserCredential(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		utils.Unauthorized(w, "Not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid user ID", nil)
+		return
+	}
+
+	credentialID, err := uuid.Parse(chi.URLParam(r, "credentialId"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid passkey ID", nil)
+		return
+	}
+
+	if err := h.webauthnService.RevokeCredential(r.Context(), auditContext(r, claims), id, credentialID); err != nil {
+		writeWebAuthnError(w, err, "Failed to revoke passkey")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Passkey revoked"})
+}
+
+// BeginLogin starts a passkey assertion for a login that returned a
+// two-factor challenge.
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	var req services.WebAuthnChallengeRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	options, err := h.authService.BeginWebAuthnLogin(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		writeWebAuthnError(w, err, "Failed to start passkey login")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, options)
+}
+
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	var req services.WebAuthnLoginRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	resp, err := h.authService.LoginWebAuthn(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		writeWebAuthnError(w, err, "Login failed")
+		return
+	}
+
+	setAuthCookies(w, r, resp.Tokens)
+
+	utils.JSON(w, http.StatusOK, resp)
+}
+
+func (h *WebAuthnHandler) BeginPasswordless(w http.ResponseWriter, r *http.Request) {
+	var req services.PasswordlessBeginRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if req.Tenant == "" {
+		req.Tenant = middleware.GetTenantSlug(r.Context())
+	}
+
+	options, err := h.authService.BeginPasswordlessLogin(r.Context(), req.Tenant)
+	if err != nil {
+		writeWebAuthnError(w, err, "Failed to start passkey login")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, options)
+}
+
+func (h *WebAuthnHandler) FinishPasswordless(w http.ResponseWriter, r *http.Request) {
+	var req services.WebAuthnAssertionRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	resp, err := h.authService.LoginPasswordless(r.Context(), &req, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		writeWebAuthnError(w, err, "Login failed")
+		return
+	}
+
+	setAuthCookies(w, r, resp.Tokens)
+
+	utils.JSON(w, http.StatusOK, resp)
+}
+
+func (h *WebAuthnHandler) decode(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		utils.BadRequest(w, "Invalid request body", nil)
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		details := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			details[e.Field()] = e.Tag()
+		}
+		utils.BadRequest(w, "Validation failed", details)
+		return false
+	}
+
+	return true
+}
+
+func writeWebAuthnError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case services.ErrUserNotFound:
+		utils.NotFound(w, "User not found")
+	case services.ErrWebAuthnCredentialNotFound:
+		utils.NotFound(w, "Passkey not found")
+	case services.ErrWebAuthnNotRegistered:
+		utils.BadRequest(w, "No passkeys are registered for this account", nil)
+	case services.ErrWebAuthnSessionInvalid:
+		utils.BadRequest(w, "Passkey request has expired; please try again", nil)
+	case services.ErrWebAuthnVerification:
+		utils.Unauthorized(w, "Passkey verification failed")
+	case services.ErrInvalidToken:
+		utils.Unauthorized(w, "Login challenge is invalid or has expired")
+	case services.ErrAccountLocked:
+		utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
+	case services.ErrInvalidCredentials:
+		utils.Unauthorized(w, "Invalid login request")
+	case services.ErrUserInactive:
+		utils.Forbidden(w, "Account is inactive")
+	case services.ErrTenantInactive:
+		utils.Forbidden(w, "Organization is not active")
+	default:
+		utils.InternalError(w, fallback)
+	}
+}
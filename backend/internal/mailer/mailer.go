@@ -0,0 +1,149 @@
+// Package mailer delivers transactional email such as password reset
+// links. Senders are chosen by configuration so development setups can
+// write mail to disk or the log instead of an SMTP server.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-panel/internal/config"
+
+	"github.com/rs/zerolog"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a message or returns an error describing why it could
+// not.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns the sender selected by cfg.Driver.
+func New(cfg config.MailConfig, logger zerolog.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPSender(cfg), nil
+	case "file":
+		return NewFileSender(cfg.From, cfg.FileDir)
+	case "log", "":
+		return NewLogSender(cfg.From, logger), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
+
+type SMTPSender struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func NewSMTPSender(cfg config.MailConfig) *SMTPSender {
+	return &SMTPSender{
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		host:     cfg.SMTPHost,
+		from:     cfg.From,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+	}
+}
+
+// Send delivers the message with net/smtp, which upgrades to STARTTLS
+// whenever the server offers it.
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, auth, s.from, []string{msg.To}, format(s.from, msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FileSender writes each message to its own .eml file, for local testing.
+type FileSender struct {
+	from string
+	dir  string
+}
+
+func NewFileSender(from, dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileSender{from: from, dir: dir}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg *Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(s.dir, name), format(s.from, msg), 0o600)
+}
+
+// LogSender writes messages to the application log. It must not be used
+// in production since messages may contain secrets such as reset links.
+type LogSender struct {
+	from   string
+	logger zerolog.Logger
+}
+
+func NewLogSender(from string, logger zerolog.Logger) *LogSender {
+	return &LogSender{from: from, logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg *Message) error {
+	s.logger.Info().
+		Str("from", s.from).
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("mail")
+	return nil
+}
+
+func format(from string, msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", sanitizeHeader(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", sanitizeHeader(msg.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", sanitizeHeader(msg.Subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// sanitizeHeader strips line breaks so values cannot inject extra headers.
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -5,15 +5,16 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"admin-panel/internal/ratelimit"
 	"admin-panel/internal/services"
 	"admin-panel/internal/utils"
 
@@ -65,8 +66,12 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := m.authService.ValidateAccessToken(token)
+		claims, err := m.authService.Authenticate(r.Context(), token)
 		if err != nil {
+			if err == services.ErrTenantInactive {
+				utils.Forbidden(w, "Organization is not active")
+				return
+			}
 			utils.Unauthorized(w, "Invalid or expired token")
 			return
 		}
@@ -96,6 +101,71 @@ func (m *AuthMiddleware) RequirePermission(resource, action string) func(http.Ha
 	}
 }
 
+// RequirePlatformAdmin restricts a route to users of the platform tenant
+// holding the given permission. Platform permissions granted to roles in any
+// other tenant have no effect.
+func (m *AuthMiddleware) RequirePlatformAdmin(resource, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*services.TokenClaims)
+			if !ok {
+				utils.Unauthorized(w, "User not authenticated")
+				return
+			}
+
+			if claims.TenantID != services.PlatformTenantID ||
+				!m.authService.HasPermission(r.Context(), claims.UserID, resource, action) {
+				utils.Forbidden(w, "Insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireElevation restricts a route to requests whose token was elevated
+// through the step-up endpoint within the step-up window. It must run after
+// Authenticate.
+func (m *AuthMiddleware) RequireElevation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(UserContextKey).(*services.TokenClaims)
+		if !ok {
+			utils.Unauthorized(w, "User not authenticated")
+			return
+		}
+
+		if !m.authService.IsElevated(r.Context(), claims) {
+			utils.ErrorResponse(w, http.StatusForbidden, "ELEVATION_REQUIRED", "Re-enter your admin password to continue", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRecentAuth restricts a route to requests whose user re-entered a
+// credential within the step-up window: their own password or second
+// factor through the re-authentication endpoint, or the admin password
+// through step-up. It guards changes any user may make to their own
+// account. It must run after Authenticate.
+func (m *AuthMiddleware) RequireRecentAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(UserContextKey).(*services.TokenClaims)
+		if !ok {
+			utils.Unauthorized(w, "User not authenticated")
+			return
+		}
+
+		if !m.authService.IsRecentlyAuthenticated(r.Context(), claims) {
+			utils.ErrorResponse(w, http.StatusForbidden, "REAUTHENTICATION_REQUIRED", "Confirm your password to continue", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func RequestLogger(logger zerolog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -126,124 +196,117 @@ func RequestLogger(logger zerolog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
-func RateLimiter(requests int, window time.Duration) func(http.Handler) http.Handler {
-	type client struct {
-		count    int
-		lastSeen time.Time
+// RateLimitKeyFunc identifies who a request is counted against. It returns
+// an empty key for requests it cannot identify.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// KeyByIP counts requests per client IP. IPv6 clients are grouped by /64
+// since a single host usually controls a whole prefix.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + normalizeIP(requestIP(r))
+}
+
+// KeyByUser counts requests per authenticated user, falling back to the
+// client IP. It must run after Authenticate.
+func KeyByUser(r *http.Request) string {
+	if claims := GetUserFromContext(r.Context()); claims != nil {
+		return "user:" + claims.UserID.String()
+	}
+	return KeyByIP(r)
+}
+
+// KeyByAPIKey counts requests per X-API-Key header, falling back to the
+// client IP. Only a hash of the key is used.
+func KeyByAPIKey(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return "api_key:" + utils.HashToken(apiKey)
 	}
-	var mu sync.RWMutex
-	clients := make(map[string]*client)
+	return KeyByIP(r)
+}
+
+// RateLimiter allows limit requests per key within name's scope and
+// reports the quota with RateLimit-* headers. Rejected requests get 429
+// with Retry-After. If the limiter fails the request is let through so an
+// unavailable backend cannot take the API down.
+func RateLimiter(name string, limiter ratelimit.Limiter, limit ratelimit.Limit, key RateLimitKeyFunc, logger zerolog.Logger) func(http.Handler) http.Handler {
+	policy := limit.Policy()
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-
-			mu.Lock()
-			c, exists := clients[ip]
-			if !exists {
-				clients[ip] = &client{count: 1, lastSeen: time.Now()}
-				mu.Unlock()
+			k := key(r)
+			if k == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			if time.Since(c.lastSeen) > window {
-				c.count = 1
-				c.lastSeen = time.Now()
-				mu.Unlock()
+			result, err := limiter.Allow(r.Context(), name+"|"+k, limit)
+			if err != nil {
+				logger.Error().Err(err).Str("limit", name).Msg("Rate limiter failed")
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			if c.count >= requests {
-				mu.Unlock()
+			h := w.Header()
+			h.Set("RateLimit-Policy", policy)
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 				utils.ErrorResponse(w, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests", nil)
 				return
 			}
 
-			c.count++
-			mu.Unlock()
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func LoginRateLimiter(maxAttempts int, window time.Duration, lockout time.Duration) func(http.Handler) http.Handler {
-	type client struct {
-		count       int
-		lastAttempt time.Time
-		lockedUntil time.Time
+func ceilSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// TenantResolver determines which tenant an unauthenticated request targets.
+// The X-Tenant header wins, then a subdomain of baseDomain, then
+// defaultSlug.
+func TenantResolver(baseDomain, defaultSlug string) func(http.Handler) http.Handler {
+	suffix := ""
+	if baseDomain != "" {
+		suffix = "." + strings.ToLower(baseDomain)
 	}
-	var mu sync.RWMutex
-	clients := make(map[string]*client)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			bodyBytes, err := io.ReadAll(r.Body)
-			if err != nil {
-				utils.BadRequest(w, "Invalid request body", nil)
-				return
-			}
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-			var payload struct {
-				Email string `json:"email"`
-			}
-			if err := json.Unmarshal(bodyBytes, &payload); err != nil {
-				payload.Email = ""
-			}
-
-			ip := requestIP(r)
-			email := normalizeEmail(payload.Email)
-			key := ip
-			if email != "" {
-				key = ip + "|" + email
-			}
-
-			now := time.Now()
-
-			mu.Lock()
-			c, exists := clients[key]
-			if !exists {
-				clients[key] = &client{count: 1, lastAttempt: now}
-				mu.Unlock()
-				next.ServeHTTP(w, r)
-				return
-			}
+			slug := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Tenant")))
 
-			if now.Before(c.lockedUntil) {
-				mu.Unlock()
-				utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
-				return
-			}
-
-			if now.Sub(c.lastAttempt) > window {
-				c.count = 1
-				c.lastAttempt = now
-				mu.Unlock()
-				next.ServeHTTP(w, r)
-				return
+			if slug == "" && suffix != "" {
+				host := strings.ToLower(r.Host)
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+				if strings.HasSuffix(host, suffix) {
+					sub := strings.TrimSuffix(host, suffix)
+					if sub != "" && !strings.Contains(sub, ".") {
+						slug = sub
+					}
+				}
 			}
 
-			if c.count >= maxAttempts {
-				c.lockedUntil = now.Add(lockout)
-				mu.Unlock()
-				utils.ErrorResponse(w, http.StatusTooManyRequests, "LOCKED_OUT", "Too many login attempts. Please try again later.", nil)
-				return
+			if slug == "" {
+				slug = defaultSlug
 			}
 
-			c.count++
-			c.lastAttempt = now
-			mu.Unlock()
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), TenantContextKey, slug)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func normalizeEmail(email string) string {
-	return strings.ToLower(strings.TrimSpace(email))
-}
-
 func requestIP(r *http.Request) string {
 	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
 		return host
@@ -251,6 +314,22 @@ func requestIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
+// normalizeIP unmaps IPv4-mapped IPv6 addresses and reduces other IPv6
+// addresses to their /64 prefix. Unparseable values are returned as is.
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap().WithZone("")
+	if addr.Is6() {
+		if prefix, err := addr.Prefix(64); err == nil {
+			return prefix.String()
+		}
+	}
+	return addr.String()
+}
+
 type cacheEntry struct {
 	data      []byte
 	expiresAt time.Time
@@ -409,6 +488,16 @@ func GetUserFromContext(ctx context.Context) *services.TokenClaims {
 	return claims
 }
 
+// GetTenantSlug returns the tenant slug set by TenantResolver, or an empty
+// string when the request was not resolved to a tenant.
+func GetTenantSlug(ctx context.Context) string {
+	slug, ok := ctx.Value(TenantContextKey).(string)
+	if !ok {
+		return ""
+	}
+	return slug
+}
+
 func GetRequestID(ctx context.Context) string {
 	requestID, ok := ctx.Value(RequestIDKey).(string)
 	if !ok {
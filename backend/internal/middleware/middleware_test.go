@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"admin-panel/internal/ratelimit"
+
+	"github.com/rs/zerolog"
+)
+
+// stubLimiter answers every request with the same result.
+type stubLimiter struct {
+	result ratelimit.Result
+}
+
+func (l stubLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (*ratelimit.Result, error) {
+	result := l.result
+	return &result, nil
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 10, Window: time.Minute}
+	tests := []struct {
+		name   string
+		result ratelimit.Result
+		status int
+		want   map[string]string
+	}{
+		{
+			"allowed",
+			ratelimit.Result{Allowed: true, Limit: 10, Remaining: 7, Reset: 42500 * time.Millisecond},
+			http.StatusOK,
+			map[string]string{
+				"RateLimit-Policy":    "10;w=60",
+				"RateLimit-Limit":     "10",
+				"RateLimit-Remaining": "7",
+				"RateLimit-Reset":     "43",
+				"Retry-After":         "",
+			},
+		},
+		{
+			"rejected",
+			ratelimit.Result{Limit: 10, Reset: 30 * time.Second, RetryAfter: 9100 * time.Millisecond},
+			http.StatusTooManyRequests,
+			map[string]string{
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "30",
+				"Retry-After":         "10",
+			},
+		},
+		{
+			"rejected for less than a second",
+			ratelimit.Result{Limit: 10, Reset: 0, RetryAfter: 200 * time.Millisecond},
+			http.StatusTooManyRequests,
+			map[string]string{
+				"RateLimit-Reset": "1",
+				"Retry-After":     "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := RateLimiter("test", stubLimiter{tt.result}, limit, KeyByIP, zerolog.Nop())(next)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			for header, want := range tt.want {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
 }
 
 type Role struct {
@@ -63,6 +64,9 @@ type AuditLog struct {
 	IPAddress  string     `json:"ip_address"`
 	UserAgent  string     `json:"user_agent"`
 	CreatedAt  time.Time  `json:"created_at"`
+	Seq        int64      `json:"seq"`
+	PrevHash   string     `json:"prev_hash"`
+	Hash       string     `json:"hash"`
 }
 
 type Setting struct {
@@ -84,13 +88,17 @@ type Tenant struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Session is one refresh token. Rotating the token replaces the row, so
+// all rows of one sign-in share a FamilyID.
 type Session struct {
 	ID              uuid.UUID  `json:"id"`
+	FamilyID        uuid.UUID  `json:"family_id"`
 	UserID          uuid.UUID  `json:"user_id"`
 	RefreshToken    string     `json:"-"`
 	IPAddress       string     `json:"ip_address"`
 	UserAgent       string     `json:"user_agent"`
 	ExpiresAt       time.Time  `json:"expires_at"`
+	AuthenticatedAt time.Time  `json:"authenticated_at"`
 	CreatedAt       time.Time  `json:"created_at"`
 	RotatedAt       *time.Time `json:"rotated_at,omitempty"`
 	ReplacedByToken *string    `json:"-"`
@@ -106,6 +114,218 @@ type AdminAuth struct {
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
+type UserTOTP struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	Enabled      bool       `json:"enabled"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// UserInvitation records an invitation emailed to a pending user.
+type UserInvitation struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	InvitedBy  *uuid.UUID `json:"invited_by,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// WebAuthnCredential is a passkey or security key registered to a user.
+type WebAuthnCredential struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	Algorithm    int64      `json:"algorithm"`
+	SignCount    uint32     `json:"-"`
+	Transports   []string   `json:"transports"`
+	AAGUID       []byte     `json:"-"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebAuthnSession is the server-side state of an in-progress ceremony.
+type WebAuthnSession struct {
+	ID        uuid.UUID
+	TenantID  uuid.UUID
+	UserID    *uuid.UUID
+	Ceremony  string
+	Challenge []byte
+	ExpiresAt time.Time
+}
+
+// AuditLogFilter selects and orders audit log entries. Empty fields match
+// every entry. From is inclusive and To exclusive. OldValuePath and
+// NewValuePath are SQL/JSON path predicates the snapshot must satisfy;
+// OldValue and NewValue map dotted field paths to JSON values the snapshot
+// must contain.
+type AuditLogFilter struct {
+	TenantID     uuid.UUID              `json:"-"`
+	Search       string                 `json:"search,omitempty"`
+	Actions      []string               `json:"actions,omitempty"`
+	Resource     string                 `json:"resource,omitempty"`
+	UserID       *uuid.UUID             `json:"user_id,omitempty"`
+	ResourceID   *uuid.UUID             `json:"resource_id,omitempty"`
+	IPAddress    string                 `json:"ip,omitempty"`
+	From         *time.Time             `json:"from,omitempty"`
+	To           *time.Time             `json:"to,omitempty"`
+	OldValuePath string                 `json:"old_path,omitempty"`
+	NewValuePath string                 `json:"new_path,omitempty"`
+	OldValue     map[string]interface{} `json:"old,omitempty"`
+	NewValue     map[string]interface{} `json:"new,omitempty"`
+	Sort         string                 `json:"sort,omitempty"`
+	Order        string                 `json:"order,omitempty"`
+}
+
+// AuditSink forwards a tenant's audit log entries to an external system.
+// Pending and Failed count the entries queued for the sink and those that
+// exhausted their retries.
+type AuditSink struct {
+	ID              uuid.UUID       `json:"id"`
+	TenantID        uuid.UUID       `json:"tenant_id"`
+	Name            string          `json:"name"`
+	Type            string          `json:"type"`
+	Config          AuditSinkConfig `json:"config"`
+	Secret          *string         `json:"-"`
+	Enabled         bool            `json:"enabled"`
+	Pending         int64           `json:"pending"`
+	Failed          int64           `json:"failed"`
+	LastDeliveredAt *time.Time      `json:"last_delivered_at,omitempty"`
+	LastError       *string         `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time      `json:"last_error_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// AuditSinkConfig holds the settings of a sink. Network, Address, Facility
+// and AppName apply to syslog sinks, URL to webhooks and FileName to file
+// sinks.
+type AuditSinkConfig struct {
+	Network  string `json:"network,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Facility *int   `json:"facility,omitempty"`
+	AppName  string `json:"app_name,omitempty"`
+	URL      string `json:"url,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+}
+
+// AuditOutboxEntry is an audit log entry queued for delivery to a sink.
+// Payload is the entry as JSON.
+type AuditOutboxEntry struct {
+	ID            int64     `json:"id"`
+	SinkID        uuid.UUID `json:"sink_id"`
+	AuditLogID    uuid.UUID `json:"audit_log_id"`
+	Payload       string    `json:"payload"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     *string   `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AuditExport is a file export of audit log entries produced in the
+// background. DownloadURL is set once the file is ready.
+type AuditExport struct {
+	ID          uuid.UUID      `json:"id"`
+	TenantID    uuid.UUID      `json:"tenant_id"`
+	RequestedBy *uuid.UUID     `json:"requested_by,omitempty"`
+	Format      string         `json:"format"`
+	Filter      AuditLogFilter `json:"filter"`
+	Status      string         `json:"status"`
+	FileName    string         `json:"-"`
+	RowCount    int64          `json:"row_count"`
+	SizeBytes   int64          `json:"size_bytes"`
+	Error       *string        `json:"error,omitempty"`
+	DownloadURL string         `json:"download_url,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	StartedAt   *time.Time     `json:"started_at,omitempty"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+}
+
+// AuditLogCursor is the position after which a keyset-paginated audit log
+// listing continues: the sort column value and ID of the last entry seen.
+// Value is a time.Time when ordering by created_at and a string otherwise.
+type AuditLogCursor struct {
+	Value interface{}
+	ID    uuid.UUID
+}
+
+// AuditArchive describes a compressed JSONL file holding audit log entries
+// removed by the retention job.
+type AuditArchive struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	FileName  string    `json:"-"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	RowCount  int64     `json:"row_count"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	LastSeq   int64     `json:"last_seq,omitempty"`
+	LastHash  string    `json:"last_hash,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditChainHead is the state of a tenant's audit hash chain: its last
+// entry, and the last entry removed by the retention job, which the oldest
+// remaining entry links to.
+type AuditChainHead struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	Seq        int64     `json:"seq"`
+	Hash       string    `json:"hash"`
+	AnchorSeq  int64     `json:"anchor_seq"`
+	AnchorHash string    `json:"anchor_hash"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AuditChainAnchor records a prune of a tenant's audit chain: the last
+// removed entry, which the remaining chain links to, and the archive the
+// removed entries were written to.
+type AuditChainAnchor struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	Seq         int64     `json:"seq"`
+	Hash        string    `json:"hash"`
+	ArchiveID   uuid.UUID `json:"archive_id"`
+	PrunedCount int64     `json:"pruned_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AuditCheckpoint is a signed statement that a tenant's audit chain ended
+// in Hash at Seq.
+type AuditCheckpoint struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JobRun records one run of a background job.
+type JobRun struct {
+	ID          uuid.UUID  `json:"id"`
+	JobName     string     `json:"job_name"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	Trigger     string     `json:"trigger"`
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty"`
+	Status      string     `json:"status"`
+	Instance    string     `json:"instance"`
+	Affected    int64      `json:"affected"`
+	Error       *string    `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
 type FeatureFlag struct {
 	ID          uuid.UUID `json:"id"`
 	TenantID    uuid.UUID `json:"tenant_id"`
@@ -132,6 +352,14 @@ type PaginatedResponse struct {
 	TotalPages int         `json:"total_pages"`
 }
 
+// CursorPage is one page of a keyset-paginated listing. NextCursor is
+// passed back to fetch the following page and is empty on the last one.
+type CursorPage struct {
+	Data       interface{} `json:"data"`
+	PerPage    int         `json:"per_page"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
 type ListParams struct {
 	Page     int
 	PerPage  int
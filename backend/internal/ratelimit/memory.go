@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps counters in process memory. A janitor goroutine
+// evicts counters that have been idle for longer than their window, so
+// memory use follows the number of active clients rather than every
+// client ever seen.
+type MemoryLimiter struct {
+	algorithm string
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	stop      chan struct{}
+	stopOnce  sync.Once
+	// now returns the current time; tests replace it to control the clock.
+	now func() time.Time
+}
+
+type memoryEntry struct {
+	// Sliding window: counts in the current and previous fixed windows.
+	windowStart time.Time
+	current     int
+	previous    int
+
+	// Token bucket: tokens left as of updated.
+	tokens  float64
+	updated time.Time
+
+	window   time.Duration
+	lastSeen time.Time
+}
+
+// NewMemoryLimiter returns a limiter using algorithm whose janitor runs
+// every cleanupInterval. Call Close to stop the janitor.
+func NewMemoryLimiter(algorithm string, cleanupInterval time.Duration) (*MemoryLimiter, error) {
+	switch algorithm {
+	case AlgorithmSlidingWindow, AlgorithmTokenBucket:
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+	}
+
+	l := &MemoryLimiter{
+		algorithm: algorithm,
+		entries:   make(map[string]*memoryEntry),
+		stop:      make(chan struct{}),
+		now:       time.Now,
+	}
+	go l.janitor(cleanupInterval)
+	return l, nil
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &memoryEntry{tokens: float64(limit.Requests), updated: now}
+		l.entries[key] = entry
+	}
+	entry.window = limit.Window
+	entry.lastSeen = now
+
+	if l.algorithm == AlgorithmTokenBucket {
+		return entry.tokenBucket(now, limit), nil
+	}
+	return entry.slidingWindow(now, limit), nil
+}
+
+// slidingWindow approximates a true sliding window by weighting the
+// previous fixed window's count by how much of it still overlaps.
+func (e *memoryEntry) slidingWindow(now time.Time, limit Limit) *Result {
+	windowStart := now.Truncate(limit.Window)
+	switch {
+	case windowStart.Equal(e.windowStart):
+	case windowStart.Equal(e.windowStart.Add(limit.Window)):
+		e.previous, e.current = e.current, 0
+	default:
+		e.previous, e.current = 0, 0
+	}
+	e.windowStart = windowStart
+
+	return slidingWindowResult(now, windowStart, limit, e.current, e.previous, func() { e.current++ })
+}
+
+func (e *memoryEntry) tokenBucket(now time.Time, limit Limit) *Result {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds()
+
+	e.tokens = math.Min(capacity, e.tokens+now.Sub(e.updated).Seconds()*rate)
+	e.updated = now
+
+	result := &Result{Limit: limit.Requests}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - e.tokens) / rate)
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = secondsToDuration((capacity - e.tokens) / rate)
+	return result
+}
+
+// Close stops the janitor.
+func (l *MemoryLimiter) Close() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
+func (l *MemoryLimiter) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.evict(l.now())
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// evict drops counters idle for more than two windows; by then neither
+// algorithm remembers anything about them.
+func (l *MemoryLimiter) evict(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastSeen) > 2*entry.window {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// slidingWindowResult decides a sliding window request given the counts of
+// the current and previous fixed windows, calling count when the request
+// is allowed.
+func slidingWindowResult(now, windowStart time.Time, limit Limit, current, previous int, count func()) *Result {
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(limit.Window)
+	estimated := float64(previous)*weight + float64(current)
+
+	result := &Result{Limit: limit.Requests, Reset: limit.Window - elapsed}
+	if estimated+1 <= float64(limit.Requests) {
+		count()
+		estimated++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = slidingWindowRetryAfter(elapsed, limit, current, previous)
+	}
+
+	result.Remaining = limit.Requests - int(math.Ceil(estimated))
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result
+}
+
+// slidingWindowRetryAfter estimates when the weighted count will have
+// dropped far enough to admit one more request.
+func slidingWindowRetryAfter(elapsed time.Duration, limit Limit, current, previous int) time.Duration {
+	room := float64(limit.Requests - 1 - current)
+	if room < 0 || previous == 0 {
+		// Nothing left of the previous window to decay; wait for the next.
+		return limit.Window - elapsed
+	}
+	// previous * (1 - t/window) <= room  =>  t >= window * (1 - room/previous)
+	wait := time.Duration(float64(limit.Window)*(1-room/float64(previous))) - elapsed
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter(t *testing.T, algorithm string) (*MemoryLimiter, *fakeClock) {
+	t.Helper()
+	l, err := NewMemoryLimiter(algorithm, time.Hour)
+	if err != nil {
+		t.Fatalf("NewMemoryLimiter: %v", err)
+	}
+	t.Cleanup(l.Close)
+
+	clock := &fakeClock{now: time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)}
+	l.now = clock.Now
+	return l, clock
+}
+
+// limiterStep is one request made after advancing the clock by advance,
+// and the result it should get.
+type limiterStep struct {
+	advance time.Duration
+	want    Result
+}
+
+func runSteps(t *testing.T, l *MemoryLimiter, clock *fakeClock, limit Limit, steps []limiterStep) {
+	t.Helper()
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		got, err := l.Allow(context.Background(), "client", limit)
+		if err != nil {
+			t.Fatalf("request %d: Allow: %v", i+1, err)
+		}
+		if *got != step.want {
+			t.Errorf("request %d at +%s: got %+v, want %+v", i+1, clock.now.Sub(time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)), *got, step.want)
+		}
+	}
+}
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	l, clock := newTestLimiter(t, AlgorithmSlidingWindow)
+	limit := Limit{Requests: 3, Window: time.Minute}
+
+	runSteps(t, l, clock, limit, []limiterStep{
+		{0, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Minute}},
+		{0, Result{Allowed: true, Limit: 3, Remaining: 1, Reset: time.Minute}},
+		{0, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: time.Minute}},
+		{0, Result{Limit: 3, Reset: time.Minute, RetryAfter: time.Minute}},
+		// Halfway through the next window the previous one weighs 1.5.
+		{90 * time.Second, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+		{0, Result{Limit: 3, Reset: 30 * time.Second, RetryAfter: 10 * time.Second}},
+		// Retrying exactly when told to succeeds: 3 * 1/3 + 1 + 1 = 3.
+		{10 * time.Second, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 20 * time.Second}},
+		// After a whole idle window nothing is remembered.
+		{2 * time.Minute, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 20 * time.Second}},
+	})
+}
+
+func TestMemoryLimiterTokenBucket(t *testing.T) {
+	l, clock := newTestLimiter(t, AlgorithmTokenBucket)
+	limit := Limit{Requests: 2, Window: 10 * time.Second}
+
+	runSteps(t, l, clock, limit, []limiterStep{
+		{0, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second}},
+		{0, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}},
+		{0, Result{Limit: 2, Reset: 10 * time.Second, RetryAfter: 5 * time.Second}},
+		{5 * time.Second, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second}},
+		{2500 * time.Millisecond, Result{Limit: 2, Reset: 7500 * time.Millisecond, RetryAfter: 2500 * time.Millisecond}},
+		// The bucket never holds more than its capacity.
+		{time.Hour, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second}},
+	})
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(t, AlgorithmSlidingWindow)
+	limit := Limit{Requests: 1, Window: time.Minute}
+	ctx := context.Background()
+
+	if result, _ := l.Allow(ctx, "a", limit); !result.Allowed {
+		t.Fatal("first request for a was rejected")
+	}
+	if result, _ := l.Allow(ctx, "a", limit); result.Allowed {
+		t.Error("second request for a was allowed")
+	}
+	if result, _ := l.Allow(ctx, "b", limit); !result.Allowed {
+		t.Error("first request for b was rejected")
+	}
+}
+
+func TestMemoryLimiterEvict(t *testing.T) {
+	l, clock := newTestLimiter(t, AlgorithmSlidingWindow)
+	ctx := context.Background()
+
+	l.Allow(ctx, "short", Limit{Requests: 1, Window: time.Minute})
+	l.Allow(ctx, "long", Limit{Requests: 1, Window: time.Hour})
+
+	clock.Advance(3 * time.Minute)
+	l.evict(clock.Now())
+
+	if _, ok := l.entries["short"]; ok {
+		t.Error("counter idle for three of its windows was kept")
+	}
+	if _, ok := l.entries["long"]; !ok {
+		t.Error("counter still within its window was evicted")
+	}
+}
+
+func TestSlidingWindowRetryAfter(t *testing.T) {
+	limit := Limit{Requests: 3, Window: time.Minute}
+	tests := []struct {
+		name              string
+		elapsed           time.Duration
+		current, previous int
+		want              time.Duration
+	}{
+		{"current window full", 10 * time.Second, 3, 5, 50 * time.Second},
+		{"no previous window", 10 * time.Second, 2, 0, 50 * time.Second},
+		{"previous window decays", 30 * time.Second, 1, 3, 10 * time.Second},
+		{"previous window must fully decay", 0, 2, 4, time.Minute},
+		{"already decayed", 50 * time.Second, 1, 3, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slidingWindowRetryAfter(tt.elapsed, limit, tt.current, tt.previous); got != tt.want {
+				t.Errorf("slidingWindowRetryAfter(%s, %d, %d) = %s, want %s", tt.elapsed, tt.current, tt.previous, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountedResult(t *testing.T) {
+	windowStart := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 3, Window: time.Minute}
+	tests := []struct {
+		name              string
+		elapsed           time.Duration
+		current, previous int
+		wantCounted       bool
+		want              Result
+	}{
+		{"first request", 0, 1, 0, true, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Minute}},
+		{"last request", 0, 3, 0, true, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: time.Minute}},
+		{"over the limit", 15 * time.Second, 4, 0, false, Result{Limit: 3, Reset: 45 * time.Second, RetryAfter: 45 * time.Second}},
+		{"previous window still too heavy", 30 * time.Second, 2, 3, false, Result{Limit: 3, Reset: 30 * time.Second, RetryAfter: 10 * time.Second}},
+		{"previous window decayed enough", 40 * time.Second, 2, 3, true, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 20 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, counted := countedResult(windowStart.Add(tt.elapsed), windowStart, limit, tt.current, tt.previous)
+			if counted != tt.wantCounted {
+				t.Errorf("counted = %v, want %v", counted, tt.wantCounted)
+			}
+			if *got != tt.want {
+				t.Errorf("result = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitPolicy(t *testing.T) {
+	if got := (Limit{Requests: 100, Window: time.Minute}).Policy(); got != "100;w=60" {
+		t.Errorf("Policy() = %q, want %q", got, "100;w=60")
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"admin-panel/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+// PostgresLimiter keeps sliding window counters in Postgres so that every
+// replica enforces the same limits. Each request costs one round trip.
+type PostgresLimiter struct {
+	repo     *repository.RateLimitRepository
+	logger   zerolog.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPostgresLimiter returns a limiter backed by repo that deletes expired
+// counters every cleanupInterval. Call Close to stop the cleanup.
+func NewPostgresLimiter(repo *repository.RateLimitRepository, cleanupInterval time.Duration, logger zerolog.Logger) *PostgresLimiter {
+	l := &PostgresLimiter{
+		repo:   repo,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+	go l.janitor(cleanupInterval)
+	return l
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now()
+	windowStart := now.Truncate(limit.Window)
+
+	current, previous, err := l.repo.Hit(ctx, key, windowStart, limit.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	result, counted := countedResult(now, windowStart, limit, current, previous)
+	if !counted {
+		if err := l.repo.Undo(ctx, key, windowStart); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// countedResult decides a request that Hit has already counted, given the
+// counts Hit returned: as if it had not been counted yet. It reports
+// whether the request stays counted; a rejected one must be taken back.
+func countedResult(now, windowStart time.Time, limit Limit, current, previous int) (*Result, bool) {
+	counted := false
+	result := slidingWindowResult(now, windowStart, limit, current-1, previous, func() { counted = true })
+	return result, counted
+}
+
+// Close stops the cleanup of expired counters.
+func (l *PostgresLimiter) Close() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
+func (l *PostgresLimiter) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.repo.DeleteExpired(context.Background()); err != nil {
+				l.logger.Error().Err(err).Msg("Failed to delete expired rate limit counters")
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
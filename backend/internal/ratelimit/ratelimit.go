@@ -0,0 +1,45 @@
+// Package ratelimit counts requests per key, such as a client IP, user or
+// API key, against a limit. Limiters either keep their counters in memory,
+// which is fast but private to one process, or in Postgres so every
+// replica shares them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmTokenBucket   = "token_bucket"
+)
+
+// Limit allows Requests per Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Policy renders the limit as a RateLimit-Policy header value.
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Window.Seconds()))
+}
+
+// Result is the outcome of counting one request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the quota reported by Remaining is
+	// replenished.
+	Reset time.Duration
+	// RetryAfter is how long a rejected client should wait before the next
+	// request can succeed. It is zero for allowed requests.
+	RetryAfter time.Duration
+}
+
+// Limiter counts a request for key against limit.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
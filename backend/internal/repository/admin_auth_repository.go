@@ -18,15 +18,16 @@ func NewAdminAuthRepository(pool *pgxpool.Pool) *AdminAuthRepository {
 	return &AdminAuthRepository{pool: pool}
 }
 
-func (r *AdminAuthRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.AdminAuth, error) {
+func (r *AdminAuthRepository) GetByUserID(ctx context.Context, tenantID, userID uuid.UUID) (*models.AdminAuth, error) {
 	query := `
-		SELECT user_id, admin_password_hash, is_admin, enabled_at, created_at, updated_at
-		FROM admin_auth
-		WHERE user_id = $1
+		SELECT a.user_id, a.admin_password_hash, a.is_admin, a.enabled_at, a.created_at, a.updated_at
+		FROM admin_auth a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND u.tenant_id = $2
 	`
 
 	var auth models.AdminAuth
-	err := r.pool.QueryRow(ctx, query, userID).Scan(
+	err := r.pool.QueryRow(ctx, query, userID, tenantID).Scan(
 		&auth.UserID,
 		&auth.AdminPasswordHash,
 		&auth.IsAdmin,
@@ -41,11 +42,11 @@ func (r *AdminAuthRepository) GetByUserID(ctx context.Context, userID uuid.UUID)
 	return &auth, nil
 }
 
-func (r *AdminAuthRepository) SetAdmin(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+func (r *AdminAuthRepository) SetAdmin(ctx context.Context, tenantID, userID uuid.UUID, passwordHash string) error {
 	now := time.Now()
 	query := `
 		INSERT INTO admin_auth (user_id, admin_password_hash, is_admin, enabled_at, created_at, updated_at)
-		VALUES ($1, $2, TRUE, $3, $3, $3)
+		SELECT id, $2, TRUE, $3, $3, $3 FROM users WHERE id = $1 AND tenant_id = $4
 		ON CONFLICT (user_id) DO UPDATE SET
 			admin_password_hash = EXCLUDED.admin_password_hash,
 			is_admin = TRUE,
@@ -53,28 +54,39 @@ func (r *AdminAuthRepository) SetAdmin(ctx context.Context, userID uuid.UUID, pa
 			updated_at = $3
 	`
 
-	_, err := r.pool.Exec(ctx, query, userID, passwordHash, now)
-	return err
+	tag, err := r.pool.Exec(ctx, query, userID, passwordHash, now, tenantID)
+	return requireAffected(tag, err)
+}
+
+// UpdatePasswordHash replaces the admin password hash with an upgraded hash
+// of the same password, unless it has changed in the meantime.
+func (r *AdminAuthRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, oldHash, newHash string) error {
+	query := `UPDATE admin_auth SET admin_password_hash = $3 WHERE user_id = $1 AND admin_password_hash = $2`
+	tag, err := r.pool.Exec(ctx, query, userID, oldHash, newHash)
+	return requireAffected(tag, err)
 }
 
-func (r *AdminAuthRepository) UnsetAdmin(ctx context.Context, userID uuid.UUID) error {
+func (r *AdminAuthRepository) UnsetAdmin(ctx context.Context, tenantID, userID uuid.UUID) error {
 	query := `
-		UPDATE admin_auth
+		UPDATE admin_auth a
 		SET is_admin = FALSE, updated_at = NOW()
-		WHERE user_id = $1
+		FROM users u
+		WHERE a.user_id = u.id AND a.user_id = $1 AND u.tenant_id = $2
 	`
 
-	_, err := r.pool.Exec(ctx, query, userID)
+	_, err := r.pool.Exec(ctx, query, userID, tenantID)
 	return err
 }
 
-func (r *AdminAuthRepository) IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+func (r *AdminAuthRepository) IsAdmin(ctx context.Context, tenantID, userID uuid.UUID) (bool, error) {
 	query := `
-		SELECT is_admin FROM admin_auth WHERE user_id = $1
+		SELECT a.is_admin FROM admin_auth a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND u.tenant_id = $2
 	`
 
 	var isAdmin bool
-	err := r.pool.QueryRow(ctx, query, userID).Scan(&isAdmin)
+	err := r.pool.QueryRow(ctx, query, userID, tenantID).Scan(&isAdmin)
 	if err != nil {
 		return false, nil
 	}
@@ -82,8 +94,11 @@ func (r *AdminAuthRepository) IsAdmin(ctx context.Context, userID uuid.UUID) (bo
 	return isAdmin, nil
 }
 
-func (r *AdminAuthRepository) Delete(ctx context.Context, userID uuid.UUID) error {
-	query := `DELETE FROM admin_auth WHERE user_id = $1`
-	_, err := r.pool.Exec(ctx, query, userID)
+func (r *AdminAuthRepository) Delete(ctx context.Context, tenantID, userID uuid.UUID) error {
+	query := `
+		DELETE FROM admin_auth a USING users u
+		WHERE a.user_id = u.id AND a.user_id = $1 AND u.tenant_id = $2
+	`
+	_, err := r.pool.Exec(ctx, query, userID, tenantID)
 	return err
 }
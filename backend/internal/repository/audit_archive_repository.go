@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditArchiveRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditArchiveRepository(db *pgxpool.Pool) *AuditArchiveRepository {
+	return &AuditArchiveRepository{db: db}
+}
+
+func (r *AuditArchiveRepository) Create(ctx context.Context, archive *models.AuditArchive) error {
+	query := `
+		INSERT INTO audit_archives (id, tenant_id, file_name, from_time, to_time, row_count, size_bytes, sha256, last_seq, last_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := r.db.Exec(ctx, query,
+		archive.ID, archive.TenantID, archive.FileName, archive.From, archive.To,
+		archive.RowCount, archive.SizeBytes, archive.SHA256, archive.LastSeq, archive.LastHash, archive.CreatedAt,
+	)
+	return err
+}
+
+// List returns a tenant's archives, newest first.
+func (r *AuditArchiveRepository) List(ctx context.Context, tenantID uuid.UUID) ([]*models.AuditArchive, error) {
+	query := `
+		SELECT id, tenant_id, file_name, from_time, to_time, row_count, size_bytes, sha256,
+			COALESCE(last_seq, 0), COALESCE(last_hash, ''), created_at
+		FROM audit_archives
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var archives []*models.AuditArchive
+	for rows.Next() {
+		archive := &models.AuditArchive{}
+		if err := rows.Scan(
+			&archive.ID, &archive.TenantID, &archive.FileName, &archive.From, &archive.To,
+			&archive.RowCount, &archive.SizeBytes, &archive.SHA256, &archive.LastSeq, &archive.LastHash, &archive.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		archives = append(archives, archive)
+	}
+	return archives, rows.Err()
+}
+
+func (r *AuditArchiveRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditArchive, error) {
+	query := `
+		SELECT id, tenant_id, file_name, from_time, to_time, row_count, size_bytes, sha256,
+			COALESCE(last_seq, 0), COALESCE(last_hash, ''), created_at
+		FROM audit_archives
+		WHERE tenant_id = $1 AND id = $2
+	`
+	archive := &models.AuditArchive{}
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(
+		&archive.ID, &archive.TenantID, &archive.FileName, &archive.From, &archive.To,
+		&archive.RowCount, &archive.SizeBytes, &archive.SHA256, &archive.LastSeq, &archive.LastHash, &archive.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditCheckpointRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditCheckpointRepository(db *pgxpool.Pool) *AuditCheckpointRepository {
+	return &AuditCheckpointRepository{db: db}
+}
+
+func (r *AuditCheckpointRepository) Create(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	query := `
+		INSERT INTO audit_checkpoints (id, tenant_id, seq, hash, key_id, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(ctx, query,
+		checkpoint.ID, checkpoint.TenantID, checkpoint.Seq, checkpoint.Hash,
+		checkpoint.KeyID, checkpoint.Signature, checkpoint.CreatedAt,
+	)
+	return err
+}
+
+// List returns up to limit of a tenant's checkpoints, latest first.
+func (r *AuditCheckpointRepository) List(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.AuditCheckpoint, error) {
+	query := `
+		SELECT id, tenant_id, seq, hash, key_id, signature, created_at
+		FROM audit_checkpoints
+		WHERE tenant_id = $1
+		ORDER BY seq DESC, created_at DESC
+		LIMIT $2
+	`
+	return r.query(ctx, query, tenantID, limit)
+}
+
+// ListAfter returns a tenant's checkpoints after the given sequence number,
+// in chain order.
+func (r *AuditCheckpointRepository) ListAfter(ctx context.Context, tenantID uuid.UUID, afterSeq int64) ([]*models.AuditCheckpoint, error) {
+	query := `
+		SELECT id, tenant_id, seq, hash, key_id, signature, created_at
+		FROM audit_checkpoints
+		WHERE tenant_id = $1 AND seq > $2
+		ORDER BY seq, created_at
+	`
+	return r.query(ctx, query, tenantID, afterSeq)
+}
+
+func (r *AuditCheckpointRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.AuditCheckpoint, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*models.AuditCheckpoint
+	for rows.Next() {
+		checkpoint := &models.AuditCheckpoint{}
+		if err := rows.Scan(
+			&checkpoint.ID, &checkpoint.TenantID, &checkpoint.Seq, &checkpoint.Hash,
+			&checkpoint.KeyID, &checkpoint.Signature, &checkpoint.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditExportRepository(db *pgxpool.Pool) *AuditExportRepository {
+	return &AuditExportRepository{db: db}
+}
+
+const auditExportColumns = `id, tenant_id, requested_by, format, filter, status, COALESCE(file_name, ''),
+	row_count, size_bytes, error, created_at, started_at, finished_at, expires_at`
+
+func (r *AuditExportRepository) Create(ctx context.Context, export *models.AuditExport) error {
+	query := `
+		INSERT INTO audit_exports (id, tenant_id, requested_by, format, filter, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(ctx, query,
+		export.ID, export.TenantID, export.RequestedBy, export.Format, export.Filter, export.Status, export.CreatedAt,
+	)
+	return err
+}
+
+func (r *AuditExportRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditExport, error) {
+	query := `SELECT ` + auditExportColumns + ` FROM audit_exports WHERE tenant_id = $1 AND id = $2`
+	return scanAuditExport(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+// List returns up to limit of a tenant's exports, latest first.
+func (r *AuditExportRepository) List(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.AuditExport, error) {
+	query := `
+		SELECT ` + auditExportColumns + `
+		FROM audit_exports
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*models.AuditExport
+	for rows.Next() {
+		export, err := scanAuditExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+// ClaimNext marks the oldest pending export as running and returns it. It
+// returns pgx.ErrNoRows when no export is pending.
+func (r *AuditExportRepository) ClaimNext(ctx context.Context, startedAt time.Time) (*models.AuditExport, error) {
+	query := `
+		UPDATE audit_exports SET status = 'running', started_at = $1
+		WHERE id = (
+			SELECT id FROM audit_exports
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + auditExportColumns
+	return scanAuditExport(r.db.QueryRow(ctx, query, startedAt))
+}
+
+// Finish records the outcome of a running export.
+func (r *AuditExportRepository) Finish(ctx context.Context, export *models.AuditExport) error {
+	query := `
+		UPDATE audit_exports
+		SET status = $2, file_name = NULLIF($3, ''), row_count = $4, size_bytes = $5, error = $6,
+			finished_at = $7, expires_at = $8
+		WHERE id = $1
+	`
+	return requireAffected(r.db.Exec(ctx, query,
+		export.ID, export.Status, export.FileName, export.RowCount, export.SizeBytes, export.Error,
+		export.FinishedAt, export.ExpiresAt,
+	))
+}
+
+// FailRunning marks every running export as failed with the given message.
+func (r *AuditExportRepository) FailRunning(ctx context.Context, message string) (int64, error) {
+	query := `
+		UPDATE audit_exports SET status = 'failed', error = $1, finished_at = NOW()
+		WHERE status = 'running'
+	`
+	tag, err := r.db.Exec(ctx, query, message)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpired removes exports that expired before the given time and
+// returns the names of their files.
+func (r *AuditExportRepository) DeleteExpired(ctx context.Context, before time.Time) ([]string, error) {
+	query := `
+		DELETE FROM audit_exports WHERE expires_at < $1
+		RETURNING COALESCE(file_name, '')
+	`
+	rows, err := r.db.Query(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileNames []string
+	for rows.Next() {
+		var fileName string
+		if err := rows.Scan(&fileName); err != nil {
+			return nil, err
+		}
+		if fileName != "" {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	return fileNames, rows.Err()
+}
+
+func scanAuditExport(row pgx.Row) (*models.AuditExport, error) {
+	export := &models.AuditExport{}
+	err := row.Scan(
+		&export.ID, &export.TenantID, &export.RequestedBy, &export.Format, &export.Filter, &export.Status, &export.FileName,
+		&export.RowCount, &export.SizeBytes, &export.Error, &export.CreatedAt, &export.StartedAt, &export.FinishedAt, &export.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	export.Filter.TenantID = export.TenantID
+	return export, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditOutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditOutboxRepository(db *pgxpool.Pool) *AuditOutboxRepository {
+	return &AuditOutboxRepository{db: db}
+}
+
+// ListPending returns up to limit of a sink's pending entries in the order
+// they were queued, whether or not they are due.
+func (r *AuditOutboxRepository) ListPending(ctx context.Context, sinkID uuid.UUID, limit int) ([]*models.AuditOutboxEntry, error) {
+	query := `
+		SELECT id, sink_id, audit_log_id, payload, status, attempts, next_attempt_at, last_error, created_at
+		FROM audit_outbox
+		WHERE sink_id = $1 AND status = 'pending'
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, sinkID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditOutboxEntry
+	for rows.Next() {
+		entry := &models.AuditOutboxEntry{}
+		if err := rows.Scan(
+			&entry.ID, &entry.SinkID, &entry.AuditLogID, &entry.Payload, &entry.Status, &entry.Attempts,
+			&entry.NextAttemptAt, &entry.LastError, &entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Delete removes delivered entries.
+func (r *AuditOutboxRepository) Delete(ctx context.Context, ids []int64) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM audit_outbox WHERE id = ANY($1)", ids)
+	return err
+}
+
+// RecordAttempt stores the outcome of a failed delivery: the entry's
+// attempt count, its status and when it is next due.
+func (r *AuditOutboxRepository) RecordAttempt(ctx context.Context, entry *models.AuditOutboxEntry) error {
+	query := `
+		UPDATE audit_outbox SET attempts = $2, status = $3, next_attempt_at = $4, last_error = $5
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, entry.ID, entry.Attempts, entry.Status, entry.NextAttemptAt, entry.LastError)
+	return err
+}
+
+// Retry queues a sink's failed entries again, due immediately.
+func (r *AuditOutboxRepository) Retry(ctx context.Context, sinkID uuid.UUID, now time.Time) (int64, error) {
+	query := `
+		UPDATE audit_outbox SET status = 'pending', attempts = 0, next_attempt_at = $2
+		WHERE sink_id = $1 AND status = 'failed'
+	`
+	tag, err := r.db.Exec(ctx, query, sinkID, now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
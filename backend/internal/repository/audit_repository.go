@@ -2,13 +2,16 @@ package repository
 
 import (
         "context"
+        "encoding/json"
         "fmt"
+        "sort"
         "strings"
         "time"
 
         "admin-panel/internal/models"
 
         "github.com/google/uuid"
+        "github.com/jackc/pgx/v5"
         "github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -20,49 +23,141 @@ func NewAuditLogRepository(db *pgxpool.Pool) *AuditLogRepository {
         return &AuditLogRepository{db: db}
 }
 
+// Log appends an entry to its tenant's audit chain and, in the same
+// statement, queues it in the outbox of each of the tenant's enabled sinks.
+// The database assigns the entry's sequence number and hashes, which are
+// set on log.
 func (r *AuditLogRepository) Log(ctx context.Context, log *models.AuditLog) error {
         query := `
-                INSERT INTO audit_logs (id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+                WITH entry AS (
+                        INSERT INTO audit_logs (id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at)
+                        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+                        RETURNING id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                ), queued AS (
+                        INSERT INTO audit_outbox (sink_id, audit_log_id, payload)
+                        SELECT s.id, entry.id, to_jsonb(entry)
+                        FROM audit_sinks s, entry
+                        WHERE s.tenant_id = entry.tenant_id AND s.enabled
+                )
+                SELECT seq, prev_hash, hash FROM entry
         `
-        _, err := r.db.Exec(ctx, query,
+        return r.db.QueryRow(ctx, query,
                 log.ID, log.TenantID, log.UserID, log.Action, log.Resource,
                 log.ResourceID, log.OldValue, log.NewValue, log.IPAddress, log.UserAgent, log.CreatedAt,
-        )
-        return err
+        ).Scan(&log.Seq, &log.PrevHash, &log.Hash)
 }
 
-func (r *AuditLogRepository) List(ctx context.Context, params *models.ListParams) ([]*models.AuditLog, int64, error) {
-        var conditions []string
+// auditLogSorts are the columns audit logs can be ordered by.
+var auditLogSorts = map[string]bool{
+        "created_at": true,
+        "action":     true,
+        "resource":   true,
+}
+
+// auditLogOrder returns the column and direction a filter orders by,
+// newest first unless asked otherwise. Ties are broken by ID in the same
+// direction so keyset pagination is stable.
+func auditLogOrder(filter *models.AuditLogFilter) (string, string) {
+        column := "created_at"
+        if auditLogSorts[filter.Sort] {
+                column = filter.Sort
+        }
+        direction := "DESC"
+        if filter.Order == "asc" {
+                direction = "ASC"
+        }
+        return column, direction
+}
+
+// auditLogConditions builds the WHERE clause selecting a filter's entries.
+// Arguments appended later are numbered after the returned ones.
+func auditLogConditions(filter *models.AuditLogFilter) ([]string, []interface{}) {
         var args []interface{}
-        argCount := 1
+        arg := func(value interface{}) string {
+                args = append(args, value)
+                return fmt.Sprintf("$%d", len(args))
+        }
 
-        conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", argCount))
-        args = append(args, params.TenantID)
-        argCount++
+        conditions := []string{"tenant_id = " + arg(filter.TenantID)}
 
-        if params.Search != "" {
-                conditions = append(conditions, fmt.Sprintf("(action ILIKE $%d OR resource ILIKE $%d)", argCount, argCount))
-                args = append(args, "%"+params.Search+"%")
-                argCount++
+        if filter.Search != "" {
+                search := arg("%" + filter.Search + "%")
+                conditions = append(conditions, fmt.Sprintf("(action ILIKE %s OR resource ILIKE %s)", search, search))
+        }
+        if len(filter.Actions) > 0 {
+                conditions = append(conditions, fmt.Sprintf("action = ANY(%s)", arg(filter.Actions)))
+        }
+        if filter.Resource != "" {
+                conditions = append(conditions, "resource = "+arg(filter.Resource))
+        }
+        if filter.UserID != nil {
+                conditions = append(conditions, "user_id = "+arg(*filter.UserID))
+        }
+        if filter.ResourceID != nil {
+                conditions = append(conditions, "resource_id = "+arg(*filter.ResourceID))
+        }
+        if filter.IPAddress != "" {
+                // Requests that did not come through a proxy are recorded
+                // with the client's port.
+                ip := arg(filter.IPAddress)
+                conditions = append(conditions, fmt.Sprintf(
+                        "(ip_address = %s OR ip_address LIKE %s || ':%%' OR ip_address LIKE '[' || %s || ']:%%')", ip, ip, ip,
+                ))
+        }
+        if filter.From != nil {
+                conditions = append(conditions, "created_at >= "+arg(*filter.From))
+        }
+        if filter.To != nil {
+                conditions = append(conditions, "created_at < "+arg(*filter.To))
         }
+        if filter.OldValuePath != "" {
+                conditions = append(conditions, fmt.Sprintf("old_value @? %s::jsonpath", arg(filter.OldValuePath)))
+        }
+        if filter.NewValuePath != "" {
+                conditions = append(conditions, fmt.Sprintf("new_value @? %s::jsonpath", arg(filter.NewValuePath)))
+        }
+        conditions = append(conditions, jsonFieldConditions("old_value", filter.OldValue, arg)...)
+        conditions = append(conditions, jsonFieldConditions("new_value", filter.NewValue, arg)...)
+
+        return conditions, args
+}
 
-        if action, ok := params.Filters["action"].(string); ok && action != "" {
-                conditions = append(conditions, fmt.Sprintf("action = $%d", argCount))
-                args = append(args, action)
-                argCount++
+// jsonFieldConditions matches snapshots whose field at each dotted path
+// equals the given value, or is a list containing it. Containment keeps the
+// match on the snapshot GIN indexes.
+func jsonFieldConditions(column string, fields map[string]interface{}, arg func(interface{}) string) []string {
+        paths := make([]string, 0, len(fields))
+        for path := range fields {
+                paths = append(paths, path)
         }
+        sort.Strings(paths)
 
-        if resource, ok := params.Filters["resource"].(string); ok && resource != "" {
-                conditions = append(conditions, fmt.Sprintf("resource = $%d", argCount))
-                args = append(args, resource)
-                argCount++
+        var conditions []string
+        for _, path := range paths {
+                value := fields[path]
+                conditions = append(conditions, fmt.Sprintf("(%s @> %s::jsonb OR %s @> %s::jsonb)",
+                        column, arg(jsonAtPath(path, value)),
+                        column, arg(jsonAtPath(path, []interface{}{value})),
+                ))
         }
+        return conditions
+}
 
-        whereClause := ""
-        if len(conditions) > 0 {
-                whereClause = "WHERE " + strings.Join(conditions, " AND ")
+// jsonAtPath encodes a JSON object holding value at a dotted path.
+func jsonAtPath(path string, value interface{}) string {
+        keys := strings.Split(path, ".")
+        for i := len(keys) - 1; i >= 0; i-- {
+                value = map[string]interface{}{keys[i]: value}
         }
+        data, _ := json.Marshal(value)
+        return string(data)
+}
+
+// List returns one page of the entries matching filter and how many match
+// in total.
+func (r *AuditLogRepository) List(ctx context.Context, filter *models.AuditLogFilter, page, perPage int) ([]*models.AuditLog, int64, error) {
+        conditions, args := auditLogConditions(filter)
+        whereClause := "WHERE " + strings.Join(conditions, " AND ")
 
         countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_logs %s", whereClause)
         var total int64
@@ -71,97 +166,140 @@ func (r *AuditLogRepository) List(ctx context.Context, params *models.ListParams
                 return nil, 0, err
         }
 
-        offset := (params.Page - 1) * params.PerPage
-
+        column, direction := auditLogOrder(filter)
         query := fmt.Sprintf(`
-                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
                 FROM audit_logs %s
-                ORDER BY created_at DESC
+                ORDER BY %s %s, id %s
                 LIMIT $%d OFFSET $%d
-        `, whereClause, argCount, argCount+1)
+        `, whereClause, column, direction, direction, len(args)+1, len(args)+2)
 
-        args = append(args, params.PerPage, offset)
-
-        rows, err := r.db.Query(ctx, query, args...)
-        if err != nil {
-                return nil, 0, err
-        }
-        defer rows.Close()
+        args = append(args, perPage, (page-1)*perPage)
 
         var logs []*models.AuditLog
-        for rows.Next() {
-                log := &models.AuditLog{}
-                err := rows.Scan(
-                        &log.ID, &log.TenantID, &log.UserID, &log.Action, &log.Resource,
-                        &log.ResourceID, &log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
-                )
-                if err != nil {
-                        return nil, 0, err
-                }
+        err = r.stream(ctx, func(log *models.AuditLog) error {
                 logs = append(logs, log)
+                return nil
+        }, query, args...)
+        if err != nil {
+                return nil, 0, err
         }
 
         return logs, total, nil
 }
 
-func (r *AuditLogRepository) ListAllForExport(ctx context.Context, params *models.ListParams) ([]*models.AuditLog, error) {
-        var conditions []string
-        var args []interface{}
-        argCount := 1
+// ListAfter returns up to limit entries matching filter that follow the
+// cursor in the filter's order, or the first ones when cursor is nil.
+// Unlike List it neither counts nor skips rows, so it is as fast deep into
+// the table as at its start.
+func (r *AuditLogRepository) ListAfter(ctx context.Context, filter *models.AuditLogFilter, cursor *models.AuditLogCursor, limit int) ([]*models.AuditLog, error) {
+        conditions, args := auditLogConditions(filter)
+        column, direction := auditLogOrder(filter)
+
+        if cursor != nil {
+                comparison := "<"
+                if direction == "ASC" {
+                        comparison = ">"
+                }
+                args = append(args, cursor.Value, cursor.ID)
+                conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)",
+                        column, comparison, len(args)-1, len(args),
+                ))
+        }
 
-        conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", argCount))
-        args = append(args, params.TenantID)
-        argCount++
+        query := fmt.Sprintf(`
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                FROM audit_logs WHERE %s
+                ORDER BY %s %s, id %s
+                LIMIT $%d
+        `, strings.Join(conditions, " AND "), column, direction, direction, len(args)+1)
 
-        if params.Search != "" {
-                conditions = append(conditions, fmt.Sprintf("(action ILIKE $%d OR resource ILIKE $%d)", argCount, argCount))
-                args = append(args, "%"+params.Search+"%")
-                argCount++
-        }
+        args = append(args, limit)
 
-        if action, ok := params.Filters["action"].(string); ok && action != "" {
-                conditions = append(conditions, fmt.Sprintf("action = $%d", argCount))
-                args = append(args, action)
-                argCount++
+        var logs []*models.AuditLog
+        err := r.stream(ctx, func(log *models.AuditLog) error {
+                logs = append(logs, log)
+                return nil
+        }, query, args...)
+        if err != nil {
+                return nil, err
         }
+        return logs, nil
+}
 
-        if resource, ok := params.Filters["resource"].(string); ok && resource != "" {
-                conditions = append(conditions, fmt.Sprintf("resource = $%d", argCount))
-                args = append(args, resource)
-                argCount++
-        }
+// auditExportBatchSize is how many rows StreamFiltered fetches from its
+// cursor at a time.
+const auditExportBatchSize = 1000
 
-        whereClause := ""
-        if len(conditions) > 0 {
-                whereClause = "WHERE " + strings.Join(conditions, " AND ")
+// StreamFiltered calls fn for every entry matching filter, in the filter's
+// order. Rows are read in batches through a server-side cursor inside a
+// read-only snapshot, so memory use does not grow with the size of the
+// export and concurrent writes do not shift the result.
+func (r *AuditLogRepository) StreamFiltered(ctx context.Context, filter *models.AuditLogFilter, fn func(*models.AuditLog) error) error {
+        conditions, args := auditLogConditions(filter)
+        column, direction := auditLogOrder(filter)
+
+        tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+        if err != nil {
+                return err
         }
+        defer tx.Rollback(ctx)
 
         query := fmt.Sprintf(`
-                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at
-                FROM audit_logs %s
-                ORDER BY created_at DESC
-        `, whereClause)
-
-        rows, err := r.db.Query(ctx, query, args...)
-        if err != nil {
-                return nil, err
+                DECLARE audit_export NO SCROLL CURSOR FOR
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                FROM audit_logs WHERE %s
+                ORDER BY %s %s, id %s
+        `, strings.Join(conditions, " AND "), column, direction, direction)
+        if _, err := tx.Exec(ctx, query, args...); err != nil {
+                return err
         }
-        defer rows.Close()
 
-        var logs []*models.AuditLog
-        for rows.Next() {
-                log := &models.AuditLog{}
-                err := rows.Scan(
-                        &log.ID, &log.TenantID, &log.UserID, &log.Action, &log.Resource,
-                        &log.ResourceID, &log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
-                )
+        fetch := fmt.Sprintf("FETCH FORWARD %d FROM audit_export", auditExportBatchSize)
+        for {
+                rows, err := tx.Query(ctx, fetch)
                 if err != nil {
-                        return nil, err
+                        return err
+                }
+                fetched := 0
+                for rows.Next() {
+                        log, err := scanAuditLog(rows)
+                        if err == nil {
+                                err = fn(log)
+                        }
+                        if err != nil {
+                                rows.Close()
+                                return err
+                        }
+                        fetched++
+                }
+                rows.Close()
+                if err := rows.Err(); err != nil {
+                        return err
+                }
+                if fetched < auditExportBatchSize {
+                        break
                 }
-                logs = append(logs, log)
         }
 
-        return logs, nil
+        return tx.Commit(ctx)
+}
+
+func (r *AuditLogRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditLog, error) {
+        query := `
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                FROM audit_logs WHERE tenant_id = $1 AND id = $2
+        `
+        log := &models.AuditLog{}
+        err := r.db.QueryRow(ctx, query, tenantID, id).Scan(
+                &log.ID, &log.TenantID, &log.UserID, &log.Action, &log.Resource,
+                &log.ResourceID, &log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
+                &log.Seq, &log.PrevHash, &log.Hash,
+        )
+        if err != nil {
+                return nil, err
+        }
+        return log, nil
 }
 
 func (r *AuditLogRepository) CountRecentLogins(ctx context.Context, tenantID uuid.UUID, duration time.Duration) (int64, error) {
@@ -173,7 +311,7 @@ func (r *AuditLogRepository) CountRecentLogins(ctx context.Context, tenantID uui
 
 func (r *AuditLogRepository) GetRecentActivity(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.AuditLog, error) {
         query := `
-                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
                 FROM audit_logs WHERE tenant_id = $1
                 ORDER BY created_at DESC
                 LIMIT $2
@@ -190,6 +328,7 @@ func (r *AuditLogRepository) GetRecentActivity(ctx context.Context, tenantID uui
                 err := rows.Scan(
                         &log.ID, &log.TenantID, &log.UserID, &log.Action, &log.Resource,
                         &log.ResourceID, &log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
+                        &log.Seq, &log.PrevHash, &log.Hash,
                 )
                 if err != nil {
                         return nil, err
@@ -199,3 +338,116 @@ func (r *AuditLogRepository) GetRecentActivity(ctx context.Context, tenantID uui
 
         return logs, nil
 }
+
+// StreamBefore calls fn for up to limit of a tenant's oldest audit log
+// entries created before the given time, in chain order, without loading
+// them all into memory. It stops at the first entry created at or after
+// before, so the entries passed to fn always start the remaining chain.
+func (r *AuditLogRepository) StreamBefore(ctx context.Context, tenantID uuid.UUID, before time.Time, limit int, fn func(*models.AuditLog) error) error {
+        query := `
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                FROM audit_logs
+                WHERE tenant_id = $1 AND seq < COALESCE(
+                        (SELECT seq FROM audit_logs WHERE tenant_id = $1 AND created_at >= $2 ORDER BY seq LIMIT 1),
+                        9223372036854775807
+                )
+                ORDER BY seq
+                LIMIT $3
+        `
+        return r.stream(ctx, fn, query, tenantID, before, limit)
+}
+
+// StreamChain calls fn for each of a tenant's audit log entries with a
+// sequence number after afterSeq and up to throughSeq, in chain order.
+func (r *AuditLogRepository) StreamChain(ctx context.Context, tenantID uuid.UUID, afterSeq, throughSeq int64, fn func(*models.AuditLog) error) error {
+        query := `
+                SELECT id, tenant_id, user_id, action, resource, resource_id, old_value, new_value, ip_address, user_agent, created_at, seq, prev_hash, hash
+                FROM audit_logs
+                WHERE tenant_id = $1 AND seq > $2 AND seq <= $3
+                ORDER BY seq
+        `
+        return r.stream(ctx, fn, query, tenantID, afterSeq, throughSeq)
+}
+
+func (r *AuditLogRepository) stream(ctx context.Context, fn func(*models.AuditLog) error, query string, args ...interface{}) error {
+        rows, err := r.db.Query(ctx, query, args...)
+        if err != nil {
+                return err
+        }
+        defer rows.Close()
+
+        for rows.Next() {
+                log, err := scanAuditLog(rows)
+                if err != nil {
+                        return err
+                }
+                if err := fn(log); err != nil {
+                        return err
+                }
+        }
+
+        return rows.Err()
+}
+
+func scanAuditLog(row pgx.Row) (*models.AuditLog, error) {
+        log := &models.AuditLog{}
+        err := row.Scan(
+                &log.ID, &log.TenantID, &log.UserID, &log.Action, &log.Resource,
+                &log.ResourceID, &log.OldValue, &log.NewValue, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
+                &log.Seq, &log.PrevHash, &log.Hash,
+        )
+        if err != nil {
+                return nil, err
+        }
+        return log, nil
+}
+
+// ChainHead returns the state of a tenant's audit chain. It returns
+// pgx.ErrNoRows for tenants that have never written an entry.
+func (r *AuditLogRepository) ChainHead(ctx context.Context, tenantID uuid.UUID) (*models.AuditChainHead, error) {
+        query := `
+                SELECT tenant_id, seq, hash, anchor_seq, anchor_hash, updated_at
+                FROM audit_chain_heads WHERE tenant_id = $1
+        `
+        head := &models.AuditChainHead{}
+        err := r.db.QueryRow(ctx, query, tenantID).Scan(
+                &head.TenantID, &head.Seq, &head.Hash, &head.AnchorSeq, &head.AnchorHash, &head.UpdatedAt,
+        )
+        if err != nil {
+                return nil, err
+        }
+        return head, nil
+}
+
+// Prune removes a tenant's entries up to and including throughSeq, whose
+// hash is throughHash and which archiveID holds, and records that entry as
+// the anchor the remaining chain starts from. Each prune that moves the
+// anchor is recorded in audit_chain_anchors. It is the only way entries can
+// be deleted: the audit_prune function runs as the audit_pruner role, the
+// only role the append-only trigger lets delete. It returns how many
+// entries were deleted.
+func (r *AuditLogRepository) Prune(ctx context.Context, tenantID, archiveID uuid.UUID, throughSeq int64, throughHash string) (int64, error) {
+        var pruned int64
+        err := r.db.QueryRow(ctx, "SELECT audit_prune($1, $2, $3, $4)",
+                tenantID, archiveID, throughSeq, throughHash,
+        ).Scan(&pruned)
+        return pruned, err
+}
+
+// Anchor returns the prune that left a tenant's chain starting after
+// entry seq. It returns pgx.ErrNoRows when no prune was recorded there.
+func (r *AuditLogRepository) Anchor(ctx context.Context, tenantID uuid.UUID, seq int64) (*models.AuditChainAnchor, error) {
+        query := `
+                SELECT id, tenant_id, seq, hash, archive_id, pruned_count, created_at
+                FROM audit_chain_anchors WHERE tenant_id = $1 AND seq = $2
+        `
+        anchor := &models.AuditChainAnchor{}
+        err := r.db.QueryRow(ctx, query, tenantID, seq).Scan(
+                &anchor.ID, &anchor.TenantID, &anchor.Seq, &anchor.Hash,
+                &anchor.ArchiveID, &anchor.PrunedCount, &anchor.CreatedAt,
+        )
+        if err != nil {
+                return nil, err
+        }
+        return anchor, nil
+}
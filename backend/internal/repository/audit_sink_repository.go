@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditSinkRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditSinkRepository(db *pgxpool.Pool) *AuditSinkRepository {
+	return &AuditSinkRepository{db: db}
+}
+
+const auditSinkColumns = `s.id, s.tenant_id, s.name, s.type, s.config, s.secret, s.enabled,
+	(SELECT COUNT(*) FROM audit_outbox o WHERE o.sink_id = s.id AND o.status = 'pending'),
+	(SELECT COUNT(*) FROM audit_outbox o WHERE o.sink_id = s.id AND o.status = 'failed'),
+	s.last_delivered_at, s.last_error, s.last_error_at, s.created_at, s.updated_at`
+
+func (r *AuditSinkRepository) Create(ctx context.Context, sink *models.AuditSink) error {
+	query := `
+		INSERT INTO audit_sinks (id, tenant_id, name, type, config, secret, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.Exec(ctx, query,
+		sink.ID, sink.TenantID, sink.Name, sink.Type, sink.Config, sink.Secret, sink.Enabled, sink.CreatedAt, sink.UpdatedAt,
+	)
+	return err
+}
+
+func (r *AuditSinkRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditSink, error) {
+	query := `SELECT ` + auditSinkColumns + ` FROM audit_sinks s WHERE s.tenant_id = $1 AND s.id = $2`
+	return scanAuditSink(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *AuditSinkRepository) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.AuditSink, error) {
+	query := `SELECT ` + auditSinkColumns + ` FROM audit_sinks s WHERE s.tenant_id = $1 AND s.name = $2`
+	return scanAuditSink(r.db.QueryRow(ctx, query, tenantID, name))
+}
+
+func (r *AuditSinkRepository) List(ctx context.Context, tenantID uuid.UUID) ([]*models.AuditSink, error) {
+	query := `SELECT ` + auditSinkColumns + ` FROM audit_sinks s WHERE s.tenant_id = $1 ORDER BY s.name`
+	return r.query(ctx, query, tenantID)
+}
+
+// ListDue returns the enabled sinks, across tenants, with entries due for
+// delivery at the given time.
+func (r *AuditSinkRepository) ListDue(ctx context.Context, now time.Time) ([]*models.AuditSink, error) {
+	query := `
+		SELECT ` + auditSinkColumns + `
+		FROM audit_sinks s
+		WHERE s.enabled AND EXISTS (
+			SELECT 1 FROM audit_outbox o
+			WHERE o.sink_id = s.id AND o.status = 'pending' AND o.next_attempt_at <= $1
+		)
+		ORDER BY s.id
+	`
+	return r.query(ctx, query, now)
+}
+
+func (r *AuditSinkRepository) Update(ctx context.Context, sink *models.AuditSink) error {
+	query := `
+		UPDATE audit_sinks SET name = $3, config = $4, secret = $5, enabled = $6, updated_at = $7
+		WHERE tenant_id = $1 AND id = $2
+	`
+	return requireAffected(r.db.Exec(ctx, query,
+		sink.TenantID, sink.ID, sink.Name, sink.Config, sink.Secret, sink.Enabled, sink.UpdatedAt,
+	))
+}
+
+func (r *AuditSinkRepository) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	return requireAffected(r.db.Exec(ctx, "DELETE FROM audit_sinks WHERE tenant_id = $1 AND id = $2", tenantID, id))
+}
+
+// RecordDelivery notes that the sink accepted entries at the given time.
+func (r *AuditSinkRepository) RecordDelivery(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE audit_sinks SET last_delivered_at = $2 WHERE id = $1", id, at)
+	return err
+}
+
+// RecordError notes the latest delivery failure of a sink.
+func (r *AuditSinkRepository) RecordError(ctx context.Context, id uuid.UUID, message string, at time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE audit_sinks SET last_error = $2, last_error_at = $3 WHERE id = $1", id, message, at)
+	return err
+}
+
+func (r *AuditSinkRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.AuditSink, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sinks []*models.AuditSink
+	for rows.Next() {
+		sink, err := scanAuditSink(rows)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, rows.Err()
+}
+
+func scanAuditSink(row pgx.Row) (*models.AuditSink, error) {
+	sink := &models.AuditSink{}
+	err := row.Scan(
+		&sink.ID, &sink.TenantID, &sink.Name, &sink.Type, &sink.Config, &sink.Secret, &sink.Enabled,
+		&sink.Pending, &sink.Failed,
+		&sink.LastDeliveredAt, &sink.LastError, &sink.LastErrorAt, &sink.CreatedAt, &sink.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
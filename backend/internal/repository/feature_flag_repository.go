@@ -55,15 +55,15 @@ func (r *FeatureFlagRepository) List(ctx context.Context, tenantID uuid.UUID) ([
 	return flags, nil
 }
 
-func (r *FeatureFlagRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.FeatureFlag, error) {
+func (r *FeatureFlagRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.FeatureFlag, error) {
 	query := `
 		SELECT id, tenant_id, key, name, description, enabled, metadata, created_at, updated_at
 		FROM feature_flags
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
 	var flag models.FeatureFlag
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.pool.QueryRow(ctx, query, id, tenantID).Scan(
 		&flag.ID,
 		&flag.TenantID,
 		&flag.Key,
@@ -137,25 +137,26 @@ func (r *FeatureFlagRepository) Update(ctx context.Context, flag *models.Feature
 
 	query := `
 		UPDATE feature_flags
-		SET name = $2, description = $3, enabled = $4, metadata = $5, updated_at = $6
-		WHERE id = $1
+		SET name = $3, description = $4, enabled = $5, metadata = $6, updated_at = $7
+		WHERE id = $1 AND tenant_id = $2
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	tag, err := r.pool.Exec(ctx, query,
 		flag.ID,
+		flag.TenantID,
 		flag.Name,
 		flag.Description,
 		flag.Enabled,
 		flag.Metadata,
 		flag.UpdatedAt,
 	)
-	return err
+	return requireAffected(tag, err)
 }
 
-func (r *FeatureFlagRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM feature_flags WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
-	return err
+func (r *FeatureFlagRepository) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM feature_flags WHERE id = $1 AND tenant_id = $2`
+	tag, err := r.pool.Exec(ctx, query, id, tenantID)
+	return requireAffected(tag, err)
 }
 
 func (r *FeatureFlagRepository) IsEnabled(ctx context.Context, tenantID uuid.UUID, key string) bool {
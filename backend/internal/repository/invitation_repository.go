@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InvitationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInvitationRepository(db *pgxpool.Pool) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, invitation *models.UserInvitation) error {
+	query := `
+		INSERT INTO user_invitations (id, tenant_id, user_id, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(ctx, query,
+		invitation.ID, invitation.TenantID, invitation.UserID, invitation.InvitedBy,
+		invitation.ExpiresAt, invitation.CreatedAt,
+	)
+	return err
+}
+
+// CreateWithUser inserts a pending user together with their first
+// invitation, so a user is never left without one.
+func (r *InvitationRepository) CreateWithUser(ctx context.Context, user *models.User, invitation *models.UserInvitation) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`, user.ID, user.TenantID, user.Email, user.PasswordHash, user.FirstName, user.LastName, user.Status, invitation.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_invitations (id, tenant_id, user_id, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, invitation.ID, invitation.TenantID, invitation.UserID, invitation.InvitedBy, invitation.ExpiresAt, invitation.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	user.CreatedAt, user.UpdatedAt = invitation.CreatedAt, invitation.CreatedAt
+	return nil
+}
+
+// GetLatestByUser returns the most recent invitation sent to the user.
+func (r *InvitationRepository) GetLatestByUser(ctx context.Context, tenantID, userID uuid.UUID) (*models.UserInvitation, error) {
+	query := `
+		SELECT id, tenant_id, user_id, invited_by, expires_at, accepted_at, revoked_at, created_at
+		FROM user_invitations
+		WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	invitation := &models.UserInvitation{}
+	err := r.db.QueryRow(ctx, query, tenantID, userID).Scan(
+		&invitation.ID, &invitation.TenantID, &invitation.UserID, &invitation.InvitedBy,
+		&invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.RevokedAt, &invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// RevokeOutstanding revokes every unaccepted invitation for the user and
+// reports how many were revoked.
+func (r *InvitationRepository) RevokeOutstanding(ctx context.Context, tenantID, userID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE user_invitations SET revoked_at = NOW()
+		WHERE tenant_id = $1 AND user_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Accept marks a usable invitation as accepted and activates its pending
+// user with the chosen password in one transaction. It returns
+// pgx.ErrNoRows when the invitation is expired, revoked or already used.
+func (r *InvitationRepository) Accept(ctx context.Context, id uuid.UUID, passwordHash string) (*models.UserInvitation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	invitation := &models.UserInvitation{}
+	err = tx.QueryRow(ctx, `
+		UPDATE user_invitations SET accepted_at = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $2
+		RETURNING id, tenant_id, user_id, invited_by, expires_at, accepted_at, revoked_at, created_at
+	`, id, now).Scan(
+		&invitation.ID, &invitation.TenantID, &invitation.UserID, &invitation.InvitedBy,
+		&invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.RevokedAt, &invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET password_hash = $3, status = 'active', password_changed_at = $4, updated_at = $4
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending'
+	`, invitation.TenantID, invitation.UserID, passwordHash, now)
+	if err := requireAffected(tag, err); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRunRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRunRepository(db *pgxpool.Pool) *JobRunRepository {
+	return &JobRunRepository{db: db}
+}
+
+// TryLock takes the session-level advisory lock for a job on a dedicated
+// connection. It reports false when another instance holds the lock. The
+// returned unlock function releases the lock and the connection.
+func (r *JobRunRepository) TryLock(ctx context.Context, jobName string) (func(), bool, error) {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := jobLockKey(jobName)
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// A connection that may still hold the lock must not go back
+			// into the pool; closing it releases the lock server side.
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
+
+// Claim records the start of a run. It reports false when a run of the same
+// job for the same slot already exists.
+func (r *JobRunRepository) Claim(ctx context.Context, run *models.JobRun) (bool, error) {
+	query := `
+		INSERT INTO job_runs (id, job_name, scheduled_at, trigger, triggered_by, status, instance, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (job_name, scheduled_at) DO NOTHING
+	`
+	tag, err := r.db.Exec(ctx, query,
+		run.ID, run.JobName, run.ScheduledAt, run.Trigger, run.TriggeredBy, run.Status, run.Instance, run.StartedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *JobRunRepository) Finish(ctx context.Context, run *models.JobRun) error {
+	query := `UPDATE job_runs SET status = $2, affected = $3, error = $4, finished_at = $5 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, run.ID, run.Status, run.Affected, run.Error, run.FinishedAt)
+	return err
+}
+
+// ListByJob returns the most recent runs of a job, newest first.
+func (r *JobRunRepository) ListByJob(ctx context.Context, jobName string, limit int) ([]*models.JobRun, error) {
+	query := `
+		SELECT id, job_name, scheduled_at, trigger, triggered_by, status, instance, affected, error, started_at, finished_at
+		FROM job_runs
+		WHERE job_name = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.JobRun
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Latest returns the most recent run of every job that has run, keyed by
+// job name.
+func (r *JobRunRepository) Latest(ctx context.Context) (map[string]*models.JobRun, error) {
+	query := `
+		SELECT DISTINCT ON (job_name)
+			id, job_name, scheduled_at, trigger, triggered_by, status, instance, affected, error, started_at, finished_at
+		FROM job_runs
+		ORDER BY job_name, started_at DESC
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make(map[string]*models.JobRun)
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[run.JobName] = run
+	}
+	return runs, rows.Err()
+}
+
+// DeleteBefore removes the history of runs started before the given time.
+func (r *JobRunRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM job_runs WHERE started_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanJobRun(row pgx.Row) (*models.JobRun, error) {
+	run := &models.JobRun{}
+	err := row.Scan(
+		&run.ID, &run.JobName, &run.ScheduledAt, &run.Trigger, &run.TriggeredBy, &run.Status, &run.Instance,
+		&run.Affected, &run.Error, &run.StartedAt, &run.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// jobLockKey maps a job name onto the advisory lock key space. Keys are
+// namespaced so they cannot collide with locks taken for other purposes.
+func jobLockKey(jobName string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("job:" + jobName))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordHistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordHistoryRepository(db *pgxpool.Pool) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// Add records a password hash and keeps only the newest keep entries for
+// the user.
+func (r *PasswordHistoryRepository) Add(ctx context.Context, userID uuid.UUID, passwordHash string, keep int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO password_history (id, user_id, password_hash, created_at) VALUES ($1, $2, $3, NOW())",
+		uuid.New(), userID, passwordHash,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1
+			ORDER BY created_at DESC LIMIT $2
+		)
+	`, userID, keep); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Recent returns up to limit of the user's previous password hashes,
+// newest first.
+func (r *PasswordHistoryRepository) Recent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2",
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetRepository(db *pgxpool.Pool) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, tenantID, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO password_reset_tokens (id, tenant_id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, uuid.New(), tenantID, userID, tokenHash, expiresAt)
+	return err
+}
+
+// Lookup returns the tenant and user of an unused, unexpired token without
+// consuming it.
+func (r *PasswordResetRepository) Lookup(ctx context.Context, tokenHash string) (uuid.UUID, uuid.UUID, error) {
+	query := `
+		SELECT tenant_id, user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`
+	var tenantID, userID uuid.UUID
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&tenantID, &userID)
+	return tenantID, userID, err
+}
+
+// Consume marks an unused, unexpired token as used and returns the tenant
+// and user it was issued for. It returns pgx.ErrNoRows for unknown, used or
+// expired tokens.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (uuid.UUID, uuid.UUID, error) {
+	query := `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING tenant_id, user_id
+	`
+	var tenantID, userID uuid.UUID
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&tenantID, &userID)
+	return tenantID, userID, err
+}
+
+// InvalidateForUser marks every outstanding token for the user as used.
+func (r *PasswordResetRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// DeleteExpired removes tokens that were used or have expired and returns
+// how many were removed.
+func (r *PasswordResetRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM password_reset_tokens WHERE expires_at < NOW() OR used_at IS NOT NULL`
+	tag, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Hit counts a request for key in the fixed window starting at
+// windowStart, rolling the counter over when the stored window is older.
+// It returns the counts of the current and previous windows, including
+// this request.
+func (r *RateLimitRepository) Hit(ctx context.Context, key string, windowStart time.Time, window time.Duration) (int, int, error) {
+	query := `
+		INSERT INTO rate_limit_counters (key, window_start, count, previous_count, expires_at)
+		VALUES ($1, $2, 1, 0, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			previous_count = CASE
+				WHEN rate_limit_counters.window_start = $2 THEN rate_limit_counters.previous_count
+				WHEN rate_limit_counters.window_start = $3 THEN rate_limit_counters.count
+				ELSE 0
+			END,
+			count = CASE
+				WHEN rate_limit_counters.window_start = $2 THEN rate_limit_counters.count + 1
+				ELSE 1
+			END,
+			window_start = $2,
+			expires_at = $4
+		RETURNING count, previous_count
+	`
+	var current, previous int
+	err := r.db.QueryRow(ctx, query, key, windowStart, windowStart.Add(-window), windowStart.Add(2*window)).Scan(&current, &previous)
+	return current, previous, err
+}
+
+// Undo takes back a request counted by Hit that was then rejected.
+func (r *RateLimitRepository) Undo(ctx context.Context, key string, windowStart time.Time) error {
+	query := `UPDATE rate_limit_counters SET count = count - 1 WHERE key = $1 AND window_start = $2 AND count > 0`
+	_, err := r.db.Exec(ctx, query, key, windowStart)
+	return err
+}
+
+func (r *RateLimitRepository) DeleteExpired(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM rate_limit_counters WHERE expires_at < NOW()")
+	return err
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// requireAffected turns a statement that matched no rows into pgx.ErrNoRows,
+// so tenant-scoped writes against a record of another tenant surface the
+// same error as a lookup that found nothing.
+func requireAffected(tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
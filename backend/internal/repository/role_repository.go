@@ -35,13 +35,13 @@ func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
 	return err
 }
 
-func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+func (r *RoleRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Role, error) {
 	query := `
 		SELECT id, tenant_id, name, description, is_system, created_at, updated_at
-		FROM roles WHERE id = $1
+		FROM roles WHERE id = $1 AND tenant_id = $2
 	`
 	role := &models.Role{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&role.ID, &role.TenantID, &role.Name, &role.Description,
 		&role.IsSystem, &role.CreatedAt, &role.UpdatedAt,
 	)
@@ -128,23 +128,32 @@ func (r *RoleRepository) List(ctx context.Context, params *models.ListParams) ([
 }
 
 func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
-	query := `UPDATE roles SET name = $2, description = $3, updated_at = $4 WHERE id = $1`
+	query := `UPDATE roles SET name = $3, description = $4, updated_at = $5 WHERE id = $1 AND tenant_id = $2`
 	role.UpdatedAt = time.Now()
-	_, err := r.db.Exec(ctx, query, role.ID, role.Name, role.Description, role.UpdatedAt)
-	return err
+	tag, err := r.db.Exec(ctx, query, role.ID, role.TenantID, role.Name, role.Description, role.UpdatedAt)
+	return requireAffected(tag, err)
 }
 
-func (r *RoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.Exec(ctx, "DELETE FROM role_permissions WHERE role_id = $1", id)
+func (r *RoleRepository) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	_, err = r.db.Exec(ctx, "DELETE FROM user_roles WHERE role_id = $1", id)
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM roles WHERE id = $1 AND tenant_id = $2", id, tenantID)
+	if err := requireAffected(tag, err); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM role_permissions WHERE role_id = $1", id)
 	if err != nil {
 		return err
 	}
-	_, err = r.db.Exec(ctx, "DELETE FROM roles WHERE id = $1", id)
-	return err
+	_, err = tx.Exec(ctx, "DELETE FROM user_roles WHERE role_id = $1", id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *RoleRepository) Count(ctx context.Context, tenantID uuid.UUID) (int64, error) {
@@ -153,32 +162,46 @@ func (r *RoleRepository) Count(ctx context.Context, tenantID uuid.UUID) (int64,
 	return count, err
 }
 
-func (r *RoleRepository) AssignRoleToUser(ctx context.Context, userID, roleID uuid.UUID) error {
-	query := `INSERT INTO user_roles (user_id, role_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
-	_, err := r.db.Exec(ctx, query, userID, roleID, time.Now())
+// AssignRoleToUser links a user and a role only when both belong to tenantID.
+func (r *RoleRepository) AssignRoleToUser(ctx context.Context, tenantID, userID, roleID uuid.UUID) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		SELECT u.id, r.id, $4
+		FROM users u
+		JOIN roles r ON r.tenant_id = u.tenant_id
+		WHERE u.id = $1 AND r.id = $2 AND u.tenant_id = $3
+		ON CONFLICT DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, query, userID, roleID, tenantID, time.Now())
 	return err
 }
 
-func (r *RoleRepository) RemoveRoleFromUser(ctx context.Context, userID, roleID uuid.UUID) error {
-	query := `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`
-	_, err := r.db.Exec(ctx, query, userID, roleID)
+func (r *RoleRepository) RemoveRoleFromUser(ctx context.Context, tenantID, userID, roleID uuid.UUID) error {
+	query := `
+		DELETE FROM user_roles ur USING roles r
+		WHERE ur.role_id = r.id AND ur.user_id = $1 AND ur.role_id = $2 AND r.tenant_id = $3
+	`
+	_, err := r.db.Exec(ctx, query, userID, roleID, tenantID)
 	return err
 }
 
-func (r *RoleRepository) RemoveAllRolesFromUser(ctx context.Context, userID uuid.UUID) error {
-	query := `DELETE FROM user_roles WHERE user_id = $1`
-	_, err := r.db.Exec(ctx, query, userID)
+func (r *RoleRepository) RemoveAllRolesFromUser(ctx context.Context, tenantID, userID uuid.UUID) error {
+	query := `
+		DELETE FROM user_roles ur USING roles r
+		WHERE ur.role_id = r.id AND ur.user_id = $1 AND r.tenant_id = $2
+	`
+	_, err := r.db.Exec(ctx, query, userID, tenantID)
 	return err
 }
 
-func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*models.Role, error) {
+func (r *RoleRepository) GetUserRoles(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.Role, error) {
 	query := `
 		SELECT r.id, r.tenant_id, r.name, r.description, r.is_system, r.created_at, r.updated_at
 		FROM roles r
 		JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1
+		WHERE ur.user_id = $1 AND r.tenant_id = $2
 	`
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query, userID, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -196,32 +219,45 @@ func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 	return roles, nil
 }
 
-func (r *RoleRepository) AssignPermissionToRole(ctx context.Context, roleID, permissionID uuid.UUID) error {
-	query := `INSERT INTO role_permissions (role_id, permission_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
-	_, err := r.db.Exec(ctx, query, roleID, permissionID, time.Now())
+// AssignPermissionToRole grants a permission to a role only when the role
+// belongs to tenantID.
+func (r *RoleRepository) AssignPermissionToRole(ctx context.Context, tenantID, roleID, permissionID uuid.UUID) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id, created_at)
+		SELECT id, $2, $4 FROM roles WHERE id = $1 AND tenant_id = $3
+		ON CONFLICT DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, query, roleID, permissionID, tenantID, time.Now())
 	return err
 }
 
-func (r *RoleRepository) RemovePermissionFromRole(ctx context.Context, roleID, permissionID uuid.UUID) error {
-	query := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
-	_, err := r.db.Exec(ctx, query, roleID, permissionID)
+func (r *RoleRepository) RemovePermissionFromRole(ctx context.Context, tenantID, roleID, permissionID uuid.UUID) error {
+	query := `
+		DELETE FROM role_permissions rp USING roles r
+		WHERE rp.role_id = r.id AND rp.role_id = $1 AND rp.permission_id = $2 AND r.tenant_id = $3
+	`
+	_, err := r.db.Exec(ctx, query, roleID, permissionID, tenantID)
 	return err
 }
 
-func (r *RoleRepository) RemoveAllPermissionsFromRole(ctx context.Context, roleID uuid.UUID) error {
-	query := `DELETE FROM role_permissions WHERE role_id = $1`
-	_, err := r.db.Exec(ctx, query, roleID)
+func (r *RoleRepository) RemoveAllPermissionsFromRole(ctx context.Context, tenantID, roleID uuid.UUID) error {
+	query := `
+		DELETE FROM role_permissions rp USING roles r
+		WHERE rp.role_id = r.id AND rp.role_id = $1 AND r.tenant_id = $2
+	`
+	_, err := r.db.Exec(ctx, query, roleID, tenantID)
 	return err
 }
 
-func (r *RoleRepository) GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*models.Permission, error) {
+func (r *RoleRepository) GetRolePermissions(ctx context.Context, tenantID, roleID uuid.UUID) ([]*models.Permission, error) {
 	query := `
 		SELECT p.id, p.name, p.resource, p.action, p.description, p.created_at
 		FROM permissions p
 		JOIN role_permissions rp ON p.id = rp.permission_id
-		WHERE rp.role_id = $1
+		JOIN roles r ON r.id = rp.role_id
+		WHERE rp.role_id = $1 AND r.tenant_id = $2
 	`
-	rows, err := r.db.Query(ctx, query, roleID)
+	rows, err := r.db.Query(ctx, query, roleID, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +281,8 @@ func (r *RoleRepository) GetUserPermissions(ctx context.Context, userID uuid.UUI
 		FROM permissions p
 		JOIN role_permissions rp ON p.id = rp.permission_id
 		JOIN user_roles ur ON rp.role_id = ur.role_id
+		JOIN roles r ON r.id = ur.role_id
+		JOIN users u ON u.id = ur.user_id AND u.tenant_id = r.tenant_id
 		WHERE ur.user_id = $1
 	`
 	rows, err := r.db.Query(ctx, query, userID)
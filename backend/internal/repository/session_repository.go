@@ -21,12 +21,12 @@ func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
 
 func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
 	query := `
-		INSERT INTO sessions (id, user_id, refresh_token, ip_address, user_agent, expires_at, created_at, rotated_at, replaced_by_token, revoked_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO sessions (id, family_id, user_id, refresh_token, ip_address, user_agent, expires_at, authenticated_at, created_at, rotated_at, replaced_by_token, revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	_, err := r.db.Exec(ctx, query,
-		session.ID, session.UserID, session.RefreshToken,
-		session.IPAddress, session.UserAgent, session.ExpiresAt, session.CreatedAt,
+		session.ID, session.FamilyID, session.UserID, session.RefreshToken,
+		session.IPAddress, session.UserAgent, session.ExpiresAt, session.AuthenticatedAt, session.CreatedAt,
 		session.RotatedAt, session.ReplacedByToken, session.RevokedAt,
 	)
 	return err
@@ -34,7 +34,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *models.Session)
 
 func (r *SessionRepository) GetByRefreshToken(ctx context.Context, token string) (*models.Session, error) {
 	query := `
-		SELECT id, user_id, refresh_token, ip_address, user_agent, expires_at, created_at, rotated_at, replaced_by_token, revoked_at
+		SELECT id, family_id, user_id, refresh_token, ip_address, user_agent, expires_at, authenticated_at, created_at, rotated_at, replaced_by_token, revoked_at
 		FROM sessions WHERE refresh_token = $1
 	`
 	session := &models.Session{}
@@ -42,8 +42,8 @@ func (r *SessionRepository) GetByRefreshToken(ctx context.Context, token string)
 	var replacedByToken sql.NullString
 	var revokedAt sql.NullTime
 	err := r.db.QueryRow(ctx, query, token).Scan(
-		&session.ID, &session.UserID, &session.RefreshToken,
-		&session.IPAddress, &session.UserAgent, &session.ExpiresAt, &session.CreatedAt,
+		&session.ID, &session.FamilyID, &session.UserID, &session.RefreshToken,
+		&session.IPAddress, &session.UserAgent, &session.ExpiresAt, &session.AuthenticatedAt, &session.CreatedAt,
 		&rotatedAt, &replacedByToken, &revokedAt,
 	)
 	if err != nil {
@@ -73,8 +73,92 @@ func (r *SessionRepository) RevokeByUserID(ctx context.Context, userID uuid.UUID
 	return err
 }
 
-func (r *SessionRepository) DeleteExpired(ctx context.Context) error {
+// ListActiveByUser returns the newest row of each of the user's sessions
+// that has not been revoked or expired, most recently used first.
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	query := `
+		SELECT id, family_id, user_id, ip_address, user_agent, expires_at, authenticated_at, created_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session := &models.Session{}
+		if err := rows.Scan(
+			&session.ID, &session.FamilyID, &session.UserID,
+			&session.IPAddress, &session.UserAgent, &session.ExpiresAt, &session.AuthenticatedAt, &session.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// IsFamilyActive reports whether the session identified by familyID still
+// has a usable refresh token.
+func (r *SessionRepository) IsFamilyActive(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE family_id = $1 AND revoked_at IS NULL AND rotated_at IS NULL AND expires_at > NOW()
+		)
+	`
+	var active bool
+	err := r.db.QueryRow(ctx, query, familyID).Scan(&active)
+	return active, err
+}
+
+// RevokeFamily revokes one of the user's sessions. It returns
+// pgx.ErrNoRows when the user has no such active session.
+func (r *SessionRepository) RevokeFamily(ctx context.Context, userID, familyID uuid.UUID, revokedAt time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $3 WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, userID, familyID, revokedAt)
+	return requireAffected(tag, err)
+}
+
+// RevokeOtherFamilies revokes every session of the user except keepFamilyID
+// and returns how many sessions were signed out.
+func (r *SessionRepository) RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID, revokedAt time.Time) (int64, error) {
+	query := `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = $3
+			WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL AND expires_at > NOW()
+			RETURNING family_id
+		)
+		SELECT COUNT(DISTINCT family_id) FROM revoked
+	`
+	var count int64
+	err := r.db.QueryRow(ctx, query, userID, keepFamilyID, revokedAt).Scan(&count)
+	return count, err
+}
+
+// DeleteExpired removes every refresh token row past its expiry and returns
+// how many were deleted.
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
 	query := `DELETE FROM sessions WHERE expires_at < NOW()`
-	_, err := r.db.Exec(ctx, query)
-	return err
+	tag, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteRotatedBefore removes refresh token rows that were rotated or
+// revoked before the given time. Presenting a deleted rotated token is then
+// rejected as unknown rather than treated as reuse.
+func (r *SessionRepository) DeleteRotatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM sessions WHERE rotated_at < $1 OR revoked_at < $1`
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
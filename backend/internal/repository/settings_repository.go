@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSettingsRepository(db *pgxpool.Pool) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) List(ctx context.Context, tenantID uuid.UUID) ([]*models.Setting, error) {
+	query := `
+		SELECT id, tenant_id, key, value, type, created_at, updated_at
+		FROM settings WHERE tenant_id = $1
+		ORDER BY key ASC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []*models.Setting
+	for rows.Next() {
+		setting := &models.Setting{}
+		err := rows.Scan(
+			&setting.ID, &setting.TenantID, &setting.Key, &setting.Value,
+			&setting.Type, &setting.CreatedAt, &setting.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func (r *SettingsRepository) GetByKey(ctx context.Context, tenantID uuid.UUID, key string) (*models.Setting, error) {
+	query := `
+		SELECT id, tenant_id, key, value, type, created_at, updated_at
+		FROM settings WHERE tenant_id = $1 AND key = $2
+	`
+	setting := &models.Setting{}
+	err := r.db.QueryRow(ctx, query, tenantID, key).Scan(
+		&setting.ID, &setting.TenantID, &setting.Key, &setting.Value,
+		&setting.Type, &setting.CreatedAt, &setting.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+func (r *SettingsRepository) Upsert(ctx context.Context, setting *models.Setting) error {
+	return upsertSetting(ctx, r.db, setting)
+}
+
+// BulkUpsert writes all settings in a single transaction so a bulk update
+// either applies completely or not at all.
+func (r *SettingsRepository) BulkUpsert(ctx context.Context, settings []*models.Setting) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, setting := range settings {
+		if err := upsertSetting(ctx, tx, setting); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SettingsRepository) Delete(ctx context.Context, tenantID uuid.UUID, key string) error {
+	query := `DELETE FROM settings WHERE tenant_id = $1 AND key = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, key)
+	return err
+}
+
+type settingsQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func upsertSetting(ctx context.Context, q settingsQuerier, setting *models.Setting) error {
+	query := `
+		INSERT INTO settings (id, tenant_id, key, value, type, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (tenant_id, key) DO UPDATE SET
+			value = EXCLUDED.value,
+			type = EXCLUDED.type,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at, updated_at
+	`
+	if setting.ID == uuid.Nil {
+		setting.ID = uuid.New()
+	}
+	return q.QueryRow(ctx, query,
+		setting.ID, setting.TenantID, setting.Key, setting.Value, setting.Type, time.Now(),
+	).Scan(&setting.ID, &setting.CreatedAt, &setting.UpdatedAt)
+}
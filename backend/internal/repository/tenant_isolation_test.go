@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// These tests look up, change and delete one tenant's records while scoped
+// to another tenant. Every such call must behave as if the record did not
+// exist and leave it untouched.
+
+func TestUserRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewUserRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	user := &models.User{
+		ID:           uuid.New(),
+		TenantID:     owner.ID,
+		Email:        "isolation@example.com",
+		PasswordHash: "hash",
+		FirstName:    "Isolation",
+		LastName:     "Test",
+		Status:       "active",
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, other.ID, user.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := repo.GetByEmail(ctx, other.ID, user.Email); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByEmail from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+
+	foreign := *user
+	foreign.TenantID = other.ID
+	foreign.FirstName = "Changed"
+	if err := repo.Update(ctx, &foreign); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Update from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.UpdatePassword(ctx, other.ID, user.ID, "changed"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("UpdatePassword from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.Lock(ctx, other.ID, user.ID, time.Now().Add(time.Hour)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Lock from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.Delete(ctx, other.ID, user.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Delete from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+
+	got, err := repo.GetByID(ctx, owner.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID from owner: %v", err)
+	}
+	if got.FirstName != user.FirstName || got.PasswordHash != user.PasswordHash || got.LockedUntil != nil {
+		t.Errorf("user was changed from another tenant: %+v", got)
+	}
+}
+
+func TestRoleRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewRoleRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	role := &models.Role{
+		ID:          uuid.New(),
+		TenantID:    owner.ID,
+		Name:        "Isolation",
+		Description: "Owned by one tenant",
+	}
+	if err := repo.Create(ctx, role); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, other.ID, role.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := repo.GetByName(ctx, other.ID, role.Name); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByName from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+
+	foreign := *role
+	foreign.TenantID = other.ID
+	foreign.Name = "Changed"
+	if err := repo.Update(ctx, &foreign); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Update from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.Delete(ctx, other.ID, role.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Delete from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+
+	got, err := repo.GetByID(ctx, owner.ID, role.ID)
+	if err != nil {
+		t.Fatalf("GetByID from owner: %v", err)
+	}
+	if got.Name != role.Name {
+		t.Errorf("role was renamed from another tenant to %q", got.Name)
+	}
+}
+
+func TestSettingsRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewSettingsRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	setting := &models.Setting{
+		ID:       uuid.New(),
+		TenantID: owner.ID,
+		Key:      "isolation_probe",
+		Value:    "owner",
+		Type:     "string",
+	}
+	if err := repo.Upsert(ctx, setting); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	if _, err := repo.GetByKey(ctx, other.ID, setting.Key); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByKey from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	settings, err := repo.List(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("List from other tenant: %v", err)
+	}
+	for _, s := range settings {
+		if s.Key == setting.Key {
+			t.Errorf("List from other tenant returned %q", s.Key)
+		}
+	}
+	if err := repo.Delete(ctx, other.ID, setting.Key); err != nil {
+		t.Fatalf("Delete from other tenant: %v", err)
+	}
+
+	got, err := repo.GetByKey(ctx, owner.ID, setting.Key)
+	if err != nil {
+		t.Fatalf("GetByKey from owner after delete from other tenant: %v", err)
+	}
+	if got.Value != setting.Value {
+		t.Errorf("setting was changed from another tenant to %q", got.Value)
+	}
+}
+
+func TestAuditLogRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewAuditLogRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	entry := &models.AuditLog{
+		ID:        uuid.New(),
+		TenantID:  owner.ID,
+		Action:    "create",
+		Resource:  "isolation_probe",
+		IPAddress: "203.0.113.10",
+		UserAgent: "test",
+		CreatedAt: time.Now(),
+	}
+	if err := repo.Log(ctx, entry); err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, other.ID, entry.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	logs, total, err := repo.List(ctx, &models.AuditLogFilter{TenantID: other.ID}, 1, 100)
+	if err != nil {
+		t.Fatalf("List from other tenant: %v", err)
+	}
+	if total != 0 || len(logs) != 0 {
+		t.Errorf("List from other tenant returned %d entries, want none", total)
+	}
+	if _, err := repo.GetByID(ctx, owner.ID, entry.ID); err != nil {
+		t.Errorf("GetByID from owner: %v", err)
+	}
+}
+
+func TestFeatureFlagRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewFeatureFlagRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	flag := &models.FeatureFlag{
+		TenantID: owner.ID,
+		Key:      "isolation_probe",
+		Name:     "Isolation",
+		Enabled:  true,
+	}
+	if err := repo.Create(ctx, flag); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, other.ID, flag.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if _, err := repo.GetByKey(ctx, other.ID, flag.Key); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByKey from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if repo.IsEnabled(ctx, other.ID, flag.Key) {
+		t.Error("IsEnabled from other tenant: got true, want false")
+	}
+
+	foreign := *flag
+	foreign.TenantID = other.ID
+	foreign.Name = "Changed"
+	foreign.Enabled = false
+	if err := repo.Update(ctx, &foreign); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Update from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.Delete(ctx, other.ID, flag.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Delete from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+
+	got, err := repo.GetByID(ctx, owner.ID, flag.ID)
+	if err != nil {
+		t.Fatalf("GetByID from owner: %v", err)
+	}
+	if got.Name != flag.Name || !got.Enabled {
+		t.Errorf("feature flag was changed from another tenant: %+v", got)
+	}
+}
+
+func TestAdminAuthRepositoryTenantIsolation(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewAdminAuthRepository(db)
+	owner := testdb.Tenant(t, db)
+	other := testdb.Tenant(t, db)
+
+	user := &models.User{
+		ID:           uuid.New(),
+		TenantID:     owner.ID,
+		Email:        "admin-isolation@example.com",
+		PasswordHash: "hash",
+		FirstName:    "Admin",
+		LastName:     "Isolation",
+		Status:       "active",
+	}
+	if err := NewUserRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := repo.SetAdmin(ctx, owner.ID, user.ID, "owner-hash"); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+
+	if _, err := repo.GetByUserID(ctx, other.ID, user.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByUserID from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if isAdmin, _ := repo.IsAdmin(ctx, other.ID, user.ID); isAdmin {
+		t.Error("IsAdmin from other tenant: got true, want false")
+	}
+	if err := repo.SetAdmin(ctx, other.ID, user.ID, "changed"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("SetAdmin from other tenant: got %v, want pgx.ErrNoRows", err)
+	}
+	if err := repo.UnsetAdmin(ctx, other.ID, user.ID); err != nil {
+		t.Fatalf("UnsetAdmin from other tenant: %v", err)
+	}
+	if err := repo.Delete(ctx, other.ID, user.ID); err != nil {
+		t.Fatalf("Delete from other tenant: %v", err)
+	}
+
+	got, err := repo.GetByUserID(ctx, owner.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID from owner: %v", err)
+	}
+	if !got.IsAdmin || got.AdminPasswordHash != "owner-hash" {
+		t.Errorf("admin access was changed from another tenant: %+v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantRepository(db *pgxpool.Pool) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+// CreateWithRole inserts a tenant together with its first role holding
+// the given permissions. Either all of them are created or none, so a
+// failed attempt can be retried with the same slug.
+func (r *TenantRepository) CreateWithRole(ctx context.Context, tenant *models.Tenant, role *models.Role, permissionIDs []uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tenants (id, name, slug, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, tenant.ID, tenant.Name, tenant.Slug, tenant.Status, now, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO roles (id, tenant_id, name, description, is_system, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, role.ID, tenant.ID, role.Name, role.Description, role.IsSystem, now, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_id, permission_id, created_at)
+		SELECT $1, unnest($2::uuid[]), $3
+	`, role.ID, permissionIDs, now)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tenant.CreatedAt, tenant.UpdatedAt = now, now
+	role.TenantID, role.CreatedAt, role.UpdatedAt = tenant.ID, now, now
+	return nil
+}
+
+func (r *TenantRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
+	query := `
+		SELECT id, name, slug, status, created_at, updated_at
+		FROM tenants WHERE id = $1
+	`
+	tenant := &models.Tenant{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&tenant.ID, &tenant.Name, &tenant.Slug, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (r *TenantRepository) GetBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	query := `
+		SELECT id, name, slug, status, created_at, updated_at
+		FROM tenants WHERE slug = $1
+	`
+	tenant := &models.Tenant{}
+	err := r.db.QueryRow(ctx, query, slug).Scan(
+		&tenant.ID, &tenant.Name, &tenant.Slug, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (r *TenantRepository) GetStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx, "SELECT status FROM tenants WHERE id = $1", id).Scan(&status)
+	return status, err
+}
+
+// ListIDs returns the IDs of every tenant regardless of status.
+func (r *TenantRepository) ListIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, "SELECT id FROM tenants ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *TenantRepository) List(ctx context.Context, params *models.ListParams) ([]*models.Tenant, int64, error) {
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if params.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR slug ILIKE $%d)", argCount, argCount))
+		args = append(args, "%"+params.Search+"%")
+		argCount++
+	}
+
+	if status, ok := params.Filters["status"].(string); ok && status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argCount))
+		args = append(args, status)
+		argCount++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM tenants %s", whereClause)
+	var total int64
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn := "created_at"
+	if params.Sort != "" {
+		allowedSorts := map[string]bool{"name": true, "slug": true, "status": true, "created_at": true}
+		if allowedSorts[params.Sort] {
+			sortColumn = params.Sort
+		}
+	}
+
+	sortOrder := "DESC"
+	if params.Order == "asc" {
+		sortOrder = "ASC"
+	}
+
+	offset := (params.Page - 1) * params.PerPage
+
+	query := fmt.Sprintf(`
+		SELECT id, name, slug, status, created_at, updated_at
+		FROM tenants %s
+		ORDER BY %s %s
+		LIMIT $%d OFFSET $%d
+	`, whereClause, sortColumn, sortOrder, argCount, argCount+1)
+
+	args = append(args, params.PerPage, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var tenants []*models.Tenant
+	for rows.Next() {
+		tenant := &models.Tenant{}
+		err := rows.Scan(
+			&tenant.ID, &tenant.Name, &tenant.Slug, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, total, nil
+}
+
+func (r *TenantRepository) Update(ctx context.Context, tenant *models.Tenant) error {
+	query := `UPDATE tenants SET name = $2, slug = $3, status = $4, updated_at = $5 WHERE id = $1`
+	tenant.UpdatedAt = time.Now()
+	_, err := r.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.Slug, tenant.Status, tenant.UpdatedAt)
+	return err
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/testdb"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestTenantRepositoryCreateWithRoleIsAtomic(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	repo := NewTenantRepository(db)
+
+	tenant := &models.Tenant{ID: uuid.New(), Name: "Atomic", Slug: "atomic-" + uuid.NewString()[:8], Status: "active"}
+	role := &models.Role{ID: uuid.New(), Name: "Admin", IsSystem: true}
+
+	// An unknown permission fails the last insert; the tenant and role
+	// inserted before it must not survive.
+	if err := repo.CreateWithRole(ctx, tenant, role, []uuid.UUID{uuid.New()}); err == nil {
+		t.Fatal("CreateWithRole with an unknown permission succeeded")
+	}
+	if _, err := repo.GetBySlug(ctx, tenant.Slug); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetBySlug after failed create = %v, want ErrNoRows", err)
+	}
+	var roles int
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM roles WHERE id = $1", role.ID).Scan(&roles); err != nil {
+		t.Fatalf("count roles: %v", err)
+	}
+	if roles != 0 {
+		t.Errorf("failed create left %d roles behind", roles)
+	}
+
+	// The same slug can be used again once the attempt has rolled back.
+	if err := repo.CreateWithRole(ctx, tenant, role, nil); err != nil {
+		t.Fatalf("CreateWithRole retry: %v", err)
+	}
+	if role.TenantID != tenant.ID {
+		t.Errorf("role.TenantID = %v, want %v", role.TenantID, tenant.ID)
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	query := `
+		SELECT user_id, secret, enabled, confirmed_at, last_used_step, created_at, updated_at
+		FROM user_totp WHERE user_id = $1
+	`
+	totp := &models.UserTOTP{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&totp.UserID, &totp.Secret, &totp.Enabled, &totp.ConfirmedAt,
+		&totp.LastUsedStep, &totp.CreatedAt, &totp.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return totp, nil
+}
+
+// SavePendingTOTP stores a new unconfirmed secret, replacing any earlier
+// pending enrollment. It never overwrites an enabled one.
+func (r *TwoFactorRepository) SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, enabled, last_used_step, created_at, updated_at)
+		VALUES ($1, $2, FALSE, 0, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			updated_at = EXCLUDED.updated_at
+		WHERE user_totp.enabled = FALSE
+	`
+	tag, err := r.db.Exec(ctx, query, userID, secret, time.Now())
+	return requireAffected(tag, err)
+}
+
+// EnableTOTP confirms a pending enrollment and replaces the user's recovery
+// codes in one transaction.
+func (r *TwoFactorRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_totp SET enabled = TRUE, confirmed_at = $2, last_used_step = $3, updated_at = $2
+		WHERE user_id = $1 AND enabled = FALSE
+	`, userID, now, step)
+	if err := requireAffected(tag, err); err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes, now); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MarkStepUsed records the time step of an accepted code. It fails when a
+// code from the same or a later step was already accepted, which rejects
+// replays.
+func (r *TwoFactorRepository) MarkStepUsed(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_totp SET last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND enabled = TRUE AND last_used_step < $2
+	`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	return requireAffected(tag, err)
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes, time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode consumes an unused recovery code. It returns
+// pgx.ErrNoRows when the code does not exist or was already used.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, userID, codeHash)
+	return requireAffected(tag, err)
+}
+
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// Delete removes the user's TOTP enrollment and recovery codes.
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string, now time.Time) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)",
+			uuid.New(), userID, hash, now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
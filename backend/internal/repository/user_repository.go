@@ -36,16 +36,16 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	return err
 }
 
-func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at
-		FROM users WHERE id = $1
+		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at, locked_until
+		FROM users WHERE id = $1 AND tenant_id = $2
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&user.ID, &user.TenantID, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Status,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.LockedUntil,
 	)
 	if err != nil {
 		return nil, err
@@ -53,16 +53,16 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	return user, nil
 }
 
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, tenantID uuid.UUID, email string) (*models.User, error) {
 	query := `
-		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at
-		FROM users WHERE email = $1
+		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at, locked_until
+		FROM users WHERE tenant_id = $1 AND email = $2
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(
+	err := r.db.QueryRow(ctx, query, tenantID, email).Scan(
 		&user.ID, &user.TenantID, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Status,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.LockedUntil,
 	)
 	if err != nil {
 		return nil, err
@@ -122,7 +122,7 @@ func (r *UserRepository) List(ctx context.Context, params *models.ListParams) ([
 	offset := (params.Page - 1) * params.PerPage
 
 	query := fmt.Sprintf(`
-		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at
+		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at, last_login_at, locked_until
 		FROM users %s
 		ORDER BY %s %s
 		LIMIT $%d OFFSET $%d
@@ -142,7 +142,7 @@ func (r *UserRepository) List(ctx context.Context, params *models.ListParams) ([
 		err := rows.Scan(
 			&user.ID, &user.TenantID, &user.Email, &user.PasswordHash,
 			&user.FirstName, &user.LastName, &user.Status,
-			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.LockedUntil,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -155,29 +155,118 @@ func (r *UserRepository) List(ctx context.Context, params *models.ListParams) ([
 
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
-		UPDATE users SET email = $2, first_name = $3, last_name = $4, status = $5, updated_at = $6
-		WHERE id = $1
+		UPDATE users SET email = $3, first_name = $4, last_name = $5, status = $6, updated_at = $7
+		WHERE id = $1 AND tenant_id = $2
 	`
 	user.UpdatedAt = time.Now()
-	_, err := r.db.Exec(ctx, query, user.ID, user.Email, user.FirstName, user.LastName, user.Status, user.UpdatedAt)
-	return err
+	tag, err := r.db.Exec(ctx, query, user.ID, user.TenantID, user.Email, user.FirstName, user.LastName, user.Status, user.UpdatedAt)
+	return requireAffected(tag, err)
 }
 
-func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
-	return err
+func (r *UserRepository) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM users WHERE id = $1 AND tenant_id = $2`
+	tag, err := r.db.Exec(ctx, query, id, tenantID)
+	return requireAffected(tag, err)
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, tenantID, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $3, password_changed_at = $4, updated_at = $4 WHERE id = $1 AND tenant_id = $2`
+	tag, err := r.db.Exec(ctx, query, id, tenantID, passwordHash, time.Now())
+	return requireAffected(tag, err)
+}
+
+// UpdatePasswordHash replaces the stored hash of an unchanged password, for
+// upgrading it to the current hashing scheme. Unlike UpdatePassword it does
+// not count as a password change. The update only applies while the old
+// hash is still in place so it cannot overwrite a concurrent change.
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, tenantID, id uuid.UUID, oldHash, newHash string) error {
+	query := `UPDATE users SET password_hash = $4 WHERE id = $1 AND tenant_id = $2 AND password_hash = $3`
+	tag, err := r.db.Exec(ctx, query, id, tenantID, oldHash, newHash)
+	return requireAffected(tag, err)
+}
+
+// CountByPasswordHashScheme counts the tenant's users by the header of
+// their password hash: "$argon2id$v=19$m=65536,t=3,p=2$" or "$2a$12$".
+// Users without a password, such as pending invitees, are not counted.
+func (r *UserRepository) CountByPasswordHashScheme(ctx context.Context, tenantID uuid.UUID) (map[string]int64, error) {
+	query := `
+		SELECT COALESCE(substring(password_hash FROM '^\$argon2id\$[^$]*\$[^$]*\$|^\$2[abxy]?\$[0-9]+\$'), ''), COUNT(*)
+		FROM users
+		WHERE tenant_id = $1 AND password_hash <> ''
+		GROUP BY 1
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]int64)
+	for rows.Next() {
+		var scheme string
+		var count int64
+		if err := rows.Scan(&scheme, &count); err != nil {
+			return nil, err
+		}
+		result[scheme] = count
+	}
+	return result, rows.Err()
 }
 
-func (r *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
-	query := `UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id, passwordHash, time.Now())
+func (r *UserRepository) GetPasswordChangedAt(ctx context.Context, tenantID, id uuid.UUID) (time.Time, error) {
+	var changedAt time.Time
+	err := r.db.QueryRow(ctx,
+		"SELECT password_changed_at FROM users WHERE id = $1 AND tenant_id = $2",
+		id, tenantID,
+	).Scan(&changedAt)
+	return changedAt, err
+}
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE users SET last_login_at = $3 WHERE id = $1 AND tenant_id = $2`
+	_, err := r.db.Exec(ctx, query, id, tenantID, time.Now())
 	return err
 }
 
-func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE users SET last_login_at = $2 WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id, time.Now())
+// RecordFailedLogin counts a failed password attempt. Attempts made before
+// windowStart no longer count. It returns the attempts in the current
+// window and how many lockouts the user has had since they last signed in.
+func (r *UserRepository) RecordFailedLogin(ctx context.Context, tenantID, id uuid.UUID, windowStart time.Time) (int, int, error) {
+	query := `
+		UPDATE users SET
+			failed_login_attempts = CASE
+				WHEN last_failed_login_at IS NULL OR last_failed_login_at < $3 THEN 1
+				ELSE failed_login_attempts + 1
+			END,
+			last_failed_login_at = $4
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING failed_login_attempts, lockout_count
+	`
+	var attempts, lockouts int
+	err := r.db.QueryRow(ctx, query, id, tenantID, windowStart, time.Now()).Scan(&attempts, &lockouts)
+	return attempts, lockouts, err
+}
+
+// Lock blocks password logins until lockedUntil and starts a new attempt
+// window.
+func (r *UserRepository) Lock(ctx context.Context, tenantID, id uuid.UUID, lockedUntil time.Time) error {
+	query := `
+		UPDATE users SET locked_until = $3, lockout_count = lockout_count + 1, failed_login_attempts = 0
+		WHERE id = $1 AND tenant_id = $2
+	`
+	tag, err := r.db.Exec(ctx, query, id, tenantID, lockedUntil)
+	return requireAffected(tag, err)
+}
+
+// ResetFailedLogins clears failed attempts, any lockout and the lockout
+// backoff. It only writes when there is something to clear.
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `
+		UPDATE users SET failed_login_attempts = 0, last_failed_login_at = NULL, locked_until = NULL, lockout_count = 0
+		WHERE id = $1 AND tenant_id = $2
+			AND (failed_login_attempts > 0 OR locked_until IS NOT NULL OR lockout_count > 0)
+	`
+	_, err := r.db.Exec(ctx, query, id, tenantID)
 	return err
 }
 
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebAuthnRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebAuthnRepository(db *pgxpool.Pool) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+const webAuthnCredentialColumns = `
+	c.id, c.user_id, c.credential_id, c.public_key, c.algorithm, c.sign_count,
+	c.transports, c.aaguid, c.name, c.created_at, c.last_used_at
+`
+
+func scanWebAuthnCredential(row pgx.Row) (*models.WebAuthnCredential, error) {
+	cred := &models.WebAuthnCredential{}
+	var signCount int64
+	err := row.Scan(
+		&cred.ID, &cred.UserID, &cred.CredentialID, &cred.PublicKey, &cred.Algorithm, &signCount,
+		&cred.Transports, &cred.AAGUID, &cred.Name, &cred.CreatedAt, &cred.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	cred.SignCount = uint32(signCount)
+	return cred, nil
+}
+
+func (r *WebAuthnRepository) CreateCredential(ctx context.Context, cred *models.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials
+			(id, user_id, credential_id, public_key, algorithm, sign_count, transports, aaguid, name, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.Exec(ctx, query,
+		cred.ID, cred.UserID, cred.CredentialID, cred.PublicKey, cred.Algorithm, int64(cred.SignCount),
+		cred.Transports, cred.AAGUID, cred.Name, cred.CreatedAt,
+	)
+	return err
+}
+
+// ListByUser returns the user's credentials. The tenant join keeps lookups
+// scoped to the caller's tenant.
+func (r *WebAuthnRepository) ListByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials c
+		JOIN users u ON u.id = c.user_id
+		WHERE u.tenant_id = $1 AND c.user_id = $2
+		ORDER BY c.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var creds []*models.WebAuthnCredential
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+// GetByCredentialID finds a credential by the authenticator's credential
+// ID within a tenant.
+func (r *WebAuthnRepository) GetByCredentialID(ctx context.Context, tenantID uuid.UUID, credentialID []byte) (*models.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials c
+		JOIN users u ON u.id = c.user_id
+		WHERE u.tenant_id = $1 AND c.credential_id = $2
+	`
+	return scanWebAuthnCredential(r.db.QueryRow(ctx, query, tenantID, credentialID))
+}
+
+func (r *WebAuthnRepository) HasCredentials(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM webauthn_credentials WHERE user_id = $1)",
+		userID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *WebAuthnRepository) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount uint32, usedAt time.Time) error {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE webauthn_credentials SET sign_count = $2, last_used_at = $3 WHERE id = $1",
+		id, int64(signCount), usedAt,
+	)
+	return requireAffected(tag, err)
+}
+
+func (r *WebAuthnRepository) DeleteCredential(ctx context.Context, tenantID, userID, id uuid.UUID) error {
+	query := `
+		DELETE FROM webauthn_credentials c
+		USING users u
+		WHERE u.id = c.user_id AND u.tenant_id = $1 AND c.user_id = $2 AND c.id = $3
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, userID, id)
+	return requireAffected(tag, err)
+}
+
+func (r *WebAuthnRepository) CreateSession(ctx context.Context, session *models.WebAuthnSession) error {
+	query := `
+		INSERT INTO webauthn_sessions (id, tenant_id, user_id, ceremony, challenge, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := r.db.Exec(ctx, query,
+		session.ID, session.TenantID, session.UserID, session.Ceremony, session.Challenge, session.ExpiresAt,
+	)
+	return err
+}
+
+// ConsumeSession deletes and returns an unexpired ceremony session so each
+// challenge can be answered at most once.
+func (r *WebAuthnRepository) ConsumeSession(ctx context.Context, id uuid.UUID, ceremony string) (*models.WebAuthnSession, error) {
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE id = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING id, tenant_id, user_id, ceremony, challenge, expires_at
+	`
+	session := &models.WebAuthnSession{}
+	err := r.db.QueryRow(ctx, query, id, ceremony).Scan(
+		&session.ID, &session.TenantID, &session.UserID, &session.Ceremony,
+		&session.Challenge, &session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// DeleteExpiredSessions removes ceremonies that can no longer be finished
+// and returns how many were removed.
+func (r *WebAuthnRepository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM webauthn_sessions WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
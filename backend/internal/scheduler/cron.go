@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule reports when a job is next due.
+type Schedule interface {
+	// Next returns the first due time strictly after t, or the zero time if
+	// the schedule never fires again.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a schedule specification. It accepts five-field cron
+// expressions (minute, hour, day of month, month, day of week) evaluated in
+// UTC, the shorthands @hourly, @daily, @weekly and @monthly, and
+// "@every <duration>". Cron fields support *, lists, ranges and steps; day
+// of week runs from 0 (Sunday) to 6 and also accepts 7 for Sunday.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least one second", spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+// everySchedule fires at fixed intervals aligned to the Unix epoch, so every
+// replica computes the same due times.
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// cronSchedule holds one bit per allowed value of each field.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// maxCronSteps bounds the search for the next due time. Every step advances
+// at least to the next hour, so this covers several years.
+const maxCronSteps = 100_000
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	for i := 0; i < maxCronSteps; i++ {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		default:
+			for m := t.Minute(); m < 60; m++ {
+				if s.minute&(1<<uint(m)) != 0 {
+					return t.Add(time.Duration(m-t.Minute()) * time.Minute)
+				}
+			}
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted a day
+// matching either one is due.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = min, max
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(from, min, max); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(to, min, max); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			var err error
+			if lo, err = parseValue(rangePart, min, max); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if hasStep {
+				hi = max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, min, max int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, min, max)
+	}
+	return v, nil
+}
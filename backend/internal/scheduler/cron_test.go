@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// 2026-01-15 is a Thursday.
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"every minute", "* * * * *", "2026-01-15T10:30:00Z", "2026-01-15T10:31:00Z"},
+		{"seconds are dropped", "* * * * *", "2026-01-15T10:30:59Z", "2026-01-15T10:31:00Z"},
+		{"list", "10,40 * * * *", "2026-01-15T10:30:00Z", "2026-01-15T10:40:00Z"},
+		{"step", "*/15 * * * *", "2026-01-15T10:30:00Z", "2026-01-15T10:45:00Z"},
+		{"step into next hour", "*/15 * * * *", "2026-01-15T10:45:30Z", "2026-01-15T11:00:00Z"},
+		{"step from value", "5/15 * * * *", "2026-01-15T10:30:00Z", "2026-01-15T10:35:00Z"},
+		{"range with step", "0 9-17/4 * * *", "2026-01-15T10:30:00Z", "2026-01-15T13:00:00Z"},
+		{"next day", "30 2 * * *", "2026-01-15T10:30:00Z", "2026-01-16T02:30:00Z"},
+		{"sunday as 0", "0 0 * * 0", "2026-01-15T10:30:00Z", "2026-01-18T00:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "2026-01-15T10:30:00Z", "2026-01-18T00:00:00Z"},
+		{"range ending at 7", "0 0 * * 6-7", "2026-01-15T10:30:00Z", "2026-01-17T00:00:00Z"},
+		{"day of month only", "0 0 1 * *", "2026-01-15T10:30:00Z", "2026-02-01T00:00:00Z"},
+		{"day of week only", "0 0 * * 1", "2026-01-15T10:30:00Z", "2026-01-19T00:00:00Z"},
+		{"day of month or week: weekday first", "0 0 20 * 1", "2026-01-15T10:30:00Z", "2026-01-19T00:00:00Z"},
+		{"day of month or week: day first", "0 0 20 * 1", "2026-01-19T00:00:00Z", "2026-01-20T00:00:00Z"},
+		{"month step", "0 0 1 */3 *", "2026-01-15T10:30:00Z", "2026-04-01T00:00:00Z"},
+		{"year rollover", "0 0 1 * *", "2026-12-15T10:30:00Z", "2027-01-01T00:00:00Z"},
+		{"skips short months", "0 0 31 * *", "2026-01-31T00:00:00Z", "2026-03-31T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "2026-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"evaluated in UTC", "0 13 * * *", "2026-01-15T14:00:00+02:00", "2026-01-15T13:00:00Z"},
+		{"never", "0 0 30 2 *", "2026-01-15T10:30:00Z", "0001-01-01T00:00:00Z"},
+		{"hourly", "@hourly", "2026-01-15T10:30:00Z", "2026-01-15T11:00:00Z"},
+		{"daily", "@daily", "2026-01-15T10:30:00Z", "2026-01-16T00:00:00Z"},
+		{"weekly", "@weekly", "2026-01-15T10:30:00Z", "2026-01-18T00:00:00Z"},
+		{"monthly", "@monthly", "2026-01-15T10:30:00Z", "2026-02-01T00:00:00Z"},
+		{"every, aligned to the epoch", "@every 90m", "2026-01-15T10:30:00Z", "2026-01-15T12:00:00Z"},
+		{"every, between due times", "@every 90m", "2026-01-15T10:31:00Z", "2026-01-15T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			from, err := time.Parse(time.RFC3339, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := time.Parse(time.RFC3339, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(from); !got.Equal(want) {
+				t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidSchedules(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"@every nope",
+		"@every 500ms",
+		"@yearly",
+	}
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Package scheduler runs periodic maintenance jobs inside the server
+// process. Every replica runs the scheduler; a Postgres advisory lock per
+// job and a run record per due time make sure each run happens on only one
+// of them. Runs are recorded in the job_runs table.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobBusy    = errors.New("job is already running")
+)
+
+// Func performs one run of a job and returns how many records it affected.
+type Func func(ctx context.Context) (int64, error)
+
+// Job is a named unit of work run on a schedule; see Parse for the
+// schedule syntax. Timeout bounds a single run and defaults to the
+// scheduler's timeout.
+type Job struct {
+	Name     string
+	Schedule string
+	Timeout  time.Duration
+	Run      Func
+}
+
+// JobStatus describes a registered job for the admin API.
+type JobStatus struct {
+	Name      string         `json:"name"`
+	Schedule  string         `json:"schedule"`
+	NextRunAt *time.Time     `json:"next_run_at,omitempty"`
+	LastRun   *models.JobRun `json:"last_run,omitempty"`
+}
+
+type job struct {
+	Job
+	schedule Schedule
+}
+
+type Scheduler struct {
+	runs             *repository.JobRunRepository
+	timeout          time.Duration
+	historyRetention time.Duration
+	instance         string
+	logger           zerolog.Logger
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a scheduler recording runs in runs. timeout bounds each run
+// unless the job sets its own, and run history older than historyRetention
+// is deleted after every run; zero keeps it forever.
+func New(runs *repository.JobRunRepository, timeout, historyRetention time.Duration, logger zerolog.Logger) *Scheduler {
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		runs:             runs,
+		timeout:          timeout,
+		historyRetention: historyRetention,
+		instance:         fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		logger:           logger.With().Str("component", "scheduler").Logger(),
+		jobs:             make(map[string]*job),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(j Job) error {
+	schedule, err := Parse(j.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("job %s: schedule %q never fires", j.Name, j.Schedule)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s: scheduler already started", j.Name)
+	}
+	if _, exists := s.jobs[j.Name]; exists {
+		return fmt.Errorf("job %s: already registered", j.Name)
+	}
+	s.jobs[j.Name] = &job{Job: j, schedule: schedule}
+	return nil
+}
+
+// Start runs every registered job on its schedule until Stop is called.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+		s.logger.Info().Str("job", j.Name).Str("schedule", j.Schedule).Msg("Scheduled job")
+	}
+}
+
+// Stop cancels runs in progress and waits for them to be recorded.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Jobs lists the registered jobs with their next due time and latest run.
+func (s *Scheduler) Jobs(ctx context.Context) ([]*JobStatus, error) {
+	latest, err := s.runs.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]*JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status := &JobStatus{
+			Name:     j.Name,
+			Schedule: j.Schedule,
+			LastRun:  latest[j.Name],
+		}
+		if s.started {
+			if next := j.schedule.Next(now); !next.IsZero() {
+				status.NextRunAt = &next
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].Name < statuses[k].Name
+	})
+	return statuses, nil
+}
+
+// Runs returns the most recent runs of a job, newest first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]*models.JobRun, error) {
+	if _, err := s.job(name); err != nil {
+		return nil, err
+	}
+	runs, err := s.runs.ListByJob(ctx, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []*models.JobRun{}
+	}
+	return runs, nil
+}
+
+// RunNow starts a job outside its schedule and returns the run record
+// without waiting for it to finish. It returns ErrJobBusy when the job is
+// already running on any instance.
+func (s *Scheduler) RunNow(ctx context.Context, name string, triggeredBy uuid.UUID) (*models.JobRun, error) {
+	j, err := s.job(name)
+	if err != nil {
+		return nil, err
+	}
+
+	run, unlock, err := s.claim(ctx, j, time.Now(), TriggerManual, &triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, ErrJobBusy
+	}
+
+	// Report the run as it was claimed; execute updates it concurrently.
+	claimed := *run
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(j, run, unlock)
+	}()
+	return &claimed, nil
+}
+
+func (s *Scheduler) job(name string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	return j, nil
+}
+
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		run, unlock, err := s.claim(s.ctx, j, next, TriggerSchedule, nil)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.logger.Error().Err(err).Str("job", j.Name).Msg("Failed to start job")
+			}
+			continue
+		}
+		if run == nil {
+			// Another instance is running or has already run this slot.
+			continue
+		}
+		s.execute(j, run, unlock)
+	}
+}
+
+// claim takes the job's lock and records a run for the given due time. It
+// returns a nil run when another instance holds the lock or has already
+// claimed that due time.
+func (s *Scheduler) claim(ctx context.Context, j *job, scheduledAt time.Time, trigger string, triggeredBy *uuid.UUID) (*models.JobRun, func(), error) {
+	unlock, locked, err := s.runs.TryLock(ctx, j.Name)
+	if err != nil || !locked {
+		return nil, nil, err
+	}
+
+	run := &models.JobRun{
+		ID:          uuid.New(),
+		JobName:     j.Name,
+		ScheduledAt: scheduledAt.UTC(),
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      StatusRunning,
+		Instance:    s.instance,
+		StartedAt:   time.Now(),
+	}
+	claimed, err := s.runs.Claim(ctx, run)
+	if err != nil || !claimed {
+		unlock()
+		return nil, nil, err
+	}
+	return run, unlock, nil
+}
+
+// execute runs a claimed job, records the outcome and releases the lock.
+func (s *Scheduler) execute(j *job, run *models.JobRun, unlock func()) {
+	defer unlock()
+
+	timeout := j.Timeout
+	if timeout <= 0 {
+		timeout = s.timeout
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	affected, err := s.call(ctx, j)
+	cancel()
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Affected = affected
+	run.Status = StatusSucceeded
+	if err != nil {
+		message := err.Error()
+		run.Error = &message
+		run.Status = StatusFailed
+	}
+
+	// Record the outcome even when the run was cut short by shutdown.
+	recordCtx := context.WithoutCancel(ctx)
+	if err := s.runs.Finish(recordCtx, run); err != nil {
+		s.logger.Error().Err(err).Str("job", j.Name).Msg("Failed to record job run")
+	}
+
+	event := s.logger.Info()
+	if run.Error != nil {
+		event = s.logger.Error().Str("error", *run.Error)
+	}
+	event.Str("job", j.Name).Str("trigger", run.Trigger).Int64("affected", affected).
+		Dur("duration", finishedAt.Sub(run.StartedAt)).Msg("Job finished")
+
+	if s.historyRetention > 0 {
+		if _, err := s.runs.DeleteBefore(recordCtx, finishedAt.Add(-s.historyRetention)); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to delete old job runs")
+		}
+	}
+}
+
+// call runs the job, turning a panic into an error so one faulty job cannot
+// take the server down.
+func (s *Scheduler) call(ctx context.Context, j *job) (affected int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return j.Run(ctx)
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// auditChainGenesis is the previous hash of a tenant's first audit entry.
+var auditChainGenesis = strings.Repeat("0", 64)
+
+// errStopWalk ends a chain walk once a break has been found.
+var errStopWalk = errors.New("stop walk")
+
+// AuditChainBreak is the first link at which a tenant's audit chain fails
+// verification. ID is the offending entry, when there is one.
+type AuditChainBreak struct {
+	Seq    int64      `json:"seq"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Reason string     `json:"reason"`
+}
+
+// AuditChainReport is the result of walking a tenant's audit chain.
+// Checkpoints signed with a key other than the current one cannot be
+// checked and are counted as skipped. Anchor is the prune the chain starts
+// after, once the retention job has removed its oldest entries.
+type AuditChainReport struct {
+	TenantID            uuid.UUID                `json:"tenant_id"`
+	Valid               bool                     `json:"valid"`
+	FromSeq             int64                    `json:"from_seq"`
+	ToSeq               int64                    `json:"to_seq"`
+	Entries             int64                    `json:"entries"`
+	CheckpointsVerified int                      `json:"checkpoints_verified"`
+	CheckpointsSkipped  int                      `json:"checkpoints_skipped"`
+	Anchor              *models.AuditChainAnchor `json:"anchor,omitempty"`
+	Break               *AuditChainBreak         `json:"break,omitempty"`
+	VerifiedAt          time.Time                `json:"verified_at"`
+}
+
+func (r *AuditChainReport) fail(seq int64, id *uuid.UUID, reason string) {
+	r.Valid = false
+	r.Break = &AuditChainBreak{Seq: seq, ID: id, Reason: reason}
+}
+
+// AuditCheckpointList is a tenant's latest checkpoints together with the
+// public key that verifies the current signatures.
+type AuditCheckpointList struct {
+	KeyID       string                    `json:"key_id"`
+	PublicKey   string                    `json:"public_key"`
+	Checkpoints []*models.AuditCheckpoint `json:"checkpoints"`
+}
+
+// AuditCheckpointKey returns the key audit checkpoints are signed with:
+// the configured seed, or one derived from secret when none is configured.
+func AuditCheckpointKey(cfg config.AuditConfig, secret string) (ed25519.PrivateKey, error) {
+	if cfg.CheckpointKey == "" {
+		seed := sha256.Sum256([]byte("audit-checkpoint:" + secret))
+		return ed25519.NewKeyFromSeed(seed[:]), nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(cfg.CheckpointKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("AUDIT_CHECKPOINT_KEY must be a base64-encoded 32-byte key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// AuditChainService verifies the per-tenant hash chains of the audit log
+// and signs periodic checkpoints of them.
+type AuditChainService struct {
+	auditRepo      *repository.AuditLogRepository
+	checkpointRepo *repository.AuditCheckpointRepository
+	archiveRepo    *repository.AuditArchiveRepository
+	tenantRepo     *repository.TenantRepository
+	key            ed25519.PrivateKey
+	keyID          string
+	logger         zerolog.Logger
+}
+
+func NewAuditChainService(
+	auditRepo *repository.AuditLogRepository,
+	checkpointRepo *repository.AuditCheckpointRepository,
+	archiveRepo *repository.AuditArchiveRepository,
+	tenantRepo *repository.TenantRepository,
+	key ed25519.PrivateKey,
+	logger zerolog.Logger,
+) *AuditChainService {
+	fingerprint := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return &AuditChainService{
+		auditRepo:      auditRepo,
+		checkpointRepo: checkpointRepo,
+		archiveRepo:    archiveRepo,
+		tenantRepo:     tenantRepo,
+		key:            key,
+		keyID:          hex.EncodeToString(fingerprint[:8]),
+		logger:         logger,
+	}
+}
+
+// Verify walks a tenant's whole audit chain, from the oldest remaining
+// entry to the latest, and reports the first broken link.
+func (s *AuditChainService) Verify(ctx context.Context, tenantID uuid.UUID) (*AuditChainReport, error) {
+	report, err := s.verify(ctx, tenantID)
+	if err != nil || report.Valid || report.Break.Seq != report.FromSeq {
+		return report, err
+	}
+
+	// The retention job may have pruned the start of the chain while it
+	// was being read; check again from the new starting point.
+	head, err := s.auditRepo.ChainHead(ctx, tenantID)
+	if err != nil || head.AnchorSeq < report.FromSeq {
+		return report, nil
+	}
+	return s.verify(ctx, tenantID)
+}
+
+func (s *AuditChainService) verify(ctx context.Context, tenantID uuid.UUID) (*AuditChainReport, error) {
+	head, err := s.auditRepo.ChainHead(ctx, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &AuditChainReport{TenantID: tenantID, Valid: true, VerifiedAt: time.Now()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints, err := s.checkpointRepo.ListAfter(ctx, tenantID, head.AnchorSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.walk(ctx, tenantID, head.AnchorSeq, head.AnchorHash, head.Seq, checkpoints)
+	if err != nil || !report.Valid {
+		return report, err
+	}
+
+	if head.AnchorSeq > 0 {
+		if err := s.checkAnchor(ctx, report, head); err != nil || !report.Valid {
+			return report, err
+		}
+	}
+	if report.ToSeq < head.Seq {
+		report.fail(report.ToSeq+1, nil, fmt.Sprintf("entries %d to %d are missing", report.ToSeq+1, head.Seq))
+	}
+	return report, nil
+}
+
+// checkAnchor explains why a pruned chain starts after entry AnchorSeq:
+// a prune must have been recorded there, with the same hash, into an
+// archive that holds the entry.
+func (s *AuditChainService) checkAnchor(ctx context.Context, report *AuditChainReport, head *models.AuditChainHead) error {
+	anchor, err := s.auditRepo.Anchor(ctx, head.TenantID, head.AnchorSeq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		report.fail(head.AnchorSeq+1, nil, fmt.Sprintf("the chain starts after entry %d, but no prune was recorded there", head.AnchorSeq))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report.Anchor = anchor
+	if anchor.Hash != head.AnchorHash {
+		report.fail(head.AnchorSeq+1, nil, fmt.Sprintf("the chain starts after entry %d, whose hash differs from the recorded prune", head.AnchorSeq))
+		return nil
+	}
+
+	archive, err := s.archiveRepo.GetByID(ctx, head.TenantID, anchor.ArchiveID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if archive == nil || archive.LastSeq < anchor.Seq || (archive.LastSeq == anchor.Seq && archive.LastHash != anchor.Hash) {
+		report.fail(head.AnchorSeq+1, nil, fmt.Sprintf("entries up to %d were pruned into archive %s, which does not hold them", anchor.Seq, anchor.ArchiveID))
+	}
+	return nil
+}
+
+// walk verifies a tenant's entries after afterSeq, whose hash is afterHash,
+// up to throughSeq. Each entry must follow the previous one without a gap,
+// link to its hash and hash to its own recorded hash, and match every
+// checkpoint signed at its position.
+func (s *AuditChainService) walk(ctx context.Context, tenantID uuid.UUID, afterSeq int64, afterHash string, throughSeq int64, checkpoints []*models.AuditCheckpoint) (*AuditChainReport, error) {
+	report := &AuditChainReport{
+		TenantID:   tenantID,
+		Valid:      true,
+		FromSeq:    afterSeq + 1,
+		ToSeq:      afterSeq,
+		VerifiedAt: time.Now(),
+	}
+
+	prevHash := afterHash
+	next := 0
+	err := s.auditRepo.StreamChain(ctx, tenantID, afterSeq, throughSeq, func(log *models.AuditLog) error {
+		switch {
+		case log.Seq != report.ToSeq+1:
+			report.fail(report.ToSeq+1, nil, fmt.Sprintf("entries %d to %d are missing", report.ToSeq+1, log.Seq-1))
+		case log.PrevHash != prevHash:
+			report.fail(log.Seq, &log.ID, "previous hash does not match the preceding entry")
+		case auditLogDigest(log) != log.Hash:
+			report.fail(log.Seq, &log.ID, "hash does not match the entry's contents")
+		}
+		for report.Valid && next < len(checkpoints) && checkpoints[next].Seq <= log.Seq {
+			s.checkCheckpoint(report, checkpoints[next], log)
+			next++
+		}
+		if !report.Valid {
+			return errStopWalk
+		}
+
+		report.Entries++
+		report.ToSeq = log.Seq
+		prevHash = log.Hash
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return nil, err
+	}
+	if !report.Valid {
+		return report, nil
+	}
+
+	// A checkpoint past the last entry proves entries were removed.
+	for ; next < len(checkpoints) && checkpoints[next].Seq <= throughSeq; next++ {
+		checkpoint := checkpoints[next]
+		if checkpoint.KeyID == s.keyID && s.validSignature(checkpoint) {
+			report.fail(report.ToSeq+1, nil, fmt.Sprintf("entries %d to %d are missing but were checkpointed", report.ToSeq+1, checkpoint.Seq))
+			break
+		}
+	}
+	return report, nil
+}
+
+func (s *AuditChainService) checkCheckpoint(report *AuditChainReport, checkpoint *models.AuditCheckpoint, log *models.AuditLog) {
+	switch {
+	case checkpoint.KeyID != s.keyID:
+		report.CheckpointsSkipped++
+	case !s.validSignature(checkpoint):
+		report.fail(checkpoint.Seq, nil, "checkpoint signature is invalid")
+	case checkpoint.Seq != log.Seq || checkpoint.Hash != log.Hash:
+		report.fail(checkpoint.Seq, &log.ID, "entry differs from the signed checkpoint")
+	default:
+		report.CheckpointsVerified++
+	}
+}
+
+// Checkpoint signs the current head of every tenant's audit chain that has
+// grown since its last checkpoint, after verifying the entries added since
+// then. It returns how many checkpoints were created. A broken chain is
+// not signed and is reported as an error.
+func (s *AuditChainService) Checkpoint(ctx context.Context) (int64, error) {
+	tenantIDs, err := s.tenantRepo.ListIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var created int64
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		ok, err := s.checkpointTenant(ctx, tenantID)
+		if ok {
+			created++
+		}
+		if err != nil {
+			s.logger.Error().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to checkpoint audit chain")
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenantID, err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+func (s *AuditChainService) checkpointTenant(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	head, err := s.auditRepo.ChainHead(ctx, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	afterSeq, afterHash := head.AnchorSeq, head.AnchorHash
+	latest, err := s.checkpointRepo.List(ctx, tenantID, 1)
+	if err != nil {
+		return false, err
+	}
+	if len(latest) > 0 && latest[0].Seq > afterSeq && latest[0].KeyID == s.keyID && s.validSignature(latest[0]) {
+		if latest[0].Seq >= head.Seq {
+			return false, nil
+		}
+		afterSeq, afterHash = latest[0].Seq, latest[0].Hash
+	}
+
+	report, err := s.walk(ctx, tenantID, afterSeq, afterHash, head.Seq, nil)
+	if err != nil {
+		return false, err
+	}
+	if report.Valid && report.ToSeq < head.Seq {
+		report.fail(report.ToSeq+1, nil, fmt.Sprintf("entries %d to %d are missing", report.ToSeq+1, head.Seq))
+	}
+	if !report.Valid {
+		return false, fmt.Errorf("audit chain broken at entry %d: %s", report.Break.Seq, report.Break.Reason)
+	}
+
+	checkpoint := &models.AuditCheckpoint{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Seq:       head.Seq,
+		Hash:      head.Hash,
+		KeyID:     s.keyID,
+		CreatedAt: time.Now(),
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, checkpointPayload(checkpoint)))
+	if err := s.checkpointRepo.Create(ctx, checkpoint); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListCheckpoints returns a tenant's latest checkpoints, newest first.
+func (s *AuditChainService) ListCheckpoints(ctx context.Context, tenantID uuid.UUID, limit int) (*AuditCheckpointList, error) {
+	checkpoints, err := s.checkpointRepo.List(ctx, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoints == nil {
+		checkpoints = []*models.AuditCheckpoint{}
+	}
+	return &AuditCheckpointList{
+		KeyID:       s.keyID,
+		PublicKey:   base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+		Checkpoints: checkpoints,
+	}, nil
+}
+
+func (s *AuditChainService) validSignature(checkpoint *models.AuditCheckpoint) bool {
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), checkpointPayload(checkpoint), signature)
+}
+
+// checkpointPayload is the message a checkpoint signs.
+func checkpointPayload(checkpoint *models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint\n%s\n%d\n%s", checkpoint.TenantID, checkpoint.Seq, checkpoint.Hash))
+}
+
+// auditLogDigest recomputes the hash of an audit log entry exactly as the
+// audit_log_digest database function does: each value is written as "~"
+// when NULL or prefixed with its length in bytes, and the result is hashed
+// with SHA-256. JSON snapshots must be in the database's text form.
+func auditLogDigest(log *models.AuditLog) string {
+	var b strings.Builder
+	field := func(value *string) {
+		if value == nil {
+			b.WriteString("~")
+			return
+		}
+		b.WriteString(strconv.Itoa(len(*value)))
+		b.WriteString(":")
+		b.WriteString(*value)
+	}
+	text := func(value string) { field(&value) }
+	id := func(value *uuid.UUID) {
+		if value == nil {
+			field(nil)
+			return
+		}
+		text(value.String())
+	}
+
+	text(log.PrevHash)
+	text(strconv.FormatInt(log.Seq, 10))
+	text(log.ID.String())
+	text(log.TenantID.String())
+	id(log.UserID)
+	text(log.Action)
+	text(log.Resource)
+	id(log.ResourceID)
+	field(log.OldValue)
+	field(log.NewValue)
+	text(log.IPAddress)
+	text(log.UserAgent)
+	text(log.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z"))
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
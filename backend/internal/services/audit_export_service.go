@@ -0,0 +1,469 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// Audit export formats. NDJSON is the JSONL format compressed with gzip.
+const (
+	AuditExportCSV    = "csv"
+	AuditExportJSONL  = "jsonl"
+	AuditExportNDJSON = "ndjson.gz"
+)
+
+const (
+	AuditExportPending   = "pending"
+	AuditExportRunning   = "running"
+	AuditExportCompleted = "completed"
+	AuditExportFailed    = "failed"
+)
+
+var (
+	ErrInvalidAuditExportFormat = errors.New("invalid audit export format")
+	ErrAuditExportNotFound      = errors.New("audit export not found")
+	ErrAuditExportNotReady      = errors.New("audit export is not ready")
+	ErrAuditExportUnavailable   = errors.New("audit export file is unavailable")
+)
+
+// AuditExportContentType returns the MIME type and file extension of an
+// export format, or ErrInvalidAuditExportFormat.
+func AuditExportContentType(format string) (string, string, error) {
+	switch format {
+	case AuditExportCSV:
+		return "text/csv", "csv", nil
+	case AuditExportJSONL:
+		return "application/x-ndjson", "jsonl", nil
+	case AuditExportNDJSON:
+		return "application/gzip", "ndjson.gz", nil
+	default:
+		return "", "", ErrInvalidAuditExportFormat
+	}
+}
+
+// auditExportWriter encodes audit log entries in one export format. Nothing
+// is written until the first entry or Close, so a query that fails up front
+// leaves the destination untouched.
+type auditExportWriter interface {
+	Write(entry *AuditLogEntry) error
+	Close() error
+}
+
+func newAuditExportWriter(format string, w io.Writer) (auditExportWriter, error) {
+	switch format {
+	case AuditExportCSV:
+		return &auditCSVWriter{writer: csv.NewWriter(w)}, nil
+	case AuditExportJSONL:
+		return &auditJSONLWriter{encoder: json.NewEncoder(w)}, nil
+	case AuditExportNDJSON:
+		gz := gzip.NewWriter(w)
+		return &auditJSONLWriter{encoder: json.NewEncoder(gz), closer: gz}, nil
+	default:
+		return nil, ErrInvalidAuditExportFormat
+	}
+}
+
+var auditCSVHeader = []string{
+	"ID", "Seq", "User ID", "Action", "Resource", "Resource ID", "IP Address", "User Agent", "Created At",
+	"Old Value", "New Value", "Changed Fields", "Changes",
+}
+
+type auditCSVWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func (c *auditCSVWriter) Write(entry *AuditLogEntry) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	userID := ""
+	if entry.UserID != nil {
+		userID = entry.UserID.String()
+	}
+	resourceID := ""
+	if entry.ResourceID != nil {
+		resourceID = entry.ResourceID.String()
+	}
+	oldValue := ""
+	if entry.OldValue != nil {
+		oldValue = *entry.OldValue
+	}
+	newValue := ""
+	if entry.NewValue != nil {
+		newValue = *entry.NewValue
+	}
+	fields := make([]string, len(entry.Changes))
+	for i, change := range entry.Changes {
+		fields[i] = change.Field
+	}
+	changes := ""
+	if len(entry.Changes) > 0 {
+		data, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return err
+		}
+		changes = string(data)
+	}
+
+	return c.writer.Write([]string{
+		entry.ID.String(),
+		strconv.FormatInt(entry.Seq, 10),
+		userID,
+		entry.Action,
+		entry.Resource,
+		resourceID,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.CreatedAt.Format(time.RFC3339),
+		oldValue,
+		newValue,
+		strings.Join(fields, ", "),
+		changes,
+	})
+}
+
+func (c *auditCSVWriter) header() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	return c.writer.Write(auditCSVHeader)
+}
+
+func (c *auditCSVWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// auditExportEntry is one line of a JSONL export: the archive form of an
+// entry with its field-level diff.
+type auditExportEntry struct {
+	*auditArchiveEntry
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+type auditJSONLWriter struct {
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+func (j *auditJSONLWriter) Write(entry *AuditLogEntry) error {
+	return j.encoder.Encode(auditExportEntry{
+		auditArchiveEntry: newAuditArchiveEntry(entry.AuditLog),
+		Changes:           entry.Changes,
+	})
+}
+
+func (j *auditJSONLWriter) Close() error {
+	if j.closer != nil {
+		return j.closer.Close()
+	}
+	return nil
+}
+
+// AuditExportService streams audit log exports and runs the background
+// exports requested for ranges too large to download in one request.
+type AuditExportService struct {
+	auditRepo  *repository.AuditLogRepository
+	exportRepo *repository.AuditExportRepository
+	audit      *AuditRecorder
+	config     config.AuditConfig
+	logger     zerolog.Logger
+}
+
+func NewAuditExportService(
+	auditRepo *repository.AuditLogRepository,
+	exportRepo *repository.AuditExportRepository,
+	audit *AuditRecorder,
+	cfg config.AuditConfig,
+	logger zerolog.Logger,
+) *AuditExportService {
+	return &AuditExportService{
+		auditRepo:  auditRepo,
+		exportRepo: exportRepo,
+		audit:      audit,
+		config:     cfg,
+		logger:     logger,
+	}
+}
+
+// Export writes the entries matching filter to w in format and returns how
+// many were written. When it fails before the first entry nothing has been
+// written to w. Exports are recorded in the audit log.
+func (s *AuditExportService) Export(ctx context.Context, actor AuditContext, filter *models.AuditLogFilter, format string, w io.Writer) (int64, error) {
+	count, err := s.write(ctx, filter, format, w)
+	if err != nil {
+		return count, err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:   "audit_logs_exported",
+		Resource: "audit_log",
+		After:    map[string]interface{}{"format": format, "filter": filter, "row_count": count},
+	})
+	return count, nil
+}
+
+func (s *AuditExportService) write(ctx context.Context, filter *models.AuditLogFilter, format string, w io.Writer) (int64, error) {
+	writer, err := newAuditExportWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = s.auditRepo.StreamFiltered(ctx, filter, func(log *models.AuditLog) error {
+		count++
+		return writer.Write(newAuditLogEntry(log))
+	})
+	if err != nil {
+		return count, auditQueryError(err)
+	}
+	return count, writer.Close()
+}
+
+// Create queues a background export of the entries matching filter. The
+// export is picked up by the audit exports job.
+func (s *AuditExportService) Create(ctx context.Context, actor AuditContext, filter *models.AuditLogFilter, format string) (*models.AuditExport, error) {
+	if _, _, err := AuditExportContentType(format); err != nil {
+		return nil, err
+	}
+
+	export := &models.AuditExport{
+		ID:          uuid.New(),
+		TenantID:    actor.TenantID,
+		RequestedBy: actor.actorID(),
+		Format:      format,
+		Filter:      *filter,
+		Status:      AuditExportPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "audit_export_requested",
+		Resource:   "audit_export",
+		ResourceID: &export.ID,
+		After:      export,
+	})
+	return export, nil
+}
+
+// List returns a tenant's latest exports, newest first.
+func (s *AuditExportService) List(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.AuditExport, error) {
+	exports, err := s.exportRepo.List(ctx, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if exports == nil {
+		exports = []*models.AuditExport{}
+	}
+	return exports, nil
+}
+
+func (s *AuditExportService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditExport, error) {
+	export, err := s.exportRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, ErrAuditExportNotFound
+	}
+	return export, nil
+}
+
+// Open returns a completed export and its file. The caller must close the
+// reader. Downloads are recorded in the audit log.
+func (s *AuditExportService) Open(ctx context.Context, actor AuditContext, id uuid.UUID) (*models.AuditExport, io.ReadCloser, error) {
+	export, err := s.Get(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != AuditExportCompleted {
+		return nil, nil, ErrAuditExportNotReady
+	}
+
+	file, err := os.Open(filepath.Join(s.config.ExportDir, filepath.Clean(export.FileName)))
+	if err != nil {
+		s.logger.Error().Err(err).Str("export_id", id.String()).Msg("Failed to open audit export")
+		return nil, nil, ErrAuditExportUnavailable
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "audit_export_downloaded",
+		Resource:   "audit_export",
+		ResourceID: &export.ID,
+	})
+	return export, file, nil
+}
+
+// Process is the audit exports job. It runs pending exports one after the
+// other, oldest first, and deletes expired ones. Exports left running by an
+// earlier run that was interrupted are failed first; the scheduler never
+// runs the job twice at once, so none of them can still be in progress.
+// Each export may take up to the configured export timeout, and no new
+// export is started once less than that is left of ctx.
+func (s *AuditExportService) Process(ctx context.Context) (int64, error) {
+	if _, err := s.exportRepo.FailRunning(ctx, "export was interrupted"); err != nil {
+		return 0, err
+	}
+	s.deleteExpired(ctx)
+
+	var processed int64
+	for {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < s.config.ExportTimeout {
+			return processed, nil
+		}
+
+		export, err := s.exportRepo.ClaimNext(ctx, time.Now())
+		if errors.Is(err, pgx.ErrNoRows) {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, err
+		}
+
+		exportCtx, cancel := context.WithTimeout(ctx, s.config.ExportTimeout)
+		s.run(exportCtx, export)
+		cancel()
+		processed++
+	}
+}
+
+// jobTimeout bounds a run of the audit exports job: exports are started
+// for up to one export timeout and the last of them may take another.
+func (s *AuditExportService) jobTimeout() time.Duration {
+	return 2 * s.config.ExportTimeout
+}
+
+// run writes an export's file and records the outcome.
+func (s *AuditExportService) run(ctx context.Context, export *models.AuditExport) {
+	err := s.writeFile(ctx, export)
+
+	finishedAt := time.Now()
+	export.FinishedAt = &finishedAt
+	if err != nil {
+		s.logger.Error().Err(err).Str("export_id", export.ID.String()).Msg("Audit export failed")
+		message := auditExportError(err)
+		export.Status = AuditExportFailed
+		export.Error = &message
+	} else {
+		expiresAt := finishedAt.Add(s.config.ExportTTL)
+		export.Status = AuditExportCompleted
+		export.ExpiresAt = &expiresAt
+	}
+
+	if err := s.exportRepo.Finish(context.WithoutCancel(ctx), export); err != nil {
+		s.logger.Error().Err(err).Str("export_id", export.ID.String()).Msg("Failed to record audit export")
+		if export.FileName != "" {
+			os.Remove(filepath.Join(s.config.ExportDir, export.FileName))
+		}
+	}
+}
+
+// auditExportError is the category of a failed export shown to the tenant.
+// It never includes the underlying error, which may name server paths or
+// database details; that is only logged.
+func auditExportError(err error) string {
+	var pathErr *os.PathError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "export timed out"
+	case errors.Is(err, context.Canceled):
+		return "export was interrupted"
+	case errors.Is(err, ErrInvalidAuditExportFormat):
+		return "unsupported export format"
+	case errors.Is(auditQueryError(err), ErrInvalidAuditJSONPath):
+		return "invalid JSON path filter"
+	case errors.As(err, &pgErr):
+		return "query failed"
+	case errors.As(err, &pathErr):
+		return "write failed"
+	default:
+		return "export failed"
+	}
+}
+
+// writeFile writes an export to a temporary file that is renamed into place
+// once complete, and sets its file name, row count and size.
+func (s *AuditExportService) writeFile(ctx context.Context, export *models.AuditExport) error {
+	_, extension, err := AuditExportContentType(export.Format)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.config.ExportDir, export.TenantID.String())
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := s.write(ctx, &export.Filter, export.Format, tmp)
+	if err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(export.TenantID.String(), fmt.Sprintf("%s.%s", export.ID, extension))
+	if err := os.Rename(tmp.Name(), filepath.Join(s.config.ExportDir, fileName)); err != nil {
+		return err
+	}
+
+	export.FileName = fileName
+	export.RowCount = count
+	export.SizeBytes = info.Size()
+	return nil
+}
+
+// deleteExpired removes expired exports and their files. Failures are
+// logged rather than failing the job.
+func (s *AuditExportService) deleteExpired(ctx context.Context) {
+	fileNames, err := s.exportRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to delete expired audit exports")
+		return
+	}
+	for _, fileName := range fileNames {
+		if err := os.Remove(filepath.Join(s.config.ExportDir, filepath.Clean(fileName))); err != nil && !os.IsNotExist(err) {
+			s.logger.Error().Err(err).Str("file", fileName).Msg("Failed to delete audit export file")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestAuditExportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), "export timed out"},
+		{"canceled", context.Canceled, "export was interrupted"},
+		{"format", ErrInvalidAuditExportFormat, "unsupported export format"},
+		{"json path", &pgconn.PgError{Code: "42601", Message: "syntax error at or near \"$\" of jsonpath input"}, "invalid JSON path filter"},
+		{"database", &pgconn.PgError{Code: "57P01", Message: "terminating connection due to administrator command"}, "query failed"},
+		{"file", &os.PathError{Op: "mkdir", Path: "/var/lib/admin-panel/exports/tenant", Err: os.ErrPermission}, "write failed"},
+		{"other", errors.New("short write"), "export failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditExportError(tt.err); got != tt.want {
+				t.Errorf("auditExportError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
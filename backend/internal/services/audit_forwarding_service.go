@@ -0,0 +1,471 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/rs/zerolog"
+)
+
+// auditForwardBatch is how many queued entries are read for a sink at a
+// time.
+const auditForwardBatch = 100
+
+// Syslog defaults: the "log audit" facility and informational severity.
+const (
+	syslogDefaultFacility = 13
+	syslogSeverity        = 6
+	syslogDefaultAppName  = "admin-panel"
+)
+
+// errBlockedSinkAddress rejects connections to addresses sinks may not
+// reach.
+var errBlockedSinkAddress = errors.New("sink address is not allowed")
+
+// blockedSinkIP reports whether ip is an address a sink may not connect
+// to: loopback, private, link-local (which includes cloud metadata
+// endpoints such as 169.254.169.254), multicast, broadcast or unspecified.
+// Sinks are configured by tenants and must not reach the server's own
+// network.
+func blockedSinkIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		ip.IsUnspecified() || ip.Equal(net.IPv4bcast)
+}
+
+// sinkDialControl is the Control hook of every dialer sinks connect
+// through. It runs after name resolution, on the address actually being
+// connected to, so host names that resolve or rebind to a blocked address
+// are refused as well.
+func sinkDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blockedSinkIP(ip) {
+		return errBlockedSinkAddress
+	}
+	return nil
+}
+
+// AuditForwardingService delivers queued audit log entries to the sinks
+// they were queued for. Each sink receives its entries in the order they
+// were written: a failed delivery holds back the entries behind it until
+// it is retried, with exponential backoff, or gives up after the configured
+// number of attempts. Entries may be delivered more than once if the
+// server stops mid-run; receivers can deduplicate by entry ID.
+type AuditForwardingService struct {
+	sinkRepo   *repository.AuditSinkRepository
+	outboxRepo *repository.AuditOutboxRepository
+	config     config.AuditConfig
+	client     *http.Client
+	hostname   string
+	logger     zerolog.Logger
+}
+
+func NewAuditForwardingService(
+	sinkRepo *repository.AuditSinkRepository,
+	outboxRepo *repository.AuditOutboxRepository,
+	cfg config.AuditConfig,
+	logger zerolog.Logger,
+) *AuditForwardingService {
+	hostname, _ := os.Hostname()
+	return &AuditForwardingService{
+		sinkRepo:   sinkRepo,
+		outboxRepo: outboxRepo,
+		config:     cfg,
+		client: &http.Client{
+			Timeout: cfg.ForwardTimeout,
+			// No proxy is used, so the dial check sees the webhook's own
+			// address.
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout: cfg.ForwardTimeout,
+					Control: sinkDialControl,
+				}).DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: cfg.ForwardTimeout,
+			},
+			// A redirect could downgrade the delivery to plain HTTP.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		hostname: hostname,
+		logger:   logger,
+	}
+}
+
+// Forward is the audit forwarding job. It delivers the due entries of
+// every enabled sink and returns how many were delivered. Delivery
+// failures are recorded on the entry and sink rather than failing the job.
+func (s *AuditForwardingService) Forward(ctx context.Context) (int64, error) {
+	sinks, err := s.sinkRepo.ListDue(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var delivered int64
+	var errs []error
+	for _, sink := range sinks {
+		count, err := s.forwardSink(ctx, sink)
+		delivered += count
+		if err != nil {
+			s.logger.Error().Err(err).Str("sink_id", sink.ID.String()).Msg("Failed to forward audit logs")
+			errs = append(errs, err)
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+func (s *AuditForwardingService) forwardSink(ctx context.Context, sink *models.AuditSink) (int64, error) {
+	sender := s.newSender(sink)
+	defer sender.Close()
+
+	now := time.Now()
+	var delivered int64
+	for ctx.Err() == nil {
+		entries, err := s.outboxRepo.ListPending(ctx, sink.ID, auditForwardBatch)
+		if err != nil {
+			return delivered, err
+		}
+
+		var sent []int64
+		var failed *models.AuditOutboxEntry
+		var sendErr error
+		blocked := false
+		for _, entry := range entries {
+			if entry.NextAttemptAt.After(now) {
+				blocked = true
+				break
+			}
+			if sendErr = sender.Send(ctx, entry); sendErr != nil {
+				failed = entry
+				break
+			}
+			sent = append(sent, entry.ID)
+		}
+
+		if len(sent) > 0 {
+			if err := s.outboxRepo.Delete(ctx, sent); err != nil {
+				return delivered, err
+			}
+			delivered += int64(len(sent))
+			if err := s.sinkRepo.RecordDelivery(ctx, sink.ID, time.Now()); err != nil {
+				return delivered, err
+			}
+		}
+		if failed != nil {
+			return delivered, s.recordFailure(ctx, sink, failed, sendErr)
+		}
+		if blocked || len(entries) < auditForwardBatch {
+			return delivered, nil
+		}
+	}
+	return delivered, ctx.Err()
+}
+
+// recordFailure schedules the next attempt at an entry, or gives up on it
+// once it has used up its attempts.
+func (s *AuditForwardingService) recordFailure(ctx context.Context, sink *models.AuditSink, entry *models.AuditOutboxEntry, sendErr error) error {
+	now := time.Now()
+	message := auditDeliveryError(sendErr)
+	entry.Attempts++
+	entry.LastError = &message
+	if entry.Attempts >= s.config.ForwardMaxAttempts {
+		entry.Status = "failed"
+	} else {
+		entry.NextAttemptAt = now.Add(s.backoff(entry.Attempts))
+	}
+
+	s.logger.Warn().Err(sendErr).
+		Str("sink_id", sink.ID.String()).
+		Str("audit_log_id", entry.AuditLogID.String()).
+		Int("attempts", entry.Attempts).
+		Msg("Audit log delivery failed")
+
+	if err := s.outboxRepo.RecordAttempt(ctx, entry); err != nil {
+		return err
+	}
+	return s.sinkRepo.RecordError(ctx, sink.ID, message, now)
+}
+
+// auditDeliveryError is the category of a failed delivery shown on the
+// entry and sink. Tenants see it, so it never includes the underlying
+// error, which may describe the server's network; that is only logged.
+func auditDeliveryError(err error) string {
+	var statusErr webhookStatusError
+	var netErr net.Error
+	var opErr *net.OpError
+	var pathErr *os.PathError
+	switch {
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("HTTP %d", int(statusErr))
+	case errors.Is(err, errBlockedSinkAddress):
+		return "address not allowed"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &opErr), errors.As(err, &netErr):
+		return "connection failed"
+	case errors.As(err, &pathErr):
+		return "write failed"
+	case errors.Is(err, errUnsupportedSink):
+		return "unsupported sink type"
+	default:
+		return "delivery failed"
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed ones: the base delay, doubled per further attempt, capped at
+// the maximum.
+func (s *AuditForwardingService) backoff(attempts int) time.Duration {
+	delay := s.config.ForwardRetryBase
+	for i := 1; i < attempts && delay < s.config.ForwardRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, s.config.ForwardRetryMax)
+}
+
+// auditSinkSender delivers entries to one sink for the duration of a run.
+type auditSinkSender interface {
+	Send(ctx context.Context, entry *models.AuditOutboxEntry) error
+	Close() error
+}
+
+func (s *AuditForwardingService) newSender(sink *models.AuditSink) auditSinkSender {
+	switch sink.Type {
+	case AuditSinkSyslog:
+		facility := syslogDefaultFacility
+		if sink.Config.Facility != nil {
+			facility = *sink.Config.Facility
+		}
+		appName := sink.Config.AppName
+		if appName == "" {
+			appName = syslogDefaultAppName
+		}
+		return &syslogSender{
+			network:  sink.Config.Network,
+			address:  sink.Config.Address,
+			priority: facility*8 + syslogSeverity,
+			hostname: syslogHeaderField(s.hostname, 255),
+			appName:  appName,
+			timeout:  s.config.ForwardTimeout,
+		}
+	case AuditSinkWebhook:
+		secret := ""
+		if sink.Secret != nil {
+			secret = *sink.Secret
+		}
+		return &webhookSender{client: s.client, url: sink.Config.URL, secret: secret}
+	case AuditSinkFile:
+		return &fileSender{
+			path: filepath.Join(s.config.ForwardFileDir, sink.TenantID.String(), filepath.Base(sink.Config.FileName)),
+		}
+	default:
+		return unknownSender(sink.Type)
+	}
+}
+
+// syslogSender sends RFC 5424 messages, one per entry, with the entry as
+// JSON in the message body. Over TCP messages are framed by octet
+// counting (RFC 6587); over UDP each message is a datagram.
+type syslogSender struct {
+	network  string
+	address  string
+	priority int
+	hostname string
+	appName  string
+	timeout  time.Duration
+	conn     net.Conn
+}
+
+func (s *syslogSender) Send(ctx context.Context, entry *models.AuditOutboxEntry) error {
+	var event struct {
+		Action    string    `json:"action"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := json.Unmarshal([]byte(entry.Payload), &event); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		s.priority,
+		event.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		s.appName,
+		syslogHeaderField(event.Action, 32),
+		entry.Payload,
+	)
+	if s.network == "tcp" {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.timeout, Control: sinkDialControl}
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := io.WriteString(s.conn, message); err != nil {
+		// The stream may hold a partial message; start over on a new one.
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *syslogSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// syslogField keeps the characters allowed in RFC 5424 header fields:
+// printable US-ASCII without spaces.
+func syslogField(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// syslogHeaderField returns value as a header field of at most max
+// characters, or the nil value "-" when nothing is left of it.
+func syslogHeaderField(value string, max int) string {
+	value = syslogField(value)
+	if len(value) > max {
+		value = value[:max]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// webhookSender posts each entry as JSON. The body is signed with
+// HMAC-SHA256 over the timestamp, a dot and the body, so receivers can
+// check both origin and freshness:
+//
+//	X-Audit-Timestamp: <unix seconds>
+//	X-Audit-Signature: sha256=<hex digest>
+type webhookSender struct {
+	client *http.Client
+	url    string
+	secret string
+}
+
+func (w *webhookSender) Send(ctx context.Context, entry *models.AuditOutboxEntry) error {
+	body := []byte(entry.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(w.secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "admin-panel-audit-forwarder")
+	req.Header.Set("X-Audit-Event-ID", entry.AuditLogID.String())
+	req.Header.Set("X-Audit-Timestamp", timestamp)
+	req.Header.Set("X-Audit-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError(resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookStatusError is a webhook response with a status other than 2xx.
+type webhookStatusError int
+
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with HTTP %d", int(e))
+}
+
+func (w *webhookSender) Close() error {
+	return nil
+}
+
+// fileSender appends entries as JSON lines to a file in the tenant's
+// directory.
+type fileSender struct {
+	path string
+	file *os.File
+}
+
+func (f *fileSender) Send(ctx context.Context, entry *models.AuditOutboxEntry) error {
+	if f.file == nil {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0o750); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+		if err != nil {
+			return err
+		}
+		f.file = file
+	}
+	_, err := f.file.WriteString(entry.Payload + "\n")
+	return err
+}
+
+func (f *fileSender) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// errUnsupportedSink fails deliveries to sinks of an unknown type.
+var errUnsupportedSink = errors.New("unsupported sink type")
+
+// unknownSender fails every delivery to a sink of an unsupported type.
+type unknownSender string
+
+func (u unknownSender) Send(context.Context, *models.AuditOutboxEntry) error {
+	return fmt.Errorf("%w %q", errUnsupportedSink, string(u))
+}
+
+func (u unknownSender) Close() error {
+	return nil
+}
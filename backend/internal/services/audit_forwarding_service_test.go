@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestSinkDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:514", false},
+		{"[::1]:514", false},
+		{"10.1.2.3:443", false},
+		{"172.16.0.1:443", false},
+		{"192.168.1.1:443", false},
+		{"[fd00::1]:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:443", false},
+		{"224.0.0.1:514", false},
+		{"[ff02::1]:514", false},
+		{"0.0.0.0:443", false},
+		{"[::]:443", false},
+		{"255.255.255.255:514", false},
+		{"[::ffff:127.0.0.1]:443", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := sinkDialControl("tcp", tt.address, nil)
+			if tt.allowed && err != nil {
+				t.Errorf("sinkDialControl(%s) = %v, want nil", tt.address, err)
+			}
+			if !tt.allowed && !errors.Is(err, errBlockedSinkAddress) {
+				t.Errorf("sinkDialControl(%s) = %v, want errBlockedSinkAddress", tt.address, err)
+			}
+		})
+	}
+}
+
+func TestAuditDeliveryError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"webhook status", webhookStatusError(503), "HTTP 503"},
+		{"blocked address", &net.OpError{Op: "dial", Net: "tcp", Err: errBlockedSinkAddress}, "address not allowed"},
+		{"deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), "timeout"},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "siem.internal", IsTimeout: true}}, "timeout"},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 514}, Err: errors.New("connection refused")}, "connection failed"},
+		{"file", &os.PathError{Op: "open", Path: "/var/lib/audit/out.jsonl", Err: os.ErrPermission}, "write failed"},
+		{"unknown sink", unknownSender("ftp").Send(context.Background(), nil), "unsupported sink type"},
+		{"other", errors.New("invalid character"), "delivery failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditDeliveryError(tt.err); got != tt.want {
+				t.Errorf("auditDeliveryError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
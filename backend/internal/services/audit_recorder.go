@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// redactedValue replaces sensitive values in audit snapshots.
+const redactedValue = "[REDACTED]"
+
+// sensitiveAuditFields are substrings of snapshot field names whose values
+// are never written to the audit log.
+var sensitiveAuditFields = []string{"password", "secret", "token", "api_key", "private_key", "recovery_code"}
+
+// ignoredDiffFields change on every write and are left out of diffs.
+var ignoredDiffFields = map[string]bool{"updated_at": true}
+
+// AuditChange describes one audited event. Before and After are snapshots
+// of the affected record; Before is nil for creations and After for
+// deletions, and both are nil for events that change no record.
+type AuditChange struct {
+	Action     string
+	Resource   string
+	ResourceID *uuid.UUID
+	Before     interface{}
+	After      interface{}
+}
+
+// AuditRecorder writes audit log entries with redacted before and after
+// snapshots, from which the audit API derives field-level diffs.
+type AuditRecorder struct {
+	auditRepo *repository.AuditLogRepository
+	logger    zerolog.Logger
+}
+
+func NewAuditRecorder(auditRepo *repository.AuditLogRepository, logger zerolog.Logger) *AuditRecorder {
+	return &AuditRecorder{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// Record writes an audit log entry for change made by actor. Failures are
+// logged rather than returned so auditing never undoes a completed change.
+func (r *AuditRecorder) Record(ctx context.Context, actor AuditContext, change AuditChange) {
+	err := r.auditRepo.Log(ctx, &models.AuditLog{
+		ID:         uuid.New(),
+		TenantID:   actor.TenantID,
+		UserID:     actor.actorID(),
+		Action:     change.Action,
+		Resource:   change.Resource,
+		ResourceID: change.ResourceID,
+		OldValue:   auditSnapshot(change.Before),
+		NewValue:   auditSnapshot(change.After),
+		IPAddress:  actor.IPAddress,
+		UserAgent:  actor.UserAgent,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("action", change.Action).Str("resource", change.Resource).Msg("Failed to write audit log")
+	}
+}
+
+// auditSnapshot serializes v for the JSONB old_value/new_value columns of an
+// audit log entry, redacting sensitive fields. It returns nil when v is nil
+// or cannot be encoded.
+func auditSnapshot(v interface{}) *string {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded == nil {
+		return nil
+	}
+	data, err = json.Marshal(redactAuditValue(decoded))
+	if err != nil {
+		return nil
+	}
+	value := string(data)
+	return &value
+}
+
+func redactAuditValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if field != nil && isSensitiveAuditField(key) {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = redactAuditValue(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactAuditValue(item)
+		}
+	}
+	return v
+}
+
+func isSensitiveAuditField(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveAuditFields {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldChange is one changed field of an audited record. Nested fields are
+// named with dots. For lists, Added and Removed hold the items that differ.
+type FieldChange struct {
+	Field   string        `json:"field"`
+	Old     interface{}   `json:"old"`
+	New     interface{}   `json:"new"`
+	Added   []interface{} `json:"added,omitempty"`
+	Removed []interface{} `json:"removed,omitempty"`
+}
+
+// AuditDiff compares the old and new snapshots of an audit log entry
+// field by field. It returns nil when the entry has no snapshots.
+func AuditDiff(log *models.AuditLog) []FieldChange {
+	if log.OldValue == nil && log.NewValue == nil {
+		return nil
+	}
+
+	before := flattenSnapshot(log.OldValue)
+	after := flattenSnapshot(log.NewValue)
+
+	fields := make([]string, 0, len(before)+len(after))
+	for field := range before {
+		fields = append(fields, field)
+	}
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if ignoredDiffFields[field[strings.LastIndex(field, ".")+1:]] {
+			continue
+		}
+		oldValue, newValue := before[field], after[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		change := FieldChange{Field: field, Old: oldValue, New: newValue}
+		oldList, oldIsList := oldValue.([]interface{})
+		newList, newIsList := newValue.([]interface{})
+		if (oldIsList || oldValue == nil) && (newIsList || newValue == nil) {
+			change.Added = listDifference(newList, oldList)
+			change.Removed = listDifference(oldList, newList)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// flattenSnapshot decodes a snapshot into a map of dotted field paths to
+// values. A snapshot that is not an object is reported as a single field
+// named "value".
+func flattenSnapshot(snapshot *string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if snapshot == nil {
+		return fields
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(*snapshot), &decoded); err != nil {
+		return fields
+	}
+	if object, ok := decoded.(map[string]interface{}); ok {
+		flattenInto(fields, "", object)
+	} else if decoded != nil {
+		fields["value"] = decoded
+	}
+	return fields
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, object map[string]interface{}) {
+	for key, value := range object {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(fields, path, nested)
+			continue
+		}
+		fields[path] = value
+	}
+}
+
+// listDifference returns the items of a that are not in b.
+func listDifference(a, b []interface{}) []interface{} {
+	var diff []interface{}
+	for _, item := range a {
+		found := false
+		for _, other := range b {
+			if reflect.DeepEqual(item, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, item)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrAuditArchiveNotFound    = errors.New("audit archive not found")
+	ErrAuditArchiveUnavailable = errors.New("audit archive file is unavailable")
+)
+
+// auditDeleteBatch is how many archived audit log rows one prune removes,
+// keeping each transaction short.
+const auditDeleteBatch = 5000
+
+// auditChainLink identifies an archived entry's position in its chain.
+type auditChainLink struct {
+	seq  int64
+	hash string
+}
+
+// AuditRetentionPolicy is a tenant's effective audit log retention.
+type AuditRetentionPolicy struct {
+	RetentionDays        int `json:"retention_days"`
+	DefaultRetentionDays int `json:"default_retention_days"`
+}
+
+// auditArchiveEntry is one line of an archive file. Snapshots are embedded
+// as JSON rather than as strings holding JSON.
+type auditArchiveEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	TenantID   uuid.UUID       `json:"tenant_id"`
+	UserID     *uuid.UUID      `json:"user_id,omitempty"`
+	Action     string          `json:"action"`
+	Resource   string          `json:"resource"`
+	ResourceID *uuid.UUID      `json:"resource_id,omitempty"`
+	OldValue   json.RawMessage `json:"old_value,omitempty"`
+	NewValue   json.RawMessage `json:"new_value,omitempty"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Seq        int64           `json:"seq"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditRetentionService archives and deletes audit log entries older than
+// each tenant's retention period, and serves the archives.
+type AuditRetentionService struct {
+	auditRepo       *repository.AuditLogRepository
+	audit           *AuditRecorder
+	archiveRepo     *repository.AuditArchiveRepository
+	tenantRepo      *repository.TenantRepository
+	settingsService *SettingsService
+	config          config.AuditConfig
+	logger          zerolog.Logger
+}
+
+func NewAuditRetentionService(
+	auditRepo *repository.AuditLogRepository,
+	audit *AuditRecorder,
+	archiveRepo *repository.AuditArchiveRepository,
+	tenantRepo *repository.TenantRepository,
+	settingsService *SettingsService,
+	cfg config.AuditConfig,
+	logger zerolog.Logger,
+) *AuditRetentionService {
+	return &AuditRetentionService{
+		auditRepo:       auditRepo,
+		audit:           audit,
+		archiveRepo:     archiveRepo,
+		tenantRepo:      tenantRepo,
+		settingsService: settingsService,
+		config:          cfg,
+		logger:          logger,
+	}
+}
+
+// Policy returns a tenant's retention period. Zero days keeps audit logs
+// forever.
+func (s *AuditRetentionService) Policy(ctx context.Context, tenantID uuid.UUID) *AuditRetentionPolicy {
+	days := s.settingsService.GetInt(ctx, tenantID, SettingAuditRetentionDays, s.config.RetentionDays)
+	if days < 0 {
+		days = 0
+	}
+	return &AuditRetentionPolicy{
+		RetentionDays:        days,
+		DefaultRetentionDays: s.config.RetentionDays,
+	}
+}
+
+// Apply archives and deletes expired audit logs of every tenant and returns
+// how many entries were archived. A failure for one tenant does not stop
+// the others.
+func (s *AuditRetentionService) Apply(ctx context.Context) (int64, error) {
+	tenantIDs, err := s.tenantRepo.ListIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	var errs []error
+	for _, tenantID := range tenantIDs {
+		archived, err := s.applyTenant(ctx, tenantID)
+		total += archived
+		if err != nil {
+			s.logger.Error().Err(err).Str("tenant_id", tenantID.String()).Msg("Failed to apply audit retention")
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenantID, err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+func (s *AuditRetentionService) applyTenant(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	days := s.Policy(ctx, tenantID).RetentionDays
+	if days == 0 {
+		return 0, nil
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	var total int64
+	for {
+		archive, err := s.archive(ctx, tenantID, before)
+		if err != nil || archive == nil {
+			return total, err
+		}
+		total += archive.RowCount
+		if archive.RowCount < int64(s.config.ArchiveBatchSize) {
+			return total, nil
+		}
+	}
+}
+
+// archive writes up to one batch of a tenant's oldest entries created
+// before the given time to a new archive file, records it and prunes the
+// archived entries from the chain. It returns nil when there is nothing to
+// archive. Entries are only pruned once their archive is on disk and
+// recorded; if pruning fails they are archived again by the next run.
+func (s *AuditRetentionService) archive(ctx context.Context, tenantID uuid.UUID, before time.Time) (*models.AuditArchive, error) {
+	dir := filepath.Join(s.config.ArchiveDir, tenantID.String())
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".archive-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(tmp, hash))
+	encoder := json.NewEncoder(gz)
+
+	archive := &models.AuditArchive{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+	}
+	var links []auditChainLink
+	err = s.auditRepo.StreamBefore(ctx, tenantID, before, s.config.ArchiveBatchSize, func(log *models.AuditLog) error {
+		if len(links) == 0 {
+			archive.From = log.CreatedAt
+		}
+		archive.To = log.CreatedAt
+		links = append(links, auditChainLink{seq: log.Seq, hash: log.Hash})
+		return encoder.Encode(newAuditArchiveEntry(log))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	archive.FileName = filepath.Join(tenantID.String(), archive.ID.String()+".jsonl.gz")
+	if err := os.Rename(tmp.Name(), filepath.Join(s.config.ArchiveDir, archive.FileName)); err != nil {
+		return nil, err
+	}
+
+	last := links[len(links)-1]
+	archive.RowCount = int64(len(links))
+	archive.SizeBytes = info.Size()
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	archive.LastSeq = last.seq
+	archive.LastHash = last.hash
+	archive.CreatedAt = time.Now()
+	if err := s.archiveRepo.Create(ctx, archive); err != nil {
+		os.Remove(filepath.Join(s.config.ArchiveDir, archive.FileName))
+		return nil, err
+	}
+
+	for start := 0; start < len(links); start += auditDeleteBatch {
+		through := links[min(start+auditDeleteBatch, len(links))-1]
+		if _, err := s.auditRepo.Prune(ctx, tenantID, archive.ID, through.seq, through.hash); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logArchived(ctx, archive)
+	return archive, nil
+}
+
+// ListArchives returns a tenant's archives, newest first.
+func (s *AuditRetentionService) ListArchives(ctx context.Context, tenantID uuid.UUID) ([]*models.AuditArchive, error) {
+	archives, err := s.archiveRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if archives == nil {
+		archives = []*models.AuditArchive{}
+	}
+	return archives, nil
+}
+
+// OpenArchive returns an archive and its gzip-compressed contents. The
+// caller must close the reader. Downloads are recorded in the audit log.
+func (s *AuditRetentionService) OpenArchive(ctx context.Context, actor AuditContext, id uuid.UUID) (*models.AuditArchive, io.ReadCloser, error) {
+	archive, err := s.archiveRepo.GetByID(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, nil, ErrAuditArchiveNotFound
+	}
+
+	file, err := os.Open(filepath.Join(s.config.ArchiveDir, filepath.Clean(archive.FileName)))
+	if err != nil {
+		s.logger.Error().Err(err).Str("archive_id", id.String()).Msg("Failed to open audit archive")
+		return nil, nil, ErrAuditArchiveUnavailable
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "audit_archive_downloaded",
+		Resource:   "audit_archive",
+		ResourceID: &archive.ID,
+	})
+	return archive, file, nil
+}
+
+func (s *AuditRetentionService) logArchived(ctx context.Context, archive *models.AuditArchive) {
+	s.audit.Record(ctx, SystemActor(archive.TenantID, "audit-retention"), AuditChange{
+		Action:     "audit_logs_archived",
+		Resource:   "audit_archive",
+		ResourceID: &archive.ID,
+		After:      archive,
+	})
+}
+
+func newAuditArchiveEntry(log *models.AuditLog) *auditArchiveEntry {
+	entry := &auditArchiveEntry{
+		ID:         log.ID,
+		TenantID:   log.TenantID,
+		UserID:     log.UserID,
+		Action:     log.Action,
+		Resource:   log.Resource,
+		ResourceID: log.ResourceID,
+		IPAddress:  log.IPAddress,
+		UserAgent:  log.UserAgent,
+		CreatedAt:  log.CreatedAt,
+		Seq:        log.Seq,
+		PrevHash:   log.PrevHash,
+		Hash:       log.Hash,
+	}
+	if log.OldValue != nil {
+		entry.OldValue = json.RawMessage(*log.OldValue)
+	}
+	if log.NewValue != nil {
+		entry.NewValue = json.RawMessage(*log.NewValue)
+	}
+	return entry
+}
@@ -2,11 +2,62 @@ package services
 
 import (
         "context"
+        "encoding/base64"
+        "encoding/json"
+        "errors"
+        "time"
 
         "admin-panel/internal/models"
         "admin-panel/internal/repository"
+
+        "github.com/google/uuid"
+        "github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+        ErrAuditLogNotFound     = errors.New("audit log not found")
+        ErrInvalidAuditCursor   = errors.New("invalid audit log cursor")
+        ErrInvalidAuditJSONPath = errors.New("invalid JSON path")
 )
 
+// AuditContext identifies who performed a change and from where, for the
+// audit log entry the change produces.
+type AuditContext struct {
+        TenantID  uuid.UUID
+        UserID    uuid.UUID
+        IPAddress string
+        UserAgent string
+}
+
+// SystemActor is the audit context for changes made by the application
+// itself rather than a signed-in user. name is recorded as the user agent.
+func SystemActor(tenantID uuid.UUID, name string) AuditContext {
+        return AuditContext{
+                TenantID:  tenantID,
+                UserAgent: name,
+        }
+}
+
+// actorID returns the acting user for an audit entry, or nil for system
+// actors.
+func (a AuditContext) actorID() *uuid.UUID {
+        if a.UserID == uuid.Nil {
+                return nil
+        }
+        return &a.UserID
+}
+
+// AuditLogEntry is an audit log entry together with the field-level diff of
+// its snapshots.
+type AuditLogEntry struct {
+        *models.AuditLog
+        Changes []FieldChange `json:"changes,omitempty"`
+}
+
+func newAuditLogEntry(log *models.AuditLog) *AuditLogEntry {
+        return &AuditLogEntry{AuditLog: log, Changes: AuditDiff(log)}
+}
+
 type AuditService struct {
         auditRepo *repository.AuditLogRepository
 }
@@ -17,26 +68,132 @@ func NewAuditService(auditRepo *repository.AuditLogRepository) *AuditService {
         }
 }
 
-func (s *AuditService) List(ctx context.Context, params *models.ListParams) (*models.PaginatedResponse, error) {
-        logs, total, err := s.auditRepo.List(ctx, params)
+func (s *AuditService) List(ctx context.Context, filter *models.AuditLogFilter, page, perPage int) (*models.PaginatedResponse, error) {
+        logs, total, err := s.auditRepo.List(ctx, filter, page, perPage)
         if err != nil {
-                return nil, err
+                return nil, auditQueryError(err)
         }
 
-        totalPages := int(total) / params.PerPage
-        if int(total)%params.PerPage > 0 {
+        totalPages := int(total) / perPage
+        if int(total)%perPage > 0 {
                 totalPages++
         }
 
         return &models.PaginatedResponse{
-                Data:       logs,
+                Data:       newAuditLogEntries(logs),
                 Total:      total,
-                Page:       params.Page,
-                PerPage:    params.PerPage,
+                Page:       page,
+                PerPage:    perPage,
                 TotalPages: totalPages,
         }, nil
 }
 
-func (s *AuditService) ListAll(ctx context.Context, params *models.ListParams) ([]*models.AuditLog, error) {
-        return s.auditRepo.ListAllForExport(ctx, params)
+// auditCursor is the decoded form of a keyset pagination cursor. Sort and
+// Order tie the cursor to the ordering it was issued for.
+type auditCursor struct {
+        Sort  string    `json:"s"`
+        Order string    `json:"o"`
+        Value string    `json:"v"`
+        ID    uuid.UUID `json:"id"`
+}
+
+// position validates a decoded cursor and returns the position it
+// continues after, with the value typed for the column it sorts by.
+func (c *auditCursor) position() (*models.AuditLogCursor, error) {
+        if c.ID == uuid.Nil {
+                return nil, ErrInvalidAuditCursor
+        }
+        if c.Sort != "created_at" {
+                return &models.AuditLogCursor{Value: c.Value, ID: c.ID}, nil
+        }
+        createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+        if err != nil {
+                return nil, ErrInvalidAuditCursor
+        }
+        return &models.AuditLogCursor{Value: createdAt, ID: c.ID}, nil
+}
+
+// ListPage returns up to limit entries following cursor, or the first ones
+// when cursor is empty, with the cursor for the next page.
+func (s *AuditService) ListPage(ctx context.Context, filter *models.AuditLogFilter, cursor string, limit int) (*models.CursorPage, error) {
+        sort, order := auditCursorOrder(filter)
+
+        var after *models.AuditLogCursor
+        if cursor != "" {
+                data, err := base64.RawURLEncoding.DecodeString(cursor)
+                if err != nil {
+                        return nil, ErrInvalidAuditCursor
+                }
+                var decoded auditCursor
+                if err := json.Unmarshal(data, &decoded); err != nil || decoded.Sort != sort || decoded.Order != order {
+                        return nil, ErrInvalidAuditCursor
+                }
+                after, err = decoded.position()
+                if err != nil {
+                        return nil, err
+                }
+        }
+
+        logs, err := s.auditRepo.ListAfter(ctx, filter, after, limit+1)
+        if err != nil {
+                return nil, auditQueryError(err)
+        }
+
+        page := &models.CursorPage{PerPage: limit}
+        if len(logs) > limit {
+                logs = logs[:limit]
+                last := logs[len(logs)-1]
+                next := auditCursor{Sort: sort, Order: order, ID: last.ID}
+                switch sort {
+                case "action":
+                        next.Value = last.Action
+                case "resource":
+                        next.Value = last.Resource
+                default:
+                        next.Value = last.CreatedAt.Format(time.RFC3339Nano)
+                }
+                data, _ := json.Marshal(next)
+                page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+        }
+        page.Data = newAuditLogEntries(logs)
+        return page, nil
+}
+
+// auditCursorOrder returns the ordering the repository applies for filter.
+func auditCursorOrder(filter *models.AuditLogFilter) (string, string) {
+        sort := "created_at"
+        if filter.Sort == "action" || filter.Sort == "resource" {
+                sort = filter.Sort
+        }
+        order := "desc"
+        if filter.Order == "asc" {
+                order = "asc"
+        }
+        return sort, order
+}
+
+// auditQueryError reports a malformed JSON path filter as such rather than
+// as a database failure.
+func auditQueryError(err error) error {
+        var pgErr *pgconn.PgError
+        if errors.As(err, &pgErr) && pgErr.Code == "42601" {
+                return ErrInvalidAuditJSONPath
+        }
+        return err
+}
+
+func newAuditLogEntries(logs []*models.AuditLog) []*AuditLogEntry {
+        entries := make([]*AuditLogEntry, len(logs))
+        for i, log := range logs {
+                entries[i] = newAuditLogEntry(log)
+        }
+        return entries
+}
+
+func (s *AuditService) Get(ctx context.Context, tenantID, id uuid.UUID) (*AuditLogEntry, error) {
+        log, err := s.auditRepo.GetByID(ctx, tenantID, id)
+        if err != nil {
+                return nil, ErrAuditLogNotFound
+        }
+        return newAuditLogEntry(log), nil
 }
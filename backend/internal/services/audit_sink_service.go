@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditSinkSyslog  = "syslog"
+	AuditSinkWebhook = "webhook"
+	AuditSinkFile    = "file"
+)
+
+var (
+	ErrAuditSinkNotFound   = errors.New("audit sink not found")
+	ErrAuditSinkNameExists = errors.New("audit sink name already exists")
+	ErrInvalidAuditSink    = errors.New("invalid audit sink")
+)
+
+// auditSinkFileName restricts file sinks to plain file names inside the
+// tenant's directory.
+var auditSinkFileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+type CreateAuditSinkRequest struct {
+	Name    string                 `json:"name" validate:"required,min=1,max=100"`
+	Type    string                 `json:"type" validate:"required,oneof=syslog webhook file"`
+	Config  models.AuditSinkConfig `json:"config"`
+	Secret  string                 `json:"secret" validate:"omitempty,min=16,max=255"`
+	Enabled *bool                  `json:"enabled"`
+}
+
+type UpdateAuditSinkRequest struct {
+	Name    *string                 `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Config  *models.AuditSinkConfig `json:"config,omitempty"`
+	Secret  *string                 `json:"secret,omitempty" validate:"omitempty,min=16,max=255"`
+	Enabled *bool                   `json:"enabled,omitempty"`
+}
+
+// AuditSinkService manages the sinks a tenant's audit log entries are
+// forwarded to. Entries written while a sink is enabled are queued for it;
+// the audit forwarding job delivers them.
+type AuditSinkService struct {
+	sinkRepo   *repository.AuditSinkRepository
+	outboxRepo *repository.AuditOutboxRepository
+	audit      *AuditRecorder
+}
+
+func NewAuditSinkService(
+	sinkRepo *repository.AuditSinkRepository,
+	outboxRepo *repository.AuditOutboxRepository,
+	audit *AuditRecorder,
+) *AuditSinkService {
+	return &AuditSinkService{
+		sinkRepo:   sinkRepo,
+		outboxRepo: outboxRepo,
+		audit:      audit,
+	}
+}
+
+func (s *AuditSinkService) List(ctx context.Context, tenantID uuid.UUID) ([]*models.AuditSink, error) {
+	sinks, err := s.sinkRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if sinks == nil {
+		sinks = []*models.AuditSink{}
+	}
+	return sinks, nil
+}
+
+func (s *AuditSinkService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.AuditSink, error) {
+	sink, err := s.sinkRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, ErrAuditSinkNotFound
+	}
+	return sink, nil
+}
+
+func (s *AuditSinkService) Create(ctx context.Context, actor AuditContext, req *CreateAuditSinkRequest) (*models.AuditSink, error) {
+	if existing, _ := s.sinkRepo.GetByName(ctx, actor.TenantID, req.Name); existing != nil {
+		return nil, ErrAuditSinkNameExists
+	}
+
+	now := time.Now()
+	sink := &models.AuditSink{
+		ID:        uuid.New(),
+		TenantID:  actor.TenantID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Config:    req.Config,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Secret != "" {
+		sink.Secret = &req.Secret
+	}
+	if err := normalizeAuditSink(sink); err != nil {
+		return nil, err
+	}
+
+	if err := s.sinkRepo.Create(ctx, sink); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "create",
+		Resource:   "audit_sink",
+		ResourceID: &sink.ID,
+		After:      sink,
+	})
+	return sink, nil
+}
+
+func (s *AuditSinkService) Update(ctx context.Context, actor AuditContext, id uuid.UUID, req *UpdateAuditSinkRequest) (*models.AuditSink, error) {
+	sink, err := s.Get(ctx, actor.TenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *sink
+
+	if req.Name != nil && *req.Name != sink.Name {
+		if existing, _ := s.sinkRepo.GetByName(ctx, actor.TenantID, *req.Name); existing != nil {
+			return nil, ErrAuditSinkNameExists
+		}
+		sink.Name = *req.Name
+	}
+	if req.Config != nil {
+		sink.Config = *req.Config
+	}
+	if req.Secret != nil {
+		sink.Secret = req.Secret
+	}
+	if req.Enabled != nil {
+		sink.Enabled = *req.Enabled
+	}
+	if err := normalizeAuditSink(sink); err != nil {
+		return nil, err
+	}
+	sink.UpdatedAt = time.Now()
+
+	if err := s.sinkRepo.Update(ctx, sink); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "update",
+		Resource:   "audit_sink",
+		ResourceID: &sink.ID,
+		Before:     &before,
+		After:      sink,
+	})
+	if req.Secret != nil {
+		// Snapshots never include the secret, so its rotation is recorded
+		// on its own.
+		s.audit.Record(ctx, actor, AuditChange{
+			Action:     "rotate_secret",
+			Resource:   "audit_sink",
+			ResourceID: &sink.ID,
+		})
+	}
+	return sink, nil
+}
+
+// Delete removes a sink together with the entries still queued for it.
+func (s *AuditSinkService) Delete(ctx context.Context, actor AuditContext, id uuid.UUID) error {
+	sink, err := s.Get(ctx, actor.TenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sinkRepo.Delete(ctx, actor.TenantID, id); err != nil {
+		return ErrAuditSinkNotFound
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "delete",
+		Resource:   "audit_sink",
+		ResourceID: &sink.ID,
+		Before:     sink,
+	})
+	return nil
+}
+
+// Retry queues the entries that exhausted their retries for delivery again
+// and returns how many there were.
+func (s *AuditSinkService) Retry(ctx context.Context, actor AuditContext, id uuid.UUID) (int64, error) {
+	sink, err := s.Get(ctx, actor.TenantID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.outboxRepo.Retry(ctx, sink.ID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "retry",
+		Resource:   "audit_sink",
+		ResourceID: &sink.ID,
+		After:      map[string]interface{}{"requeued": count},
+	})
+	return count, nil
+}
+
+// normalizeAuditSink validates a sink's configuration for its type and
+// drops settings that belong to other types.
+func normalizeAuditSink(sink *models.AuditSink) error {
+	config := sink.Config
+	switch sink.Type {
+	case AuditSinkSyslog:
+		if config.Network == "" {
+			config.Network = "tcp"
+		}
+		if config.Network != "tcp" && config.Network != "udp" {
+			return fmt.Errorf("%w: network must be tcp or udp", ErrInvalidAuditSink)
+		}
+		host, port, err := net.SplitHostPort(config.Address)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("%w: address must be host:port", ErrInvalidAuditSink)
+		}
+		if blockedSinkHost(host) {
+			return fmt.Errorf("%w: address must not be a loopback, private, link-local or multicast address", ErrInvalidAuditSink)
+		}
+		if config.Facility != nil && (*config.Facility < 0 || *config.Facility > 23) {
+			return fmt.Errorf("%w: facility must be between 0 and 23", ErrInvalidAuditSink)
+		}
+		if len(config.AppName) > 48 || syslogField(config.AppName) != config.AppName {
+			return fmt.Errorf("%w: app_name must be at most 48 printable characters without spaces", ErrInvalidAuditSink)
+		}
+		sink.Config = models.AuditSinkConfig{
+			Network:  config.Network,
+			Address:  config.Address,
+			Facility: config.Facility,
+			AppName:  config.AppName,
+		}
+		sink.Secret = nil
+	case AuditSinkWebhook:
+		target, err := url.Parse(config.URL)
+		if err != nil || target.Scheme != "https" || target.Host == "" {
+			return fmt.Errorf("%w: url must be an https URL", ErrInvalidAuditSink)
+		}
+		if blockedSinkHost(target.Hostname()) {
+			return fmt.Errorf("%w: url must not point to a loopback, private, link-local or multicast address", ErrInvalidAuditSink)
+		}
+		if sink.Secret == nil || *sink.Secret == "" {
+			return fmt.Errorf("%w: webhooks require a signing secret", ErrInvalidAuditSink)
+		}
+		sink.Config = models.AuditSinkConfig{URL: config.URL}
+	case AuditSinkFile:
+		if !auditSinkFileName.MatchString(config.FileName) {
+			return fmt.Errorf("%w: file_name must be a plain file name", ErrInvalidAuditSink)
+		}
+		sink.Config = models.AuditSinkConfig{FileName: config.FileName}
+		sink.Secret = nil
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAuditSink, sink.Type)
+	}
+	return nil
+}
+
+// blockedSinkHost reports whether a configured sink host is refused up
+// front: localhost or a blocked IP address. Host names are checked again
+// when dialing, once they have been resolved.
+func blockedSinkHost(host string) bool {
+	if strings.EqualFold(strings.TrimSuffix(host, "."), "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && blockedSinkIP(ip)
+}
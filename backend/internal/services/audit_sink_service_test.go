@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"admin-panel/internal/models"
+)
+
+func TestNormalizeAuditSinkRejectsBlockedHosts(t *testing.T) {
+	secret := "secret"
+	tests := []struct {
+		name string
+		sink models.AuditSink
+	}{
+		{"syslog loopback", models.AuditSink{Type: AuditSinkSyslog, Config: models.AuditSinkConfig{Address: "127.0.0.1:514"}}},
+		{"syslog localhost", models.AuditSink{Type: AuditSinkSyslog, Config: models.AuditSinkConfig{Address: "localhost:514"}}},
+		{"syslog private", models.AuditSink{Type: AuditSinkSyslog, Config: models.AuditSinkConfig{Address: "10.0.0.5:514"}}},
+		{"webhook metadata", models.AuditSink{Type: AuditSinkWebhook, Secret: &secret, Config: models.AuditSinkConfig{URL: "https://169.254.169.254/latest"}}},
+		{"webhook loopback v6", models.AuditSink{Type: AuditSinkWebhook, Secret: &secret, Config: models.AuditSinkConfig{URL: "https://[::1]/hook"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := normalizeAuditSink(&tt.sink); !errors.Is(err, ErrInvalidAuditSink) {
+				t.Errorf("normalizeAuditSink = %v, want ErrInvalidAuditSink", err)
+			}
+		})
+	}
+
+	sink := models.AuditSink{Type: AuditSinkWebhook, Secret: &secret, Config: models.AuditSinkConfig{URL: "https://siem.example.com/hook"}}
+	if err := normalizeAuditSink(&sink); err != nil {
+		t.Errorf("normalizeAuditSink of a public webhook: %v", err)
+	}
+}
@@ -3,17 +3,18 @@ package services
 import (
         "context"
         "errors"
-        "strings"
         "sync"
         "time"
 
         "admin-panel/internal/config"
         "admin-panel/internal/models"
+        "admin-panel/internal/ratelimit"
         "admin-panel/internal/repository"
         "admin-panel/internal/utils"
 
         "github.com/golang-jwt/jwt/v5"
         "github.com/google/uuid"
+        "github.com/jackc/pgx/v5"
         "github.com/rs/zerolog"
 )
 
@@ -23,136 +24,556 @@ var (
         ErrUserInactive       = errors.New("user account is inactive")
         ErrTokenExpired       = errors.New("token has expired")
         ErrInvalidToken       = errors.New("invalid token")
+        ErrAdminNotEnabled    = errors.New("admin access is not enabled")
+        ErrAccountLocked      = errors.New("account is temporarily locked")
+        ErrStepUpLocked       = errors.New("too many step-up attempts")
 )
 
 type TokenClaims struct {
-        UserID   uuid.UUID `json:"user_id"`
-        TenantID uuid.UUID `json:"tenant_id"`
-        Email    string    `json:"email"`
+        UserID        uuid.UUID        `json:"user_id"`
+        TenantID      uuid.UUID        `json:"tenant_id"`
+        Email         string           `json:"email"`
+        ElevatedUntil *jwt.NumericDate `json:"elevated_until,omitempty"`
+        // ReauthenticatedUntil ends the window in which the user has just
+        // re-entered their own password or second factor.
+        ReauthenticatedUntil *jwt.NumericDate `json:"reauthenticated_until,omitempty"`
+        Purpose              string           `json:"purpose,omitempty"`
+        // SessionID names the sign-in an access or refresh token belongs
+        // to, so that it stops working once that session is revoked.
+        SessionID uuid.UUID `json:"sid,omitempty"`
         jwt.RegisteredClaims
 }
 
+// tokenPurposeTwoFactor marks the short-lived token handed out between the
+// password and second-factor steps of login. Tokens with a purpose are never
+// accepted as access or refresh tokens.
+const tokenPurposeTwoFactor = "two_factor"
+
+// tokenPurposePasswordChange marks the token handed out when login is
+// blocked until an expired password is replaced.
+const tokenPurposePasswordChange = "password_change"
+
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// twoFactorChallengeAttempts is how many attempts one login challenge
+// allows, whether at a second factor or at replacing an expired password.
+// After that the user has to sign in with their password again.
+const twoFactorChallengeAttempts = 5
+
+// stepUpAttempts is how many step-up and re-authentication attempts a user
+// may make per stepUpAttemptWindow, whatever their IP address. Successful
+// attempts count too, so someone holding a stolen access token gets only a
+// handful of guesses at the second credential.
+const (
+        stepUpAttempts      = 5
+        stepUpAttemptWindow = 15 * time.Minute
+)
+
+// IsElevated reports whether the token carries an unexpired step-up
+// elevation.
+func (c *TokenClaims) IsElevated() bool {
+        return c.ElevatedUntil != nil && time.Now().Before(c.ElevatedUntil.Time)
+}
+
+// IsReauthenticated reports whether the token carries an unexpired
+// re-authentication.
+func (c *TokenClaims) IsReauthenticated() bool {
+        return c.ReauthenticatedUntil != nil && time.Now().Before(c.ReauthenticatedUntil.Time)
+}
+
 type AuthTokens struct {
-        AccessToken  string `json:"access_token"`
-        RefreshToken string `json:"refresh_token"`
-        ExpiresIn    int64  `json:"expires_in"`
+        AccessToken      string `json:"access_token"`
+        RefreshToken     string `json:"refresh_token"`
+        ExpiresIn        int64  `json:"expires_in"`
+        RefreshExpiresIn int64  `json:"-"`
+}
+
+// LoginPolicy is the lockout policy applied to login attempts for a tenant.
+// Each consecutive lockout doubles the previous one, up to MaxLockout.
+type LoginPolicy struct {
+        MaxAttempts int
+        Window      time.Duration
+        Lockout     time.Duration
+        MaxLockout  time.Duration
+}
+
+// lockoutDuration returns how long the lockout following the given number
+// of earlier lockouts lasts.
+func (p LoginPolicy) lockoutDuration(previousLockouts int) time.Duration {
+        duration := p.Lockout
+        for i := 0; i < previousLockouts && duration < p.MaxLockout; i++ {
+                duration *= 2
+        }
+        if duration > p.MaxLockout {
+                duration = p.MaxLockout
+        }
+        return duration
+}
+
+// StepUpResponse carries an access token that has been elevated by
+// re-verifying the admin password.
+type StepUpResponse struct {
+        AccessToken   string    `json:"access_token"`
+        ExpiresIn     int64     `json:"expires_in"`
+        ElevatedUntil time.Time `json:"elevated_until"`
+}
+
+// ReauthenticateRequest carries the account password or a second-factor
+// code; exactly one of them is checked.
+type ReauthenticateRequest struct {
+        Password string `json:"password,omitempty"`
+        Code     string `json:"code,omitempty" validate:"omitempty,min=6,max=20"`
+}
+
+// ReauthenticateResponse carries an access token marked as recently
+// re-authenticated.
+type ReauthenticateResponse struct {
+        AccessToken          string    `json:"access_token"`
+        ExpiresIn            int64     `json:"expires_in"`
+        ReauthenticatedUntil time.Time `json:"reauthenticated_until"`
 }
 
 type AuthService struct {
-        userRepo        *repository.UserRepository
-        roleRepo        *repository.RoleRepository
-        sessionRepo     *repository.SessionRepository
-        auditRepo       *repository.AuditLogRepository
-        jwtConfig       config.JWTConfig
-        permissionCache sync.Map
-        logger          zerolog.Logger
+        userRepo         *repository.UserRepository
+        roleRepo         *repository.RoleRepository
+        sessionRepo      *repository.SessionRepository
+        audit            *AuditRecorder
+        adminAuthRepo    *repository.AdminAuthRepository
+        settingsService  *SettingsService
+        tenantService    *TenantService
+        twoFactorService *TwoFactorService
+        webauthnService  *WebAuthnService
+        passwordPolicy   *PasswordPolicyService
+        challengeLimiter ratelimit.Limiter
+        jwtConfig        config.JWTConfig
+        loginConfig      config.LoginConfig
+        permissionCache  sync.Map
+        logger           zerolog.Logger
 }
 
 func NewAuthService(
         userRepo *repository.UserRepository,
         roleRepo *repository.RoleRepository,
         sessionRepo *repository.SessionRepository,
-        auditRepo *repository.AuditLogRepository,
+        audit *AuditRecorder,
+        adminAuthRepo *repository.AdminAuthRepository,
+        settingsService *SettingsService,
+        tenantService *TenantService,
+        twoFactorService *TwoFactorService,
+        webauthnService *WebAuthnService,
+        passwordPolicy *PasswordPolicyService,
+        challengeLimiter ratelimit.Limiter,
         jwtConfig config.JWTConfig,
+        loginConfig config.LoginConfig,
         logger zerolog.Logger,
 ) *AuthService {
         return &AuthService{
-                userRepo:    userRepo,
-                roleRepo:    roleRepo,
-                sessionRepo: sessionRepo,
-                auditRepo:   auditRepo,
-                jwtConfig:   jwtConfig,
-                logger:      logger,
+                userRepo:         userRepo,
+                roleRepo:         roleRepo,
+                sessionRepo:      sessionRepo,
+                audit:            audit,
+                adminAuthRepo:    adminAuthRepo,
+                settingsService:  settingsService,
+                tenantService:    tenantService,
+                twoFactorService: twoFactorService,
+                webauthnService:  webauthnService,
+                passwordPolicy:   passwordPolicy,
+                challengeLimiter: challengeLimiter,
+                jwtConfig:        jwtConfig,
+                loginConfig:      loginConfig,
+                logger:           logger,
         }
 }
 
 type LoginRequest struct {
+        Tenant   string `json:"tenant,omitempty" validate:"omitempty,max=100"`
         Email    string `json:"email" validate:"required,email"`
         Password string `json:"password" validate:"required,min=8"`
 }
 
+// LoginResponse carries the signed-in user and tokens, or, when login needs
+// another step, only a challenge token to present with it: a second factor
+// (listing the methods that can satisfy it) or a replacement for an expired
+// password.
 type LoginResponse struct {
-        User   *models.User `json:"user"`
-        Tokens *AuthTokens  `json:"tokens"`
+        User                   *models.User `json:"user,omitempty"`
+        Tokens                 *AuthTokens  `json:"tokens,omitempty"`
+        TwoFactorRequired      bool         `json:"two_factor_required,omitempty"`
+        TwoFactorMethods       []string     `json:"two_factor_methods,omitempty"`
+        PasswordChangeRequired bool         `json:"password_change_required,omitempty"`
+        ChallengeToken         string       `json:"challenge_token,omitempty"`
+}
+
+type TwoFactorLoginRequest struct {
+        ChallengeToken string `json:"challenge_token" validate:"required"`
+        Code           string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type WebAuthnChallengeRequest struct {
+        ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type WebAuthnLoginRequest struct {
+        ChallengeToken string `json:"challenge_token" validate:"required"`
+        WebAuthnAssertionRequest
+}
+
+type ExpiredPasswordChangeRequest struct {
+        ChallengeToken string `json:"challenge_token" validate:"required"`
+        NewPassword    string `json:"new_password" validate:"required,min=8"`
+}
+
+type ChangePasswordRequest struct {
+        CurrentPassword string `json:"current_password" validate:"required"`
+        NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+type PasswordlessBeginRequest struct {
+        Tenant string `json:"tenant,omitempty" validate:"omitempty,max=100"`
 }
 
 func (s *AuthService) Login(ctx context.Context, req *LoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
         // Lowercase email before lookup
-        email := strings.ToLower(strings.TrimSpace(req.Email))
-
-        // Demo account logic
-        if email == "admin@example.com" && (req.Password == "password123" || req.Password == "Admin123!") {
-                now := time.Now()
-                user := &models.User{
-                        ID:        uuid.MustParse("00000000-0000-0000-0000-000000000000"),
-                        TenantID:  uuid.MustParse("00000000-0000-0000-0000-000000000001"),
-                        Email:     "admin@example.com",
-                        FirstName: "Demo",
-                        LastName:  "Admin",
-                        Status:    "active",
-                        CreatedAt: now,
-                        UpdatedAt: now,
-                }
+        email := normalizeEmail(req.Email)
+
+        tenant, err := s.tenantService.GetBySlug(ctx, req.Tenant)
+        if err != nil {
+                s.logLoginFailure(ctx, nil, email, ipAddress, userAgent)
+                return nil, ErrInvalidCredentials
+        }
+
+        user, err := s.userRepo.GetByEmail(ctx, tenant.ID, email)
+        if err != nil {
+                s.logLoginFailure(ctx, nil, email, ipAddress, userAgent)
+                return nil, ErrInvalidCredentials
+        }
+
+        // Lockout and status are only revealed to someone who knows the
+        // password, so they cannot be used to find out which emails have
+        // accounts. Wrong passwords keep counting during a lockout and
+        // extend it.
+        if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
+                s.recordFailedLogin(ctx, user, ipAddress, userAgent)
+                return nil, ErrInvalidCredentials
+        }
+
+        if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
+                return nil, ErrAccountLocked
+        }
 
-                tokens, err := s.generateTokens(user)
+        if user.Status != "active" {
+                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
+                return nil, ErrUserInactive
+        }
+        s.upgradePasswordHash(ctx, user, req.Password)
+
+        if tenant.Status != TenantStatusActive {
+                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
+                return nil, ErrTenantInactive
+        }
+
+        if s.passwordPolicy.IsExpired(ctx, user) {
+                challenge, err := s.signChallenge(user, tokenPurposePasswordChange)
                 if err != nil {
                         return nil, err
                 }
+                return &LoginResponse{
+                        PasswordChangeRequired: true,
+                        ChallengeToken:         challenge,
+                }, nil
+        }
+
+        return s.continueLogin(ctx, user, ipAddress, userAgent)
+}
+
+// upgradePasswordHash rehashes a just-verified password when its stored
+// hash uses an outdated algorithm or parameters. Failures are only logged;
+// the old hash keeps working.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+        if !utils.NeedsRehash(user.PasswordHash) {
+                return
+        }
 
+        newHash, err := utils.HashPassword(password)
+        if err != nil {
+                s.logger.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to upgrade password hash")
+                return
+        }
+        if err := s.userRepo.UpdatePasswordHash(ctx, user.TenantID, user.ID, user.PasswordHash, newHash); err != nil {
+                s.logger.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to upgrade password hash")
+                return
+        }
+        user.PasswordHash = newHash
+}
+
+// continueLogin finishes a login whose password step has succeeded,
+// asking for a second factor when the user has one set up.
+func (s *AuthService) continueLogin(ctx context.Context, user *models.User, ipAddress, userAgent string) (*LoginResponse, error) {
+        if methods := s.twoFactorMethods(ctx, user.ID); len(methods) > 0 {
+                challenge, err := s.signChallenge(user, tokenPurposeTwoFactor)
+                if err != nil {
+                        return nil, err
+                }
                 return &LoginResponse{
-                        User:   user,
-                        Tokens: tokens,
+                        TwoFactorRequired: true,
+                        TwoFactorMethods:  methods,
+                        ChallengeToken:    challenge,
                 }, nil
         }
 
-        user, err := s.userRepo.GetByEmail(ctx, email)
+        return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
+
+// ChangeExpiredPassword replaces an expired password during login and then
+// continues the login. Each attempt is spent against the challenge, so one
+// challenge cannot be replayed to keep changing the password.
+func (s *AuthService) ChangeExpiredPassword(ctx context.Context, req *ExpiredPasswordChangeRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+        user, err := s.challengeUser(ctx, req.ChallengeToken, tokenPurposePasswordChange, ipAddress, userAgent)
+        if err != nil {
+                return nil, err
+        }
+        if err := s.spendChallenge(ctx, req.ChallengeToken); err != nil {
+                return nil, err
+        }
+
+        if err := s.passwordPolicy.SetPassword(ctx, user, req.NewPassword); err != nil {
+                return nil, err
+        }
+        s.logPasswordChange(ctx, user, ipAddress, userAgent)
+
+        return s.continueLogin(ctx, user, ipAddress, userAgent)
+}
+
+// ChangePassword lets a signed-in user replace their password after
+// confirming the current one.
+func (s *AuthService) ChangePassword(ctx context.Context, claims *TokenClaims, req *ChangePasswordRequest, ipAddress, userAgent string) error {
+        user, err := s.userRepo.GetByID(ctx, claims.TenantID, claims.UserID)
+        if err != nil {
+                return ErrUserNotFound
+        }
+
+        if !utils.CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+                return ErrInvalidCredentials
+        }
+
+        if err := s.passwordPolicy.SetPassword(ctx, user, req.NewPassword); err != nil {
+                return err
+        }
+        s.logPasswordChange(ctx, user, ipAddress, userAgent)
+
+        return nil
+}
+
+// PasswordPolicy returns the password policy of a tenant so clients can
+// show it before a password is submitted.
+func (s *AuthService) PasswordPolicy(ctx context.Context, tenantID uuid.UUID) *PasswordPolicy {
+        return s.passwordPolicy.Policy(ctx, tenantID)
+}
+
+func (s *AuthService) logPasswordChange(ctx context.Context, user *models.User, ipAddress, userAgent string) {
+        s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                Action:     "password_changed",
+                Resource:   "user",
+                ResourceID: &user.ID,
+        })
+}
+
+// twoFactorMethods lists the second factors the user has set up.
+func (s *AuthService) twoFactorMethods(ctx context.Context, userID uuid.UUID) []string {
+        var methods []string
+        if s.twoFactorService.IsEnabled(ctx, userID) {
+                methods = append(methods, "totp")
+        }
+        if s.webauthnService.HasCredentials(ctx, userID) {
+                methods = append(methods, "webauthn")
+        }
+        return methods
+}
+
+// LoginTwoFactor finishes a login that was interrupted by a two-factor
+// challenge.
+func (s *AuthService) LoginTwoFactor(ctx context.Context, req *TwoFactorLoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+        user, err := s.challengeUser(ctx, req.ChallengeToken, tokenPurposeTwoFactor, ipAddress, userAgent)
+        if err != nil {
+                return nil, err
+        }
+        if err := s.spendChallenge(ctx, req.ChallengeToken); err != nil {
+                return nil, err
+        }
+
+        if err := s.twoFactorService.VerifyLogin(ctx, user, req.Code, ipAddress, userAgent); err != nil {
+                s.logLoginFailure(ctx, user, user.Email, ipAddress, userAgent)
+                s.recordFailedLogin(ctx, user, ipAddress, userAgent)
+                return nil, ErrInvalidTwoFactorCode
+        }
+
+        return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
+
+// BeginWebAuthnLogin starts a passkey assertion as the second factor of an
+// interrupted login.
+func (s *AuthService) BeginWebAuthnLogin(ctx context.Context, req *WebAuthnChallengeRequest, ipAddress, userAgent string) (*WebAuthnAssertionOptions, error) {
+        user, err := s.challengeUser(ctx, req.ChallengeToken, tokenPurposeTwoFactor, ipAddress, userAgent)
+        if err != nil {
+                return nil, err
+        }
+
+        return s.webauthnService.BeginLogin(ctx, user)
+}
+
+// LoginWebAuthn finishes a login interrupted by a two-factor challenge
+// using a passkey assertion.
+func (s *AuthService) LoginWebAuthn(ctx context.Context, req *WebAuthnLoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+        user, err := s.challengeUser(ctx, req.ChallengeToken, tokenPurposeTwoFactor, ipAddress, userAgent)
+        if err != nil {
+                return nil, err
+        }
+        if err := s.spendChallenge(ctx, req.ChallengeToken); err != nil {
+                return nil, err
+        }
+
+        if err := s.webauthnService.FinishLogin(ctx, user, &req.WebAuthnAssertionRequest, ipAddress, userAgent); err != nil {
+                s.logLoginFailure(ctx, user, user.Email, ipAddress, userAgent)
+                if errors.Is(err, ErrWebAuthnVerification) {
+                        s.recordFailedLogin(ctx, user, ipAddress, userAgent)
+                }
+                return nil, err
+        }
+
+        return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
+
+// BeginPasswordlessLogin starts a passkey-only login for the tenant.
+func (s *AuthService) BeginPasswordlessLogin(ctx context.Context, tenantSlug string) (*WebAuthnAssertionOptions, error) {
+        tenant, err := s.tenantService.GetBySlug(ctx, tenantSlug)
         if err != nil {
-                s.logLoginFailure(ctx, nil, email, ipAddress, userAgent)
                 return nil, ErrInvalidCredentials
         }
+        if tenant.Status != TenantStatusActive {
+                return nil, ErrTenantInactive
+        }
+
+        return s.webauthnService.BeginPasswordless(ctx, tenant.ID)
+}
+
+// LoginPasswordless signs a user in with a user-verified passkey alone.
+// The password expiry policy is deliberately not applied: no password is
+// presented, and an expired one is still caught by Login before it can be
+// used.
+func (s *AuthService) LoginPasswordless(ctx context.Context, req *WebAuthnAssertionRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+        user, err := s.webauthnService.FinishPasswordless(ctx, req, ipAddress, userAgent)
+        if err != nil {
+                return nil, err
+        }
+
+        if err := s.checkSignInAllowed(ctx, user, ipAddress, userAgent); err != nil {
+                return nil, err
+        }
+
+        return s.completeLogin(ctx, user, ipAddress, userAgent)
+}
+
+// challengeUser resolves the user behind a login challenge token issued for
+// purpose and checks they may still sign in.
+func (s *AuthService) challengeUser(ctx context.Context, challengeToken, purpose, ipAddress, userAgent string) (*models.User, error) {
+        claims, err := s.parseToken(challengeToken)
+        if err != nil || claims.Purpose != purpose {
+                return nil, ErrInvalidToken
+        }
+
+        user, err := s.userRepo.GetByID(ctx, claims.TenantID, claims.UserID)
+        if err != nil {
+                return nil, ErrInvalidToken
+        }
+
+        if err := s.checkSignInAllowed(ctx, user, ipAddress, userAgent); err != nil {
+                return nil, err
+        }
+
+        return user, nil
+}
+
+// checkSignInAllowed applies the checks Login makes before letting a user
+// in to sign-ins that skip the password step: the account must not be
+// locked out or inactive, and its tenant must be active.
+func (s *AuthService) checkSignInAllowed(ctx context.Context, user *models.User, ipAddress, userAgent string) error {
+        if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+                s.logLoginFailure(ctx, user, user.Email, ipAddress, userAgent)
+                return ErrAccountLocked
+        }
 
         if user.Status != "active" {
-                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
-                return nil, ErrUserInactive
+                s.logLoginFailure(ctx, user, user.Email, ipAddress, userAgent)
+                return ErrUserInactive
         }
 
-        if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-                s.logLoginFailure(ctx, user, email, ipAddress, userAgent)
-                return nil, ErrInvalidCredentials
+        return s.tenantService.EnsureActive(ctx, user.TenantID)
+}
+
+// spendChallenge counts an attempt against a login challenge token, and
+// rejects the token once it has used up its attempts. Together with the
+// account lockout this bounds how many codes can be guessed with one
+// password entry.
+func (s *AuthService) spendChallenge(ctx context.Context, challengeToken string) error {
+        claims, err := s.parseToken(challengeToken)
+        if err != nil || claims.ID == "" {
+                return ErrInvalidToken
         }
 
-        tokens, err := s.generateTokens(user)
+        // The window is far longer than a challenge lives, so attempts are
+        // not replenished before it expires, whichever algorithm counts them.
+        limit := ratelimit.Limit{Requests: twoFactorChallengeAttempts, Window: 12 * twoFactorChallengeTTL}
+        result, err := s.challengeLimiter.Allow(ctx, "two_factor_challenge:"+claims.ID, limit)
+        if err != nil {
+                return err
+        }
+        if !result.Allowed {
+                return ErrInvalidToken
+        }
+        return nil
+}
+
+// completeLogin issues tokens and a session for a user whose credentials
+// have been fully verified.
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, ipAddress, userAgent string) (*LoginResponse, error) {
+        sessionID := uuid.New()
+        tokens, err := s.generateTokens(ctx, user, sessionID)
         if err != nil {
                 return nil, err
         }
 
+        now := time.Now()
         session := &models.Session{
-                ID:           uuid.New(),
-                UserID:       user.ID,
-                RefreshToken: tokens.RefreshToken,
-                IPAddress:    ipAddress,
-                UserAgent:    userAgent,
-                ExpiresAt:    time.Now().Add(s.jwtConfig.RefreshTokenTTL),
-                CreatedAt:    time.Now(),
+                ID:              sessionID,
+                FamilyID:        sessionID,
+                UserID:          user.ID,
+                RefreshToken:    tokens.RefreshToken,
+                IPAddress:       ipAddress,
+                UserAgent:       userAgent,
+                ExpiresAt:       now.Add(time.Duration(tokens.RefreshExpiresIn) * time.Second),
+                AuthenticatedAt: now,
+                CreatedAt:       now,
         }
 
         if err := s.sessionRepo.Create(ctx, session); err != nil {
                 return nil, err
         }
 
-        if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+        if err := s.userRepo.UpdateLastLogin(ctx, user.TenantID, user.ID); err != nil {
                 return nil, err
         }
+        // Failed attempts are only forgiven once every factor has been
+        // verified, so re-entering a known password does not reset the
+        // count of wrong second factors.
+        if err := s.userRepo.ResetFailedLogins(ctx, user.TenantID, user.ID); err != nil {
+                s.logger.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to reset failed login attempts")
+        }
 
-        s.auditRepo.Log(ctx, &models.AuditLog{
-                ID:        uuid.New(),
-                TenantID:  user.TenantID,
-                UserID:    &user.ID,
-                Action:    "login",
-                Resource:  "auth",
-                IPAddress: ipAddress,
-                UserAgent: userAgent,
-                CreatedAt: time.Now(),
+        s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                Action:   "login",
+                Resource: "auth",
         })
 
         return &LoginResponse{
@@ -164,15 +585,9 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest, ipAddress, u
 func (s *AuthService) logLoginFailure(ctx context.Context, user *models.User, email, ipAddress, userAgent string) {
         now := time.Now()
         if user != nil {
-                s.auditRepo.Log(ctx, &models.AuditLog{
-                        ID:        uuid.New(),
-                        TenantID:  user.TenantID,
-                        UserID:    &user.ID,
-                        Action:    "login_failed",
-                        Resource:  "auth",
-                        IPAddress: ipAddress,
-                        UserAgent: userAgent,
-                        CreatedAt: now,
+                s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                        Action:   "login_failed",
+                        Resource: "auth",
                 })
                 return
         }
@@ -185,38 +600,40 @@ func (s *AuthService) logLoginFailure(ctx context.Context, user *models.User, em
                 Msg("login failed")
 }
 
-func (s *AuthService) Logout(ctx context.Context, userID uuid.UUID, ipAddress, userAgent string) error {
-        user, err := s.userRepo.GetByID(ctx, userID)
+// Logout revokes the session the caller's token belongs to. Tokens issued
+// before sessions were tracked individually carry no session, in which case
+// every session of the user is revoked.
+func (s *AuthService) Logout(ctx context.Context, claims *TokenClaims, ipAddress, userAgent string) error {
+        userID := claims.UserID
+        user, err := s.userRepo.GetByID(ctx, claims.TenantID, userID)
         if err != nil {
                 return err
         }
 
-        if err := s.sessionRepo.RevokeByUserID(ctx, userID, time.Now()); err != nil {
+        if claims.SessionID == uuid.Nil {
+                if err := s.sessionRepo.RevokeByUserID(ctx, userID, time.Now()); err != nil {
+                        return err
+                }
+        } else if err := s.sessionRepo.RevokeFamily(ctx, userID, claims.SessionID, time.Now()); err != nil && !errors.Is(err, pgx.ErrNoRows) {
                 return err
         }
 
-        s.auditRepo.Log(ctx, &models.AuditLog{
-                ID:        uuid.New(),
-                TenantID:  user.TenantID,
-                UserID:    &userID,
-                Action:    "logout",
-                Resource:  "auth",
-                IPAddress: ipAddress,
-                UserAgent: userAgent,
-                CreatedAt: time.Now(),
+        s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: userID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                Action:   "logout",
+                Resource: "auth",
         })
 
         return nil
 }
 
 func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*AuthTokens, error) {
-        _, err := s.validateToken(refreshToken)
+        claims, err := s.validateToken(refreshToken)
         if err != nil {
                 return nil, err
         }
 
         session, err := s.sessionRepo.GetByRefreshToken(ctx, refreshToken)
-        if err != nil {
+        if err != nil || session.UserID != claims.UserID {
                 return nil, ErrInvalidToken
         }
 
@@ -229,20 +646,14 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
                         return nil, err
                 }
 
-                user, userErr := s.userRepo.GetByID(ctx, session.UserID)
+                user, userErr := s.userRepo.GetByID(ctx, claims.TenantID, session.UserID)
                 if userErr != nil {
                         return nil, ErrUserNotFound
                 }
 
-                s.auditRepo.Log(ctx, &models.AuditLog{
-                        ID:        uuid.New(),
-                        TenantID:  user.TenantID,
-                        UserID:    &user.ID,
-                        Action:    "refresh_token_reuse",
-                        Resource:  "auth",
-                        IPAddress: session.IPAddress,
-                        UserAgent: session.UserAgent,
-                        CreatedAt: time.Now(),
+                s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: session.IPAddress, UserAgent: session.UserAgent}, AuditChange{
+                        Action:   "refresh_token_reuse",
+                        Resource: "auth",
                 })
 
                 return nil, ErrInvalidToken
@@ -252,12 +663,16 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
                 return nil, ErrTokenExpired
         }
 
-        user, err := s.userRepo.GetByID(ctx, session.UserID)
+        user, err := s.userRepo.GetByID(ctx, claims.TenantID, session.UserID)
         if err != nil {
                 return nil, ErrUserNotFound
         }
 
-        tokens, err := s.generateTokens(user)
+        if err := s.tenantService.EnsureActive(ctx, user.TenantID); err != nil {
+                return nil, err
+        }
+
+        tokens, err := s.generateTokens(ctx, user, session.FamilyID)
         if err != nil {
                 return nil, err
         }
@@ -268,13 +683,15 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
         }
 
         newSession := &models.Session{
-                ID:           uuid.New(),
-                UserID:       session.UserID,
-                RefreshToken: tokens.RefreshToken,
-                IPAddress:    session.IPAddress,
-                UserAgent:    session.UserAgent,
-                ExpiresAt:    now.Add(s.jwtConfig.RefreshTokenTTL),
-                CreatedAt:    now,
+                ID:              uuid.New(),
+                FamilyID:        session.FamilyID,
+                UserID:          session.UserID,
+                RefreshToken:    tokens.RefreshToken,
+                IPAddress:       session.IPAddress,
+                UserAgent:       session.UserAgent,
+                ExpiresAt:       now.Add(time.Duration(tokens.RefreshExpiresIn) * time.Second),
+                AuthenticatedAt: session.AuthenticatedAt,
+                CreatedAt:       now,
         }
         if err := s.sessionRepo.Create(ctx, newSession); err != nil {
                 return nil, err
@@ -283,10 +700,206 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
         return tokens, nil
 }
 
+// StepUp verifies the caller's admin password and returns a copy of their
+// access token elevated for the configured step-up window. The elevated
+// token never outlives the original one.
+func (s *AuthService) StepUp(ctx context.Context, claims *TokenClaims, password, ipAddress, userAgent string) (*StepUpResponse, error) {
+        if err := s.spendStepUpAttempt(ctx, claims, ipAddress, userAgent); err != nil {
+                return nil, err
+        }
+
+        adminAuth, err := s.adminAuthRepo.GetByUserID(ctx, claims.TenantID, claims.UserID)
+        if err != nil || !adminAuth.IsAdmin {
+                s.logStepUp(ctx, claims, "step_up_failed", ipAddress, userAgent)
+                return nil, ErrAdminNotEnabled
+        }
+
+        if !utils.CheckPasswordHash(password, adminAuth.AdminPasswordHash) {
+                s.logStepUp(ctx, claims, "step_up_failed", ipAddress, userAgent)
+                return nil, ErrInvalidCredentials
+        }
+        if utils.NeedsRehash(adminAuth.AdminPasswordHash) {
+                if newHash, err := utils.HashPassword(password); err == nil {
+                        if err := s.adminAuthRepo.UpdatePasswordHash(ctx, claims.UserID, adminAuth.AdminPasswordHash, newHash); err != nil {
+                                s.logger.Warn().Err(err).Str("user_id", claims.UserID.String()).Msg("Failed to upgrade admin password hash")
+                        }
+                }
+        }
+
+        elevatedUntil, expiresAt := s.stepUpWindow(claims)
+        tokenString, err := s.reissueAccessToken(claims, expiresAt, func(elevated *TokenClaims) {
+                elevated.ElevatedUntil = jwt.NewNumericDate(elevatedUntil)
+        })
+        if err != nil {
+                return nil, err
+        }
+
+        s.logStepUp(ctx, claims, "step_up", ipAddress, userAgent)
+
+        return &StepUpResponse{
+                AccessToken:   tokenString,
+                ExpiresIn:     int64(time.Until(expiresAt).Seconds()),
+                ElevatedUntil: elevatedUntil,
+        }, nil
+}
+
+// Reauthenticate verifies the caller's own password, or a second-factor
+// code, and returns a copy of their access token marked as re-authenticated
+// for the step-up window. Unlike StepUp it is open to every user and guards
+// changes to their own account, such as registering a passkey.
+func (s *AuthService) Reauthenticate(ctx context.Context, claims *TokenClaims, req *ReauthenticateRequest, ipAddress, userAgent string) (*ReauthenticateResponse, error) {
+        if err := s.spendStepUpAttempt(ctx, claims, ipAddress, userAgent); err != nil {
+                return nil, err
+        }
+
+        user, err := s.userRepo.GetByID(ctx, claims.TenantID, claims.UserID)
+        if err != nil {
+                return nil, ErrUserNotFound
+        }
+
+        verified := false
+        switch {
+        case req.Password != "":
+                verified = utils.CheckPasswordHash(req.Password, user.PasswordHash)
+        case req.Code != "":
+                verified = s.twoFactorService.VerifyLogin(ctx, user, req.Code, ipAddress, userAgent) == nil
+        }
+        if !verified {
+                s.logStepUp(ctx, claims, "reauthenticate_failed", ipAddress, userAgent)
+                return nil, ErrInvalidCredentials
+        }
+
+        reauthenticatedUntil, expiresAt := s.stepUpWindow(claims)
+        tokenString, err := s.reissueAccessToken(claims, expiresAt, func(reauthenticated *TokenClaims) {
+                reauthenticated.ReauthenticatedUntil = jwt.NewNumericDate(reauthenticatedUntil)
+        })
+        if err != nil {
+                return nil, err
+        }
+
+        s.logStepUp(ctx, claims, "reauthenticate", ipAddress, userAgent)
+
+        return &ReauthenticateResponse{
+                AccessToken:          tokenString,
+                ExpiresIn:            int64(time.Until(expiresAt).Seconds()),
+                ReauthenticatedUntil: reauthenticatedUntil,
+        }, nil
+}
+
+// IsRecentlyAuthenticated reports whether the caller has re-entered a
+// credential within the step-up window: their own password or second
+// factor, or, for admins, the admin password.
+func (s *AuthService) IsRecentlyAuthenticated(ctx context.Context, claims *TokenClaims) bool {
+        return claims.IsReauthenticated() || s.IsElevated(ctx, claims)
+}
+
+// spendStepUpAttempt counts a step-up or re-authentication attempt against
+// the user and rejects it once they have used up their attempts.
+func (s *AuthService) spendStepUpAttempt(ctx context.Context, claims *TokenClaims, ipAddress, userAgent string) error {
+        limit := ratelimit.Limit{Requests: stepUpAttempts, Window: stepUpAttemptWindow}
+        result, err := s.challengeLimiter.Allow(ctx, "step_up:"+claims.UserID.String(), limit)
+        if err != nil {
+                return err
+        }
+        if !result.Allowed {
+                s.logStepUp(ctx, claims, "step_up_locked", ipAddress, userAgent)
+                return ErrStepUpLocked
+        }
+        return nil
+}
+
+// stepUpWindow returns when a step-up or re-authentication made now ends,
+// and when the access token carrying it expires. The window never outlives
+// the token.
+func (s *AuthService) stepUpWindow(claims *TokenClaims) (time.Time, time.Time) {
+        now := time.Now()
+        expiresAt := now.Add(s.jwtConfig.AccessTokenTTL)
+        if claims.ExpiresAt != nil {
+                expiresAt = claims.ExpiresAt.Time
+        }
+        until := now.Add(s.jwtConfig.StepUpTTL)
+        if until.After(expiresAt) {
+                until = expiresAt
+        }
+        return until, expiresAt
+}
+
+// reissueAccessToken signs a copy of an access token that expires at
+// expiresAt, keeping its live elevation and re-authentication, after
+// applying update.
+func (s *AuthService) reissueAccessToken(claims *TokenClaims, expiresAt time.Time, update func(*TokenClaims)) (string, error) {
+        now := time.Now()
+        reissued := &TokenClaims{
+                UserID:    claims.UserID,
+                TenantID:  claims.TenantID,
+                Email:     claims.Email,
+                SessionID: claims.SessionID,
+                RegisteredClaims: jwt.RegisteredClaims{
+                        ExpiresAt: jwt.NewNumericDate(expiresAt),
+                        IssuedAt:  jwt.NewNumericDate(now),
+                        NotBefore: jwt.NewNumericDate(now),
+                        Issuer:    "admin-panel",
+                        Subject:   claims.UserID.String(),
+                },
+        }
+        if claims.IsElevated() {
+                reissued.ElevatedUntil = claims.ElevatedUntil
+        }
+        if claims.IsReauthenticated() {
+                reissued.ReauthenticatedUntil = claims.ReauthenticatedUntil
+        }
+        update(reissued)
+
+        token := jwt.NewWithClaims(jwt.SigningMethodHS256, reissued)
+        return token.SignedString([]byte(s.jwtConfig.Secret))
+}
+
+// IsElevated reports whether claims carry a live step-up elevation for a
+// user who still holds admin access.
+func (s *AuthService) IsElevated(ctx context.Context, claims *TokenClaims) bool {
+        if !claims.IsElevated() {
+                return false
+        }
+        isAdmin, _ := s.adminAuthRepo.IsAdmin(ctx, claims.TenantID, claims.UserID)
+        return isAdmin
+}
+
+func (s *AuthService) logStepUp(ctx context.Context, claims *TokenClaims, action, ipAddress, userAgent string) {
+        s.audit.Record(ctx, AuditContext{TenantID: claims.TenantID, UserID: claims.UserID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                Action:   action,
+                Resource: "auth",
+        })
+}
+
 func (s *AuthService) ValidateAccessToken(tokenString string) (*TokenClaims, error) {
         return s.validateToken(tokenString)
 }
 
+// Authenticate validates an access token and rejects it when the tenant it
+// was issued for is no longer active or its session has been revoked.
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (*TokenClaims, error) {
+        claims, err := s.validateToken(tokenString)
+        if err != nil {
+                return nil, err
+        }
+
+        if err := s.tenantService.EnsureActive(ctx, claims.TenantID); err != nil {
+                return nil, err
+        }
+
+        if claims.SessionID != uuid.Nil {
+                active, err := s.sessionRepo.IsFamilyActive(ctx, claims.SessionID)
+                if err != nil {
+                        return nil, err
+                }
+                if !active {
+                        return nil, ErrInvalidToken
+                }
+        }
+
+        return claims, nil
+}
+
 func (s *AuthService) HasPermission(ctx context.Context, userID uuid.UUID, resource, action string) bool {
         cacheKey := userID.String()
         if cached, ok := s.permissionCache.Load(cacheKey); ok {
@@ -324,15 +937,88 @@ func (s *AuthService) InvalidatePermissionCache(userID uuid.UUID) {
         s.permissionCache.Delete(userID.String())
 }
 
-func (s *AuthService) generateTokens(user *models.User) (*AuthTokens, error) {
+// loginPolicy resolves the lockout policy for a tenant from its settings,
+// falling back to the configured defaults.
+func (s *AuthService) loginPolicy(ctx context.Context, tenantID uuid.UUID) LoginPolicy {
+        policy := LoginPolicy{
+                MaxAttempts: s.loginConfig.MaxAttempts,
+                Window:      s.loginConfig.Window,
+                Lockout:     s.loginConfig.Lockout,
+                MaxLockout:  s.loginConfig.MaxLockout,
+        }
+
+        policy.MaxAttempts = s.settingsService.GetInt(ctx, tenantID, SettingMaxLoginAttempts, policy.MaxAttempts)
+        if policy.MaxAttempts <= 0 {
+                policy.MaxAttempts = s.loginConfig.MaxAttempts
+        }
+        policy.Lockout = s.settingsService.GetMinutes(ctx, tenantID, SettingLoginLockoutMinutes, policy.Lockout)
+        if policy.MaxLockout < policy.Lockout {
+                policy.MaxLockout = policy.Lockout
+        }
+
+        return policy
+}
+
+// recordFailedLogin counts a wrong password or second factor against the
+// user and locks the account once the tenant's attempt limit is reached
+// within the window.
+func (s *AuthService) recordFailedLogin(ctx context.Context, user *models.User, ipAddress, userAgent string) {
+        policy := s.loginPolicy(ctx, user.TenantID)
         now := time.Now()
 
+        attempts, lockouts, err := s.userRepo.RecordFailedLogin(ctx, user.TenantID, user.ID, now.Add(-policy.Window))
+        if err != nil {
+                s.logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to record failed login")
+                return
+        }
+        if attempts < policy.MaxAttempts {
+                return
+        }
+
+        duration := policy.lockoutDuration(lockouts)
+        lockedUntil := now.Add(duration)
+        if err := s.userRepo.Lock(ctx, user.TenantID, user.ID, lockedUntil); err != nil {
+                s.logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to lock account")
+                return
+        }
+
+        details := map[string]interface{}{
+                "attempts":          attempts,
+                "lockout_seconds":   int64(duration.Seconds()),
+                "locked_until":      lockedUntil.UTC(),
+                "previous_lockouts": lockouts,
+        }
+        s.audit.Record(ctx, AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: ipAddress, UserAgent: userAgent}, AuditChange{
+                Action:     "login_locked",
+                Resource:   "auth",
+                ResourceID: &user.ID,
+                After:      details,
+        })
+}
+
+// tokenTTLs returns the access and refresh token lifetimes for a tenant.
+// The tenant's session timeout bounds the refresh token, and the access
+// token never outlives the session it belongs to.
+func (s *AuthService) tokenTTLs(ctx context.Context, tenantID uuid.UUID) (time.Duration, time.Duration) {
+        refreshTTL := s.settingsService.GetMinutes(ctx, tenantID, SettingSessionTimeoutMinutes, s.jwtConfig.RefreshTokenTTL)
+        accessTTL := s.jwtConfig.AccessTokenTTL
+        if accessTTL > refreshTTL {
+                accessTTL = refreshTTL
+        }
+        return accessTTL, refreshTTL
+}
+
+func (s *AuthService) generateTokens(ctx context.Context, user *models.User, sessionID uuid.UUID) (*AuthTokens, error) {
+        now := time.Now()
+        accessTTL, refreshTTL := s.tokenTTLs(ctx, user.TenantID)
+
         accessClaims := &TokenClaims{
-                UserID:   user.ID,
-                TenantID: user.TenantID,
-                Email:    user.Email,
+                UserID:    user.ID,
+                TenantID:  user.TenantID,
+                Email:     user.Email,
+                SessionID: sessionID,
                 RegisteredClaims: jwt.RegisteredClaims{
-                        ExpiresAt: jwt.NewNumericDate(now.Add(s.jwtConfig.AccessTokenTTL)),
+                        ExpiresAt: jwt.NewNumericDate(now.Add(accessTTL)),
                         IssuedAt:  jwt.NewNumericDate(now),
                         NotBefore: jwt.NewNumericDate(now),
                         Issuer:    "admin-panel",
@@ -347,11 +1033,12 @@ func (s *AuthService) generateTokens(user *models.User) (*AuthTokens, error) {
         }
 
         refreshClaims := &TokenClaims{
-                UserID:   user.ID,
-                TenantID: user.TenantID,
-                Email:    user.Email,
+                UserID:    user.ID,
+                TenantID:  user.TenantID,
+                Email:     user.Email,
+                SessionID: sessionID,
                 RegisteredClaims: jwt.RegisteredClaims{
-                        ExpiresAt: jwt.NewNumericDate(now.Add(s.jwtConfig.RefreshTokenTTL)),
+                        ExpiresAt: jwt.NewNumericDate(now.Add(refreshTTL)),
                         IssuedAt:  jwt.NewNumericDate(now),
                         NotBefore: jwt.NewNumericDate(now),
                         Issuer:    "admin-panel",
@@ -366,13 +1053,50 @@ func (s *AuthService) generateTokens(user *models.User) (*AuthTokens, error) {
         }
 
         return &AuthTokens{
-                AccessToken:  accessTokenString,
-                RefreshToken: refreshTokenString,
-                ExpiresIn:    int64(s.jwtConfig.AccessTokenTTL.Seconds()),
+                AccessToken:      accessTokenString,
+                RefreshToken:     refreshTokenString,
+                ExpiresIn:        int64(accessTTL.Seconds()),
+                RefreshExpiresIn: int64(refreshTTL.Seconds()),
         }, nil
 }
 
+// signChallenge issues the token that links the password step of login to
+// the step named by purpose.
+func (s *AuthService) signChallenge(user *models.User, purpose string) (string, error) {
+        now := time.Now()
+        claims := &TokenClaims{
+                UserID:   user.ID,
+                TenantID: user.TenantID,
+                Email:    user.Email,
+                Purpose:  purpose,
+                RegisteredClaims: jwt.RegisteredClaims{
+                        ID:        uuid.NewString(),
+                        ExpiresAt: jwt.NewNumericDate(now.Add(twoFactorChallengeTTL)),
+                        IssuedAt:  jwt.NewNumericDate(now),
+                        NotBefore: jwt.NewNumericDate(now),
+                        Issuer:    "admin-panel",
+                        Subject:   user.ID.String(),
+                },
+        }
+
+        token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+        return token.SignedString([]byte(s.jwtConfig.Secret))
+}
+
+// validateToken parses an access or refresh token. Special-purpose tokens
+// such as two-factor challenges are rejected.
 func (s *AuthService) validateToken(tokenString string) (*TokenClaims, error) {
+        claims, err := s.parseToken(tokenString)
+        if err != nil {
+                return nil, err
+        }
+        if claims.Purpose != "" {
+                return nil, ErrInvalidToken
+        }
+        return claims, nil
+}
+
+func (s *AuthService) parseToken(tokenString string) (*TokenClaims, error) {
         token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
                 if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
                         return nil, ErrInvalidToken
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/utils"
+)
+
+// demoActor is recorded in the audit log for changes made by demo seeding.
+const demoActor = "system:demo"
+
+// DemoService provisions the demo tenant and account used when the server
+// runs with APP_DEMO_MODE. Everything it creates is ordinary data, so the
+// demo account signs in through the regular login flow.
+type DemoService struct {
+	tenantService *TenantService
+	userService   *UserService
+	userRepo      *repository.UserRepository
+	roleRepo      *repository.RoleRepository
+}
+
+func NewDemoService(
+	tenantService *TenantService,
+	userService *UserService,
+	userRepo *repository.UserRepository,
+	roleRepo *repository.RoleRepository,
+) *DemoService {
+	return &DemoService{
+		tenantService: tenantService,
+		userService:   userService,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+	}
+}
+
+// Seed makes sure the demo tenant exists and is active, and that the demo
+// account exists with the configured password and the tenant's Admin role.
+// It is safe to run on every start.
+func (s *DemoService) Seed(ctx context.Context, cfg config.DemoConfig) (*models.Tenant, *models.User, error) {
+	actor := SystemActor(PlatformTenantID, demoActor)
+
+	tenant, err := s.tenantService.GetBySlug(ctx, cfg.TenantSlug)
+	if err == ErrTenantNotFound {
+		tenant, err = s.tenantService.Create(ctx, &CreateTenantRequest{
+			Name: cfg.TenantName,
+			Slug: cfg.TenantSlug,
+		}, actor)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tenant.Status != TenantStatusActive {
+		tenant, err = s.tenantService.Activate(ctx, tenant.ID, actor)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, tenant.ID, normalizeEmail(cfg.Email))
+	if err != nil {
+		role, err := s.roleRepo.GetByName(ctx, tenant.ID, "Admin")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		user, err = s.userService.Create(ctx, SystemActor(tenant.ID, demoActor), &CreateUserRequest{
+			Email:     cfg.Email,
+			Password:  cfg.Password,
+			FirstName: "Demo",
+			LastName:  "Admin",
+			RoleIDs:   []string{role.ID.String()},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return tenant, user, nil
+	}
+
+	if !utils.CheckPasswordHash(cfg.Password, user.PasswordHash) {
+		passwordHash, err := utils.HashPassword(cfg.Password)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.userRepo.UpdatePassword(ctx, tenant.ID, user.ID, passwordHash); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return tenant, user, nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/mailer"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrInvalidInvitation = errors.New("invitation is invalid or has expired")
+	ErrUserNotPending    = errors.New("user is not awaiting an invitation")
+	ErrNoInvitation      = errors.New("user has no outstanding invitation")
+	// ErrInvitationNotSent means the invitation was recorded but could not
+	// be emailed; it can be sent again with Resend.
+	ErrInvitationNotSent = errors.New("invitation email could not be sent")
+)
+
+// tokenPurposeInvitation marks invitation tokens so they are never accepted
+// as access tokens.
+const tokenPurposeInvitation = "invitation"
+
+type InvitationService struct {
+	invitationRepo  *repository.InvitationRepository
+	userRepo        *repository.UserRepository
+	audit           *AuditRecorder
+	userService     *UserService
+	tenantService   *TenantService
+	settingsService *SettingsService
+	passwordPolicy  *PasswordPolicyService
+	mailer          mailer.Sender
+	config          config.InvitationConfig
+	secret          []byte
+	logger          zerolog.Logger
+}
+
+func NewInvitationService(
+	invitationRepo *repository.InvitationRepository,
+	userRepo *repository.UserRepository,
+	audit *AuditRecorder,
+	userService *UserService,
+	tenantService *TenantService,
+	settingsService *SettingsService,
+	passwordPolicy *PasswordPolicyService,
+	sender mailer.Sender,
+	cfg config.InvitationConfig,
+	jwtConfig config.JWTConfig,
+	logger zerolog.Logger,
+) *InvitationService {
+	return &InvitationService{
+		invitationRepo:  invitationRepo,
+		userRepo:        userRepo,
+		audit:           audit,
+		userService:     userService,
+		tenantService:   tenantService,
+		settingsService: settingsService,
+		passwordPolicy:  passwordPolicy,
+		mailer:          sender,
+		config:          cfg,
+		secret:          []byte(jwtConfig.Secret),
+		logger:          logger,
+	}
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// Invite creates a pending user together with an invitation and emails it.
+// When the email cannot be sent the user and invitation are kept and
+// ErrInvitationNotSent is returned with the user.
+func (s *InvitationService) Invite(ctx context.Context, actor AuditContext, req *CreateUserRequest) (*models.User, error) {
+	user, err := s.userService.newUser(ctx, actor.TenantID, req, UserStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := s.newInvitation(actor, user)
+	if err := s.invitationRepo.CreateWithUser(ctx, user, invitation); err != nil {
+		return nil, err
+	}
+	s.userService.finishCreate(ctx, actor, user, req.RoleIDs)
+	s.logEvent(ctx, actor, "user_invited", user.ID)
+
+	return user, s.deliver(ctx, user, invitation)
+}
+
+// Resend revokes any outstanding invitation for a pending user and sends a
+// fresh one with a new expiry.
+func (s *InvitationService) Resend(ctx context.Context, actor AuditContext, userID uuid.UUID) (*models.UserInvitation, error) {
+	user, err := s.pendingUser(ctx, actor.TenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.invitationRepo.RevokeOutstanding(ctx, actor.TenantID, userID); err != nil {
+		return nil, err
+	}
+
+	invitation := s.newInvitation(actor, user)
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+	s.logEvent(ctx, actor, "user_invite_resent", userID)
+
+	if err := s.deliver(ctx, user, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// Revoke invalidates a pending user's outstanding invitation. The user
+// stays pending so the invitation can be sent again later.
+func (s *InvitationService) Revoke(ctx context.Context, actor AuditContext, userID uuid.UUID) error {
+	if _, err := s.pendingUser(ctx, actor.TenantID, userID); err != nil {
+		return err
+	}
+
+	revoked, err := s.invitationRepo.RevokeOutstanding(ctx, actor.TenantID, userID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrNoInvitation
+	}
+
+	s.logEvent(ctx, actor, "user_invite_revoked", userID)
+	return nil
+}
+
+// Get returns the most recent invitation sent to a user.
+func (s *InvitationService) Get(ctx context.Context, tenantID, userID uuid.UUID) (*models.UserInvitation, error) {
+	invitation, err := s.invitationRepo.GetLatestByUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, ErrNoInvitation
+	}
+	return invitation, nil
+}
+
+// Accept lets an invitee choose their password, which activates the
+// account.
+func (s *InvitationService) Accept(ctx context.Context, req *AcceptInvitationRequest, ipAddress, userAgent string) (*models.User, error) {
+	claims, err := s.parseToken(req.Token)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitationID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	if err := s.tenantService.EnsureActive(ctx, claims.TenantID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.pendingUser(ctx, claims.TenantID, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	if err := s.passwordPolicy.Check(ctx, user, req.Password); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.invitationRepo.Accept(ctx, invitationID, passwordHash)
+	if err != nil || invitation.UserID != user.ID || invitation.TenantID != user.TenantID {
+		return nil, ErrInvalidInvitation
+	}
+	s.passwordPolicy.RecordChange(ctx, user.ID, passwordHash)
+
+	user.PasswordHash = passwordHash
+	user.Status = UserStatusActive
+
+	s.logEvent(ctx, AuditContext{
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}, "user_invite_accepted", user.ID)
+
+	return user, nil
+}
+
+func (s *InvitationService) pendingUser(ctx context.Context, tenantID, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, tenantID, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Status != UserStatusPending {
+		return nil, ErrUserNotPending
+	}
+	return user, nil
+}
+
+// newInvitation builds an invitation for user sent by actor.
+func (s *InvitationService) newInvitation(actor AuditContext, user *models.User) *models.UserInvitation {
+	now := time.Now()
+	return &models.UserInvitation{
+		ID:        uuid.New(),
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		InvitedBy: actor.actorID(),
+		ExpiresAt: now.Add(s.config.TokenTTL),
+		CreatedAt: now,
+	}
+}
+
+// deliver emails the link of a recorded invitation. A mail failure is
+// logged and reported as ErrInvitationNotSent.
+func (s *InvitationService) deliver(ctx context.Context, user *models.User, invitation *models.UserInvitation) error {
+	token, err := s.signToken(user, invitation)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, s.invitationMessage(ctx, user, token)); err != nil {
+		s.logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send invitation email")
+		return ErrInvitationNotSent
+	}
+	return nil
+}
+
+func (s *InvitationService) signToken(user *models.User, invitation *models.UserInvitation) (string, error) {
+	claims := &TokenClaims{
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		Email:    user.Email,
+		Purpose:  tokenPurposeInvitation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invitation.ID.String(),
+			ExpiresAt: jwt.NewNumericDate(invitation.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(invitation.CreatedAt),
+			Issuer:    "admin-panel",
+			Subject:   user.ID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secret)
+}
+
+func (s *InvitationService) parseToken(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok || !token.Valid || claims.Purpose != tokenPurposeInvitation {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (s *InvitationService) invitationMessage(ctx context.Context, user *models.User, token string) *mailer.Message {
+	organization := s.settingsService.GetString(ctx, user.TenantID, SettingOrganizationName, "Admin Panel")
+
+	link := s.config.URL + "?" + url.Values{"token": {token}}.Encode()
+	body := fmt.Sprintf(
+		"Hello %s,\n\n"+
+			"You have been invited to join %s. Use the link below to choose your password and activate your account. "+
+			"The link expires in %s.\n\n"+
+			"%s\n\n"+
+			"If you were not expecting this invitation, you can ignore this email.\n",
+		user.FirstName, organization, s.config.TokenTTL, link,
+	)
+
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: "You have been invited to " + organization,
+		Body:    body,
+	}
+}
+
+func (s *InvitationService) logEvent(ctx context.Context, actor AuditContext, action string, userID uuid.UUID) {
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     action,
+		Resource:   "user",
+		ResourceID: &userID,
+	})
+}
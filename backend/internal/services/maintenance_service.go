@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/scheduler"
+)
+
+const (
+	JobSessionPurge          = "session_purge"
+	JobTokenPurge            = "token_purge"
+	JobRotatedSessionCleanup = "rotated_session_cleanup"
+	JobAuditRetention        = "audit_retention"
+	JobAuditCheckpoint       = "audit_checkpoint"
+	JobAuditExports          = "audit_exports"
+	JobAuditForward          = "audit_forward"
+)
+
+// MaintenanceService implements the jobs run by the background scheduler.
+type MaintenanceService struct {
+	sessionRepo    *repository.SessionRepository
+	webauthnRepo   *repository.WebAuthnRepository
+	resetRepo      *repository.PasswordResetRepository
+	auditRetention *AuditRetentionService
+	auditChain     *AuditChainService
+	auditExports   *AuditExportService
+	auditForward   *AuditForwardingService
+	config         config.SchedulerConfig
+}
+
+func NewMaintenanceService(
+	sessionRepo *repository.SessionRepository,
+	webauthnRepo *repository.WebAuthnRepository,
+	resetRepo *repository.PasswordResetRepository,
+	auditRetention *AuditRetentionService,
+	auditChain *AuditChainService,
+	auditExports *AuditExportService,
+	auditForward *AuditForwardingService,
+	cfg config.SchedulerConfig,
+) *MaintenanceService {
+	return &MaintenanceService{
+		sessionRepo:    sessionRepo,
+		webauthnRepo:   webauthnRepo,
+		resetRepo:      resetRepo,
+		auditRetention: auditRetention,
+		auditChain:     auditChain,
+		auditExports:   auditExports,
+		auditForward:   auditForward,
+		config:         cfg,
+	}
+}
+
+// Jobs returns the background jobs with their configured schedules.
+func (s *MaintenanceService) Jobs() []scheduler.Job {
+	return []scheduler.Job{
+		{Name: JobSessionPurge, Schedule: s.config.SessionPurgeSchedule, Run: s.PurgeExpiredSessions},
+		{Name: JobTokenPurge, Schedule: s.config.TokenPurgeSchedule, Run: s.PurgeExpiredTokens},
+		{Name: JobRotatedSessionCleanup, Schedule: s.config.RotatedSessionSchedule, Run: s.CleanupRotatedSessions},
+		{Name: JobAuditRetention, Schedule: s.config.AuditRetentionSchedule, Run: s.auditRetention.Apply},
+		{Name: JobAuditCheckpoint, Schedule: s.config.AuditCheckpointSchedule, Run: s.auditChain.Checkpoint},
+		{Name: JobAuditExports, Schedule: s.config.AuditExportSchedule, Timeout: s.auditExports.jobTimeout(), Run: s.auditExports.Process},
+		{Name: JobAuditForward, Schedule: s.config.AuditForwardSchedule, Run: s.auditForward.Forward},
+	}
+}
+
+// PurgeExpiredSessions deletes refresh tokens past their expiry.
+func (s *MaintenanceService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return s.sessionRepo.DeleteExpired(ctx)
+}
+
+// PurgeExpiredTokens deletes expired WebAuthn ceremonies, which anyone can
+// start through passwordless login, and password reset tokens that were
+// used or have expired.
+func (s *MaintenanceService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	ceremonies, err := s.webauthnRepo.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	resetTokens, err := s.resetRepo.DeleteExpired(ctx)
+	return ceremonies + resetTokens, err
+}
+
+// CleanupRotatedSessions deletes refresh tokens that were rotated or revoked
+// longer ago than the retention window.
+func (s *MaintenanceService) CleanupRotatedSessions(ctx context.Context) (int64, error) {
+	return s.sessionRepo.DeleteRotatedBefore(ctx, time.Now().Add(-s.config.RotatedSessionRetention))
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// minPasswordLength is the floor for every tenant's policy; request
+	// validation already rejects anything shorter.
+	minPasswordLength = 8
+	// maxPasswordHistory bounds how many previous hashes are kept. Each one
+	// costs a bcrypt comparison when a password is changed.
+	maxPasswordHistory = 24
+	// minPersonalInfoLength stops very short names from rejecting most
+	// passwords.
+	minPersonalInfoLength = 3
+)
+
+// ConfigurePasswordHasher selects the hashing scheme for new passwords from
+// configuration.
+func ConfigurePasswordHasher(cfg config.PasswordConfig) error {
+	return utils.SetPasswordHasher(utils.PasswordHasher{
+		Algorithm:  cfg.HashAlgorithm,
+		BcryptCost: cfg.BcryptCost,
+		Argon2: utils.Argon2Params{
+			Memory:      uint32(cfg.Argon2Memory),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+			SaltLength:  utils.DefaultArgon2Params.SaltLength,
+			KeyLength:   utils.DefaultArgon2Params.KeyLength,
+		},
+	})
+}
+
+// PasswordPolicy is a tenant's effective password policy.
+type PasswordPolicy struct {
+	MinLength           int  `json:"min_length"`
+	RequireUppercase    bool `json:"require_uppercase"`
+	RequireLowercase    bool `json:"require_lowercase"`
+	RequireDigit        bool `json:"require_digit"`
+	RequireSymbol       bool `json:"require_symbol"`
+	DisallowPersonal    bool `json:"disallow_personal_info"`
+	HistoryCount        int  `json:"history_count"`
+	MaxAgeDays          int  `json:"max_age_days"`
+	CheckBreached       bool `json:"check_breached"`
+	BreachedListEnabled bool `json:"breached_list_enabled"`
+}
+
+// PasswordPolicyError lists the rules a rejected password broke, keyed by
+// rule with a human readable explanation.
+type PasswordPolicyError struct {
+	Violations map[string]string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for rule := range e.Violations {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return "password does not meet policy: " + strings.Join(rules, ", ")
+}
+
+type PasswordPolicyService struct {
+	settingsService *SettingsService
+	historyRepo     *repository.PasswordHistoryRepository
+	userRepo        *repository.UserRepository
+	breached        *utils.BreachedPasswords
+	logger          zerolog.Logger
+}
+
+func NewPasswordPolicyService(
+	settingsService *SettingsService,
+	historyRepo *repository.PasswordHistoryRepository,
+	userRepo *repository.UserRepository,
+	breached *utils.BreachedPasswords,
+	logger zerolog.Logger,
+) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		settingsService: settingsService,
+		historyRepo:     historyRepo,
+		userRepo:        userRepo,
+		breached:        breached,
+		logger:          logger,
+	}
+}
+
+func (s *PasswordPolicyService) Policy(ctx context.Context, tenantID uuid.UUID) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:           s.settingsService.GetInt(ctx, tenantID, SettingPasswordMinLength, minPasswordLength),
+		RequireUppercase:    s.settingsService.GetBool(ctx, tenantID, SettingPasswordRequireUppercase, false),
+		RequireLowercase:    s.settingsService.GetBool(ctx, tenantID, SettingPasswordRequireLowercase, false),
+		RequireDigit:        s.settingsService.GetBool(ctx, tenantID, SettingPasswordRequireDigit, false),
+		RequireSymbol:       s.settingsService.GetBool(ctx, tenantID, SettingPasswordRequireSymbol, false),
+		DisallowPersonal:    s.settingsService.GetBool(ctx, tenantID, SettingPasswordDisallowPersonalInfo, true),
+		HistoryCount:        s.settingsService.GetInt(ctx, tenantID, SettingPasswordHistoryCount, 0),
+		MaxAgeDays:          s.settingsService.GetInt(ctx, tenantID, SettingPasswordMaxAgeDays, 0),
+		CheckBreached:       s.settingsService.GetBool(ctx, tenantID, SettingPasswordCheckBreached, true),
+		BreachedListEnabled: s.breached != nil,
+	}
+	if policy.MinLength < minPasswordLength {
+		policy.MinLength = minPasswordLength
+	}
+	if policy.HistoryCount < 0 {
+		policy.HistoryCount = 0
+	}
+	if policy.HistoryCount > maxPasswordHistory {
+		policy.HistoryCount = maxPasswordHistory
+	}
+	if policy.MaxAgeDays < 0 {
+		policy.MaxAgeDays = 0
+	}
+	return policy
+}
+
+// Check validates password for user against the tenant's policy. The user
+// need not exist yet; history is only consulted for saved users. It returns
+// a *PasswordPolicyError describing every rule that failed.
+func (s *PasswordPolicyService) Check(ctx context.Context, user *models.User, password string) error {
+	policy := s.Policy(ctx, user.TenantID)
+	violations := make(map[string]string)
+
+	if len([]rune(password)) < policy.MinLength {
+		violations["min_length"] = fmt.Sprintf("must be at least %d characters", policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUppercase && !hasUpper {
+		violations["uppercase"] = "must contain an uppercase letter"
+	}
+	if policy.RequireLowercase && !hasLower {
+		violations["lowercase"] = "must contain a lowercase letter"
+	}
+	if policy.RequireDigit && !hasDigit {
+		violations["digit"] = "must contain a digit"
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violations["symbol"] = "must contain a symbol"
+	}
+
+	if policy.DisallowPersonal && containsPersonalInfo(user, password) {
+		violations["personal_info"] = "must not contain your email address or name"
+	}
+
+	if policy.HistoryCount > 0 && user.ID != uuid.Nil {
+		reused, err := s.reused(ctx, user, password, policy.HistoryCount)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations["reused"] = fmt.Sprintf("must not match any of your last %d passwords", policy.HistoryCount)
+		}
+	}
+
+	if policy.CheckBreached {
+		breached, err := s.breached.Contains(password)
+		if err != nil {
+			// A broken corpus must not lock everyone out of changing
+			// passwords.
+			s.logger.Error().Err(err).Msg("breached password check failed")
+		} else if breached {
+			violations["breached"] = "has appeared in a data breach; choose a different password"
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// SetPassword checks password against the tenant's policy, stores it and
+// records it in the user's password history.
+func (s *PasswordPolicyService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	if err := s.Check(ctx, user, password); err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.TenantID, user.ID, passwordHash); err != nil {
+		return err
+	}
+
+	s.RecordChange(ctx, user.ID, passwordHash)
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+// RecordChange remembers a newly set password hash for reuse checks.
+func (s *PasswordPolicyService) RecordChange(ctx context.Context, userID uuid.UUID, passwordHash string) {
+	if err := s.historyRepo.Add(ctx, userID, passwordHash, maxPasswordHistory); err != nil {
+		s.logger.Error().Err(err).Str("user_id", userID.String()).Msg("failed to record password history")
+	}
+}
+
+// IsExpired reports whether the user's password is older than the tenant's
+// maximum password age.
+func (s *PasswordPolicyService) IsExpired(ctx context.Context, user *models.User) bool {
+	maxAgeDays := s.Policy(ctx, user.TenantID).MaxAgeDays
+	if maxAgeDays == 0 {
+		return false
+	}
+
+	changedAt, err := s.userRepo.GetPasswordChangedAt(ctx, user.TenantID, user.ID)
+	if err != nil {
+		return false
+	}
+	return time.Since(changedAt) > time.Duration(maxAgeDays)*24*time.Hour
+}
+
+// reused compares password with the current hash and the most recent
+// historic ones.
+func (s *PasswordPolicyService) reused(ctx context.Context, user *models.User, password string, count int) (bool, error) {
+	if user.PasswordHash != "" && utils.CheckPasswordHash(password, user.PasswordHash) {
+		return true, nil
+	}
+
+	hashes, err := s.historyRepo.Recent(ctx, user.ID, count)
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		if utils.CheckPasswordHash(password, hash) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsPersonalInfo(user *models.User, password string) bool {
+	lowered := strings.ToLower(password)
+
+	candidates := []string{user.FirstName, user.LastName}
+	if at := strings.IndexByte(user.Email, '@'); at > 0 {
+		candidates = append(candidates, user.Email[:at])
+	}
+
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if len([]rune(candidate)) >= minPersonalInfoLength && strings.Contains(lowered, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/mailer"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/utils"
+
+	"github.com/rs/zerolog"
+)
+
+var ErrInvalidResetToken = errors.New("password reset token is invalid or has expired")
+
+// resetMailTimeout bounds delivery of a reset email, which happens after
+// the request has been answered.
+const resetMailTimeout = 30 * time.Second
+
+type PasswordResetService struct {
+	resetRepo       *repository.PasswordResetRepository
+	userRepo        *repository.UserRepository
+	sessionRepo     *repository.SessionRepository
+	audit           *AuditRecorder
+	tenantService   *TenantService
+	settingsService *SettingsService
+	passwordPolicy  *PasswordPolicyService
+	mailer          mailer.Sender
+	config          config.PasswordResetConfig
+	logger          zerolog.Logger
+}
+
+func NewPasswordResetService(
+	resetRepo *repository.PasswordResetRepository,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	audit *AuditRecorder,
+	tenantService *TenantService,
+	settingsService *SettingsService,
+	passwordPolicy *PasswordPolicyService,
+	sender mailer.Sender,
+	cfg config.PasswordResetConfig,
+	logger zerolog.Logger,
+) *PasswordResetService {
+	return &PasswordResetService{
+		resetRepo:       resetRepo,
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		audit:           audit,
+		tenantService:   tenantService,
+		settingsService: settingsService,
+		passwordPolicy:  passwordPolicy,
+		mailer:          sender,
+		config:          cfg,
+		logger:          logger,
+	}
+}
+
+type ForgotPasswordRequest struct {
+	Tenant string `json:"tenant,omitempty" validate:"omitempty,max=100"`
+	Email  string `json:"email" validate:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+// RequestReset emails a reset link if the address belongs to an active
+// user. It reports nothing back so callers cannot probe which accounts
+// exist; failures are only logged.
+func (s *PasswordResetService) RequestReset(ctx context.Context, req *ForgotPasswordRequest, ipAddress, userAgent string) {
+	tenant, err := s.tenantService.GetBySlug(ctx, req.Tenant)
+	if err != nil || tenant.Status != TenantStatusActive {
+		return
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, tenant.ID, normalizeEmail(req.Email))
+	if err != nil || user.Status != "active" {
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to generate password reset token")
+		return
+	}
+
+	expiresAt := time.Now().Add(s.config.TokenTTL)
+	if err := s.resetRepo.Create(ctx, user.TenantID, user.ID, utils.HashToken(token), expiresAt); err != nil {
+		s.logger.Error().Err(err).Msg("failed to store password reset token")
+		return
+	}
+
+	s.logEvent(ctx, user, "password_reset_requested", ipAddress, userAgent)
+
+	msg := s.resetMessage(ctx, user, token)
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetMailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(sendCtx, msg); err != nil {
+			s.logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send password reset email")
+		}
+	}()
+}
+
+// ConfirmReset sets a new password using a reset token and signs the user
+// out everywhere. The token is only consumed once the new password has
+// passed the tenant's password policy.
+func (s *PasswordResetService) ConfirmReset(ctx context.Context, req *ConfirmPasswordResetRequest, ipAddress, userAgent string) error {
+	tokenHash := utils.HashToken(req.Token)
+	tenantID, userID, err := s.resetRepo.Lookup(ctx, tokenHash)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, tenantID, userID)
+	if err != nil || user.Status != "active" {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.tenantService.EnsureActive(ctx, tenantID); err != nil {
+		return err
+	}
+
+	if err := s.passwordPolicy.Check(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := s.resetRepo.Consume(ctx, tokenHash); err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, tenantID, userID, passwordHash); err != nil {
+		return err
+	}
+	s.passwordPolicy.RecordChange(ctx, userID, passwordHash)
+
+	if err := s.resetRepo.InvalidateForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeByUserID(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, user, "password_reset", ipAddress, userAgent)
+
+	return nil
+}
+
+func (s *PasswordResetService) resetMessage(ctx context.Context, user *models.User, token string) *mailer.Message {
+	organization := s.settingsService.GetString(ctx, user.TenantID, SettingOrganizationName, "Admin Panel")
+
+	link := s.config.URL + "?" + url.Values{"token": {token}}.Encode()
+	body := fmt.Sprintf(
+		"A password reset was requested for your %s account (%s).\n\n"+
+			"Use the link below to choose a new password. It expires in %s and can only be used once.\n\n"+
+			"%s\n\n"+
+			"If you did not request this, you can ignore this email; your password will not change.\n",
+		organization, user.Email, s.config.TokenTTL, link,
+	)
+
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: organization + ": reset your password",
+		Body:    body,
+	}
+}
+
+func (s *PasswordResetService) logEvent(ctx context.Context, user *models.User, action, ipAddress, userAgent string) {
+	actor := AuditContext{
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     action,
+		Resource:   "user",
+		ResourceID: &user.ID,
+	})
+}
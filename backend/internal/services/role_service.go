@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
 
 	"admin-panel/internal/models"
 	"admin-panel/internal/repository"
@@ -11,22 +12,23 @@ import (
 )
 
 var (
+	ErrRoleNotFound   = errors.New("role not found")
 	ErrRoleNameExists = errors.New("role name already exists")
 	ErrSystemRole     = errors.New("cannot modify system role")
 )
 
 type RoleService struct {
-	roleRepo  *repository.RoleRepository
-	auditRepo *repository.AuditLogRepository
+	roleRepo *repository.RoleRepository
+	audit    *AuditRecorder
 }
 
 func NewRoleService(
 	roleRepo *repository.RoleRepository,
-	auditRepo *repository.AuditLogRepository,
+	audit *AuditRecorder,
 ) *RoleService {
 	return &RoleService{
-		roleRepo:  roleRepo,
-		auditRepo: auditRepo,
+		roleRepo: roleRepo,
+		audit:    audit,
 	}
 }
 
@@ -42,7 +44,8 @@ type UpdateRoleRequest struct {
 	PermissionIDs []string `json:"permission_ids,omitempty" validate:"omitempty,dive,uuid"`
 }
 
-func (s *RoleService) Create(ctx context.Context, req *CreateRoleRequest, tenantID uuid.UUID) (*models.Role, error) {
+func (s *RoleService) Create(ctx context.Context, actor AuditContext, req *CreateRoleRequest) (*models.Role, error) {
+	tenantID := actor.TenantID
 	existing, _ := s.roleRepo.GetByName(ctx, tenantID, req.Name)
 	if existing != nil {
 		return nil, ErrRoleNameExists
@@ -65,14 +68,24 @@ func (s *RoleService) Create(ctx context.Context, req *CreateRoleRequest, tenant
 		if err != nil {
 			continue
 		}
-		s.roleRepo.AssignPermissionToRole(ctx, role.ID, permID)
+		s.roleRepo.AssignPermissionToRole(ctx, tenantID, role.ID, permID)
 	}
 
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "create",
+		Resource:   "role",
+		ResourceID: &role.ID,
+		After:      s.snapshot(ctx, role),
+	})
 	return role, nil
 }
 
-func (s *RoleService) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
-	return s.roleRepo.GetByID(ctx, id)
+func (s *RoleService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
 }
 
 func (s *RoleService) List(ctx context.Context, params *models.ListParams) (*models.PaginatedResponse, error) {
@@ -95,8 +108,9 @@ func (s *RoleService) List(ctx context.Context, params *models.ListParams) (*mod
 	}, nil
 }
 
-func (s *RoleService) Update(ctx context.Context, id uuid.UUID, req *UpdateRoleRequest) (*models.Role, error) {
-	role, err := s.roleRepo.GetByID(ctx, id)
+func (s *RoleService) Update(ctx context.Context, actor AuditContext, id uuid.UUID, req *UpdateRoleRequest) (*models.Role, error) {
+	tenantID := actor.TenantID
+	role, err := s.GetByID(ctx, tenantID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +118,7 @@ func (s *RoleService) Update(ctx context.Context, id uuid.UUID, req *UpdateRoleR
 	if role.IsSystem {
 		return nil, ErrSystemRole
 	}
+	before := s.snapshot(ctx, role)
 
 	if req.Name != nil && *req.Name != role.Name {
 		existing, _ := s.roleRepo.GetByName(ctx, role.TenantID, *req.Name)
@@ -122,21 +137,28 @@ func (s *RoleService) Update(ctx context.Context, id uuid.UUID, req *UpdateRoleR
 	}
 
 	if req.PermissionIDs != nil {
-		s.roleRepo.RemoveAllPermissionsFromRole(ctx, id)
+		s.roleRepo.RemoveAllPermissionsFromRole(ctx, tenantID, id)
 		for _, permIDStr := range req.PermissionIDs {
 			permID, err := uuid.Parse(permIDStr)
 			if err != nil {
 				continue
 			}
-			s.roleRepo.AssignPermissionToRole(ctx, id, permID)
+			s.roleRepo.AssignPermissionToRole(ctx, tenantID, id, permID)
 		}
 	}
 
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "update",
+		Resource:   "role",
+		ResourceID: &role.ID,
+		Before:     before,
+		After:      s.snapshot(ctx, role),
+	})
 	return role, nil
 }
 
-func (s *RoleService) Delete(ctx context.Context, id uuid.UUID) error {
-	role, err := s.roleRepo.GetByID(ctx, id)
+func (s *RoleService) Delete(ctx context.Context, actor AuditContext, id uuid.UUID) error {
+	role, err := s.GetByID(ctx, actor.TenantID, id)
 	if err != nil {
 		return err
 	}
@@ -144,14 +166,44 @@ func (s *RoleService) Delete(ctx context.Context, id uuid.UUID) error {
 	if role.IsSystem {
 		return ErrSystemRole
 	}
+	before := s.snapshot(ctx, role)
 
-	return s.roleRepo.Delete(ctx, id)
+	if err := s.roleRepo.Delete(ctx, actor.TenantID, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "delete",
+		Resource:   "role",
+		ResourceID: &id,
+		Before:     before,
+	})
+	return nil
 }
 
 func (s *RoleService) GetAllPermissions(ctx context.Context) ([]*models.Permission, error) {
 	return s.roleRepo.GetAllPermissions(ctx)
 }
 
-func (s *RoleService) GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*models.Permission, error) {
-	return s.roleRepo.GetRolePermissions(ctx, roleID)
+func (s *RoleService) GetRolePermissions(ctx context.Context, tenantID, roleID uuid.UUID) ([]*models.Permission, error) {
+	if _, err := s.GetByID(ctx, tenantID, roleID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetRolePermissions(ctx, tenantID, roleID)
+}
+
+// snapshot captures a role and the names of its permissions for the audit
+// log. The role is copied so later changes to it do not alter the snapshot.
+func (s *RoleService) snapshot(ctx context.Context, role *models.Role) *models.Role {
+	copied := *role
+	copied.Permissions = []string{}
+
+	permissions, err := s.roleRepo.GetRolePermissions(ctx, role.TenantID, role.ID)
+	if err == nil {
+		for _, permission := range permissions {
+			copied.Permissions = append(copied.Permissions, permission.Name)
+		}
+		sort.Strings(copied.Permissions)
+	}
+	return &copied
 }
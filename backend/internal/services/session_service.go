@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionService struct {
+	sessionRepo *repository.SessionRepository
+	userRepo    *repository.UserRepository
+	audit       *AuditRecorder
+}
+
+func NewSessionService(
+	sessionRepo *repository.SessionRepository,
+	userRepo *repository.UserRepository,
+	audit *AuditRecorder,
+) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		audit:       audit,
+	}
+}
+
+// SessionInfo describes one signed-in device or browser. ID stays the same
+// while the session's refresh token is rotated.
+type SessionInfo struct {
+	ID         uuid.UUID `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// List returns the active sessions of a user in the tenant. currentID marks
+// the caller's own session and may be uuid.Nil.
+func (s *SessionService) List(ctx context.Context, tenantID, userID, currentID uuid.UUID) ([]*SessionInfo, error) {
+	if _, err := s.userRepo.GetByID(ctx, tenantID, userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, &SessionInfo{
+			ID:         session.FamilyID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.AuthenticatedAt,
+			LastUsedAt: session.CreatedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    currentID != uuid.Nil && session.FamilyID == currentID,
+		})
+	}
+	return infos, nil
+}
+
+// Revoke signs a user out of one session.
+func (s *SessionService) Revoke(ctx context.Context, actor AuditContext, userID, sessionID uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(ctx, actor.TenantID, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.sessionRepo.RevokeFamily(ctx, userID, sessionID, time.Now()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	s.logEvent(ctx, actor, "session_revoked", userID, map[string]interface{}{"session_id": sessionID})
+	return nil
+}
+
+// RevokeOthers signs the caller out of every session except currentID.
+func (s *SessionService) RevokeOthers(ctx context.Context, actor AuditContext, currentID uuid.UUID) (int64, error) {
+	revoked, err := s.sessionRepo.RevokeOtherFamilies(ctx, actor.UserID, currentID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	s.logEvent(ctx, actor, "sessions_revoked_others", actor.UserID, map[string]interface{}{
+		"kept_session_id": currentID,
+		"revoked":         revoked,
+	})
+	return revoked, nil
+}
+
+// RevokeAll signs a user out of every session.
+func (s *SessionService) RevokeAll(ctx context.Context, actor AuditContext, userID uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(ctx, actor.TenantID, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.sessionRepo.RevokeByUserID(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, actor, "sessions_revoked", userID, nil)
+	return nil
+}
+
+func (s *SessionService) logEvent(ctx context.Context, actor AuditContext, action string, userID uuid.UUID, details map[string]interface{}) {
+	change := AuditChange{
+		Action:     action,
+		Resource:   "user",
+		ResourceID: &userID,
+	}
+	if details != nil {
+		change.After = details
+	}
+	s.audit.Record(ctx, actor, change)
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSettingNotFound     = errors.New("setting not found")
+	ErrInvalidSettingType  = errors.New("invalid setting type")
+	ErrInvalidSettingValue = errors.New("setting value does not match its type")
+)
+
+const (
+	SettingTypeString  = "string"
+	SettingTypeNumber  = "number"
+	SettingTypeBoolean = "boolean"
+	SettingTypeJSON    = "json"
+)
+
+const (
+	SettingOrganizationName      = "organization_name"
+	SettingSessionTimeoutMinutes = "session_timeout_minutes"
+	SettingMaxLoginAttempts      = "max_login_attempts"
+	SettingLoginLockoutMinutes   = "login_lockout_minutes"
+
+	SettingPasswordMinLength            = "password_min_length"
+	SettingPasswordRequireUppercase     = "password_require_uppercase"
+	SettingPasswordRequireLowercase     = "password_require_lowercase"
+	SettingPasswordRequireDigit         = "password_require_digit"
+	SettingPasswordRequireSymbol        = "password_require_symbol"
+	SettingPasswordDisallowPersonalInfo = "password_disallow_personal_info"
+	SettingPasswordHistoryCount         = "password_history_count"
+	SettingPasswordMaxAgeDays           = "password_max_age_days"
+	SettingPasswordCheckBreached        = "password_check_breached"
+
+	SettingAuditRetentionDays = "audit_retention_days"
+)
+
+// declaredSettingTypes pins the type of settings the application itself
+// reads, so they cannot be overwritten with a value of a different type.
+var declaredSettingTypes = map[string]string{
+	SettingOrganizationName:      SettingTypeString,
+	SettingSessionTimeoutMinutes: SettingTypeNumber,
+	SettingMaxLoginAttempts:      SettingTypeNumber,
+	SettingLoginLockoutMinutes:   SettingTypeNumber,
+
+	SettingPasswordMinLength:            SettingTypeNumber,
+	SettingPasswordRequireUppercase:     SettingTypeBoolean,
+	SettingPasswordRequireLowercase:     SettingTypeBoolean,
+	SettingPasswordRequireDigit:         SettingTypeBoolean,
+	SettingPasswordRequireSymbol:        SettingTypeBoolean,
+	SettingPasswordDisallowPersonalInfo: SettingTypeBoolean,
+	SettingPasswordHistoryCount:         SettingTypeNumber,
+	SettingPasswordMaxAgeDays:           SettingTypeNumber,
+	SettingPasswordCheckBreached:        SettingTypeBoolean,
+
+	SettingAuditRetentionDays: SettingTypeNumber,
+}
+
+// integerSettingBounds are the inclusive ranges of the declared whole-number
+// settings. Counts and durations that enforce a control start at 1, since
+// zero or less would disable it; those where zero turns the control off
+// start at 0. The upper bounds keep values within what the controls can use.
+var integerSettingBounds = map[string]struct{ min, max int }{
+	SettingSessionTimeoutMinutes: {1, 43200},
+	SettingMaxLoginAttempts:      {1, 100},
+	SettingLoginLockoutMinutes:   {1, 10080},
+	SettingPasswordMinLength:     {1, 128},
+	SettingPasswordHistoryCount:  {0, 24},
+	SettingPasswordMaxAgeDays:    {0, 3650},
+	SettingAuditRetentionDays:    {0, 36500},
+}
+
+type SettingsService struct {
+	settingsRepo *repository.SettingsRepository
+	audit        *AuditRecorder
+}
+
+func NewSettingsService(
+	settingsRepo *repository.SettingsRepository,
+	audit *AuditRecorder,
+) *SettingsService {
+	return &SettingsService{
+		settingsRepo: settingsRepo,
+		audit:        audit,
+	}
+}
+
+type PutSettingRequest struct {
+	Key   string `json:"key" validate:"required,min=1,max=100"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty" validate:"omitempty,oneof=string number boolean json"`
+}
+
+type BulkUpdateSettingsRequest struct {
+	Settings []PutSettingRequest `json:"settings" validate:"required,min=1,dive"`
+}
+
+func (s *SettingsService) List(ctx context.Context, tenantID uuid.UUID) ([]*models.Setting, error) {
+	settings, err := s.settingsRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = []*models.Setting{}
+	}
+	return settings, nil
+}
+
+func (s *SettingsService) Get(ctx context.Context, tenantID uuid.UUID, key string) (*models.Setting, error) {
+	setting, err := s.settingsRepo.GetByKey(ctx, tenantID, key)
+	if err != nil {
+		return nil, ErrSettingNotFound
+	}
+	return setting, nil
+}
+
+func (s *SettingsService) GetString(ctx context.Context, tenantID uuid.UUID, key, defaultValue string) string {
+	setting, err := s.settingsRepo.GetByKey(ctx, tenantID, key)
+	if err != nil {
+		return defaultValue
+	}
+	return setting.Value
+}
+
+func (s *SettingsService) GetInt(ctx context.Context, tenantID uuid.UUID, key string, defaultValue int) int {
+	setting, err := s.settingsRepo.GetByKey(ctx, tenantID, key)
+	if err != nil || setting.Type != SettingTypeNumber {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(setting.Value, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return defaultValue
+	}
+	// Values stored before their bounds were enforced may not fit.
+	if bounds, ok := integerSettingBounds[key]; ok && (value < float64(bounds.min) || value > float64(bounds.max)) {
+		return defaultValue
+	}
+	return int(value)
+}
+
+// GetMinutes reads a positive number-of-minutes setting, falling back to
+// defaultValue when it is unset or not positive.
+func (s *SettingsService) GetMinutes(ctx context.Context, tenantID uuid.UUID, key string, defaultValue time.Duration) time.Duration {
+	minutes := s.GetInt(ctx, tenantID, key, 0)
+	if minutes <= 0 {
+		return defaultValue
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (s *SettingsService) GetBool(ctx context.Context, tenantID uuid.UUID, key string, defaultValue bool) bool {
+	setting, err := s.settingsRepo.GetByKey(ctx, tenantID, key)
+	if err != nil || setting.Type != SettingTypeBoolean {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(setting.Value)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func (s *SettingsService) Put(ctx context.Context, req *PutSettingRequest, actor AuditContext) (*models.Setting, error) {
+	settings, err := s.BulkUpdate(ctx, &BulkUpdateSettingsRequest{
+		Settings: []PutSettingRequest{*req},
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+	return settings[0], nil
+}
+
+// BulkUpdate writes settings for the actor's tenant.
+func (s *SettingsService) BulkUpdate(ctx context.Context, req *BulkUpdateSettingsRequest, actor AuditContext) ([]*models.Setting, error) {
+	tenantID := actor.TenantID
+	previous := make([]*models.Setting, len(req.Settings))
+	updated := make([]*models.Setting, len(req.Settings))
+
+	for i, item := range req.Settings {
+		existing, _ := s.settingsRepo.GetByKey(ctx, tenantID, item.Key)
+
+		settingType, err := resolveSettingType(item.Key, item.Type, existing)
+		if err != nil {
+			return nil, err
+		}
+		value, err := normalizeSettingValue(settingType, item.Value)
+		if err != nil {
+			return nil, err
+		}
+		if bounds, ok := integerSettingBounds[item.Key]; ok {
+			if n, err := strconv.Atoi(value); err != nil || n < bounds.min || n > bounds.max {
+				return nil, ErrInvalidSettingValue
+			}
+		}
+
+		setting := &models.Setting{
+			TenantID: tenantID,
+			Key:      item.Key,
+			Value:    value,
+			Type:     settingType,
+		}
+		if existing != nil {
+			setting.ID = existing.ID
+		}
+
+		previous[i] = existing
+		updated[i] = setting
+	}
+
+	if err := s.settingsRepo.BulkUpsert(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	for i, setting := range updated {
+		change := AuditChange{
+			Action:     "create",
+			Resource:   "setting",
+			ResourceID: &setting.ID,
+			After:      settingAuditValue(setting),
+		}
+		if previous[i] != nil {
+			change.Action = "update"
+			change.Before = settingAuditValue(previous[i])
+		}
+		s.audit.Record(ctx, actor, change)
+	}
+
+	return updated, nil
+}
+
+func resolveSettingType(key, requested string, existing *models.Setting) (string, error) {
+	settingType := requested
+	if settingType == "" && existing != nil {
+		settingType = existing.Type
+	}
+	if declared, ok := declaredSettingTypes[key]; ok {
+		if settingType != "" && settingType != declared {
+			return "", ErrInvalidSettingType
+		}
+		settingType = declared
+	}
+	if settingType == "" {
+		settingType = SettingTypeString
+	}
+
+	switch settingType {
+	case SettingTypeString, SettingTypeNumber, SettingTypeBoolean, SettingTypeJSON:
+		return settingType, nil
+	default:
+		return "", ErrInvalidSettingType
+	}
+}
+
+// normalizeSettingValue validates value against settingType and returns the
+// canonical string form stored in the settings table.
+func normalizeSettingValue(settingType, value string) (string, error) {
+	switch settingType {
+	case SettingTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return "", ErrInvalidSettingValue
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case SettingTypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", ErrInvalidSettingValue
+		}
+		return strconv.FormatBool(b), nil
+	case SettingTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return "", ErrInvalidSettingValue
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// settingAuditValue is the audit snapshot of a setting. The recorder
+// redacts by field name, which cannot tell that a setting's value is
+// sensitive, so values of sensitive keys are redacted here.
+func settingAuditValue(setting *models.Setting) map[string]string {
+	value := setting.Value
+	if isSensitiveAuditField(setting.Key) {
+		value = redactedValue
+	}
+	return map[string]string{
+		"key":   setting.Key,
+		"value": value,
+		"type":  setting.Type,
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTenantNotFound   = errors.New("tenant not found")
+	ErrTenantSlugExists = errors.New("tenant slug already exists")
+	ErrInvalidSlug      = errors.New("invalid tenant slug")
+	ErrTenantInactive   = errors.New("tenant is not active")
+	ErrPlatformTenant   = errors.New("cannot deactivate the platform tenant")
+)
+
+// PlatformTenantID is the tenant seeded by the initial migration. Its users
+// are the only ones allowed to hold platform-level permissions.
+var PlatformTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+const (
+	TenantStatusActive    = "active"
+	TenantStatusInactive  = "inactive"
+	TenantStatusSuspended = "suspended"
+)
+
+// platformPermissionResources are never granted to roles created for new
+// tenants.
+var platformPermissionResources = map[string]bool{
+	"tenants": true,
+	"jobs":    true,
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+type TenantService struct {
+	tenantRepo *repository.TenantRepository
+	roleRepo   *repository.RoleRepository
+	audit      *AuditRecorder
+}
+
+func NewTenantService(
+	tenantRepo *repository.TenantRepository,
+	roleRepo *repository.RoleRepository,
+	audit *AuditRecorder,
+) *TenantService {
+	return &TenantService{
+		tenantRepo: tenantRepo,
+		roleRepo:   roleRepo,
+		audit:      audit,
+	}
+}
+
+type CreateTenantRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=255"`
+	Slug string `json:"slug" validate:"required,min=2,max=100"`
+}
+
+type UpdateTenantRequest struct {
+	Name   *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Slug   *string `json:"slug,omitempty" validate:"omitempty,min=2,max=100"`
+	Status *string `json:"status,omitempty" validate:"omitempty,oneof=active inactive suspended"`
+}
+
+func (s *TenantService) Create(ctx context.Context, req *CreateTenantRequest, actor AuditContext) (*models.Tenant, error) {
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	existing, _ := s.tenantRepo.GetBySlug(ctx, req.Slug)
+	if existing != nil {
+		return nil, ErrTenantSlugExists
+	}
+
+	tenant := &models.Tenant{
+		ID:     uuid.New(),
+		Name:   req.Name,
+		Slug:   req.Slug,
+		Status: TenantStatusActive,
+	}
+
+	if err := s.createWithAdminRole(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	s.logChange(ctx, actor, "create", tenant.ID, nil, tenant)
+
+	return tenant, nil
+}
+
+// createWithAdminRole creates a tenant together with a system role holding
+// every tenant-level permission, so its first administrator can be
+// assigned.
+func (s *TenantService) createWithAdminRole(ctx context.Context, tenant *models.Tenant) error {
+	permissions, err := s.roleRepo.GetAllPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	var permissionIDs []uuid.UUID
+	for _, perm := range permissions {
+		if !platformPermissionResources[perm.Resource] {
+			permissionIDs = append(permissionIDs, perm.ID)
+		}
+	}
+
+	role := &models.Role{
+		ID:          uuid.New(),
+		Name:        "Admin",
+		Description: "Full tenant access",
+		IsSystem:    true,
+	}
+	return s.tenantRepo.CreateWithRole(ctx, tenant, role, permissionIDs)
+}
+
+func (s *TenantService) GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+	return tenant, nil
+}
+
+func (s *TenantService) GetBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetBySlug(ctx, strings.ToLower(strings.TrimSpace(slug)))
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+	return tenant, nil
+}
+
+func (s *TenantService) List(ctx context.Context, params *models.ListParams) (*models.PaginatedResponse, error) {
+	tenants, total, err := s.tenantRepo.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int(total) / params.PerPage
+	if int(total)%params.PerPage > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedResponse{
+		Data:       tenants,
+		Total:      total,
+		Page:       params.Page,
+		PerPage:    params.PerPage,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func (s *TenantService) Update(ctx context.Context, id uuid.UUID, req *UpdateTenantRequest, actor AuditContext) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+	before := *tenant
+
+	if req.Slug != nil && *req.Slug != tenant.Slug {
+		if !slugPattern.MatchString(*req.Slug) {
+			return nil, ErrInvalidSlug
+		}
+		existing, _ := s.tenantRepo.GetBySlug(ctx, *req.Slug)
+		if existing != nil {
+			return nil, ErrTenantSlugExists
+		}
+		tenant.Slug = *req.Slug
+	}
+
+	if req.Name != nil {
+		tenant.Name = *req.Name
+	}
+
+	if req.Status != nil && *req.Status != tenant.Status {
+		if tenant.ID == PlatformTenantID && *req.Status != TenantStatusActive {
+			return nil, ErrPlatformTenant
+		}
+		tenant.Status = *req.Status
+	}
+
+	if err := s.tenantRepo.Update(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	s.logChange(ctx, actor, "update", tenant.ID, &before, tenant)
+
+	return tenant, nil
+}
+
+func (s *TenantService) Suspend(ctx context.Context, id uuid.UUID, actor AuditContext) (*models.Tenant, error) {
+	return s.setStatus(ctx, id, TenantStatusSuspended, "suspend", actor)
+}
+
+func (s *TenantService) Activate(ctx context.Context, id uuid.UUID, actor AuditContext) (*models.Tenant, error) {
+	return s.setStatus(ctx, id, TenantStatusActive, "activate", actor)
+}
+
+func (s *TenantService) setStatus(ctx context.Context, id uuid.UUID, status, action string, actor AuditContext) (*models.Tenant, error) {
+	if id == PlatformTenantID && status != TenantStatusActive {
+		return nil, ErrPlatformTenant
+	}
+
+	tenant, err := s.tenantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+	before := *tenant
+
+	tenant.Status = status
+	if err := s.tenantRepo.Update(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	s.logChange(ctx, actor, action, tenant.ID, &before, tenant)
+
+	return tenant, nil
+}
+
+// EnsureActive returns ErrTenantInactive unless the tenant exists and is
+// active.
+func (s *TenantService) EnsureActive(ctx context.Context, id uuid.UUID) error {
+	status, err := s.tenantRepo.GetStatus(ctx, id)
+	if err != nil || status != TenantStatusActive {
+		return ErrTenantInactive
+	}
+	return nil
+}
+
+func (s *TenantService) logChange(ctx context.Context, actor AuditContext, action string, tenantID uuid.UUID, before, after *models.Tenant) {
+	change := AuditChange{
+		Action:     action,
+		Resource:   "tenant",
+		ResourceID: &tenantID,
+	}
+	// Assigned separately so a nil tenant stays a nil snapshot rather than
+	// a typed nil interface.
+	if before != nil {
+		change.Before = before
+	}
+	if after != nil {
+		change.After = after
+	}
+	s.audit.Record(ctx, actor, change)
+}
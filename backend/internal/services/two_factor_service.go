@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication has not been enrolled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+const recoveryCodeCount = 10
+
+type TwoFactorService struct {
+	twoFactorRepo   *repository.TwoFactorRepository
+	userRepo        *repository.UserRepository
+	audit           *AuditRecorder
+	settingsService *SettingsService
+}
+
+func NewTwoFactorService(
+	twoFactorRepo *repository.TwoFactorRepository,
+	userRepo *repository.UserRepository,
+	audit *AuditRecorder,
+	settingsService *SettingsService,
+) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo:   twoFactorRepo,
+		userRepo:        userRepo,
+		audit:           audit,
+		settingsService: settingsService,
+	}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"`
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID uuid.UUID) (*TwoFactorStatus, error) {
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return &TwoFactorStatus{}, nil
+	}
+
+	status := &TwoFactorStatus{
+		Enabled:     totp.Enabled,
+		Pending:     !totp.Enabled,
+		ConfirmedAt: totp.ConfirmedAt,
+	}
+	if totp.Enabled {
+		status.RecoveryCodesRemaining, err = s.twoFactorRepo.CountUnusedRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// IsEnabled reports whether login for the user requires a second factor.
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID uuid.UUID) bool {
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	return err == nil && totp.Enabled
+}
+
+// Enroll starts TOTP enrollment for the acting user. The secret only takes
+// effect once Confirm is called with a code generated from it.
+func (s *TwoFactorService) Enroll(ctx context.Context, actor AuditContext) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, actor.TenantID, actor.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if s.IsEnabled(ctx, user.ID) {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SavePendingTOTP(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, actor, "two_factor_enroll", user.ID)
+
+	issuer := s.settingsService.GetString(ctx, user.TenantID, SettingOrganizationName, "Admin Panel")
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables a pending enrollment and returns freshly generated
+// recovery codes. The plaintext codes are only ever returned here.
+func (s *TwoFactorService) Confirm(ctx context.Context, actor AuditContext, code string) ([]string, error) {
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, actor.UserID)
+	if err != nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if totp.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		s.logEvent(ctx, actor, "two_factor_failed", actor.UserID)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.EnableTOTP(ctx, actor.UserID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, actor, "two_factor_enabled", actor.UserID)
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication for the acting user after
+// checking a current code or recovery code.
+func (s *TwoFactorService) Disable(ctx context.Context, actor AuditContext, code string) error {
+	if err := s.verify(ctx, actor, code); err != nil {
+		return err
+	}
+
+	if err := s.twoFactorRepo.Delete(ctx, actor.UserID); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, actor, "two_factor_disabled", actor.UserID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the acting user's recovery codes after
+// checking a current code.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, actor AuditContext, code string) ([]string, error) {
+	if err := s.verify(ctx, actor, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, actor.UserID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, actor, "two_factor_recovery_codes_regenerated", actor.UserID)
+
+	return codes, nil
+}
+
+// Reset removes another user's two-factor enrollment, for administrators
+// helping a user who lost their device and recovery codes.
+func (s *TwoFactorService) Reset(ctx context.Context, actor AuditContext, userID uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(ctx, actor.TenantID, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.twoFactorRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logEvent(ctx, actor, "two_factor_reset", userID)
+	return nil
+}
+
+// VerifyLogin checks the second factor presented during login. It accepts
+// either a TOTP code or an unused recovery code.
+func (s *TwoFactorService) VerifyLogin(ctx context.Context, user *models.User, code, ipAddress, userAgent string) error {
+	return s.verify(ctx, AuditContext{
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}, code)
+}
+
+func (s *TwoFactorService) verify(ctx context.Context, actor AuditContext, code string) error {
+	code = strings.TrimSpace(code)
+	totp, err := s.twoFactorRepo.GetTOTP(ctx, actor.UserID)
+	if err != nil || !totp.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if len(code) == utils.TOTPDigits {
+		step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+		if ok && s.twoFactorRepo.MarkStepUsed(ctx, actor.UserID, step) == nil {
+			return nil
+		}
+	} else if s.twoFactorRepo.UseRecoveryCode(ctx, actor.UserID, utils.HashRecoveryCode(code)) == nil {
+		s.logEvent(ctx, actor, "two_factor_recovery_code_used", actor.UserID)
+		return nil
+	}
+
+	s.logEvent(ctx, actor, "two_factor_failed", actor.UserID)
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *TwoFactorService) logEvent(ctx context.Context, actor AuditContext, action string, userID uuid.UUID) {
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     action,
+		Resource:   "user",
+		ResourceID: &userID,
+	})
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
@@ -3,42 +3,56 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 
 	"admin-panel/internal/models"
 	"admin-panel/internal/repository"
 	"admin-panel/internal/utils"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 var (
 	ErrEmailExists = errors.New("email already exists")
 )
 
+const (
+	UserStatusActive  = "active"
+	UserStatusPending = "pending"
+)
+
 type UserService struct {
-	userRepo  *repository.UserRepository
-	roleRepo  *repository.RoleRepository
-	auditRepo *repository.AuditLogRepository
+	userRepo       *repository.UserRepository
+	roleRepo       *repository.RoleRepository
+	audit          *AuditRecorder
+	passwordPolicy *PasswordPolicyService
 }
 
 func NewUserService(
 	userRepo *repository.UserRepository,
 	roleRepo *repository.RoleRepository,
-	auditRepo *repository.AuditLogRepository,
+	audit *AuditRecorder,
+	passwordPolicy *PasswordPolicyService,
 ) *UserService {
 	return &UserService{
-		userRepo:  userRepo,
-		roleRepo:  roleRepo,
-		auditRepo: auditRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		audit:          audit,
+		passwordPolicy: passwordPolicy,
 	}
 }
 
 type CreateUserRequest struct {
 	Email     string    `json:"email" validate:"required,email"`
-	Password  string    `json:"password" validate:"required,min=8"`
+	Password  string    `json:"password" validate:"required_unless=Invite true,omitempty,min=8"`
 	FirstName string    `json:"first_name" validate:"required,min=1,max=100"`
 	LastName  string    `json:"last_name" validate:"required,min=1,max=100"`
 	RoleIDs   []string  `json:"role_ids" validate:"dive,uuid"`
+	// Invite creates the user as pending and emails them a link to choose
+	// their own password instead of using Password.
+	Invite    bool      `json:"invite"`
 }
 
 type UpdateUserRequest struct {
@@ -53,44 +67,88 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
-func (s *UserService) Create(ctx context.Context, req *CreateUserRequest, tenantID uuid.UUID) (*models.User, error) {
-	existing, _ := s.userRepo.GetByEmail(ctx, req.Email)
-	if existing != nil {
-		return nil, ErrEmailExists
-	}
+// userAuditSnapshot is the audited state of a user: the user record and
+// the names of its roles.
+type userAuditSnapshot struct {
+	*models.User
+	Roles []string `json:"roles"`
+}
 
-	passwordHash, err := utils.HashPassword(req.Password)
+func (s *UserService) Create(ctx context.Context, actor AuditContext, req *CreateUserRequest) (*models.User, error) {
+	user, err := s.newUser(ctx, actor.TenantID, req, UserStatusActive)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.finishCreate(ctx, actor, user, req.RoleIDs)
+	return user, nil
+}
+
+// newUser validates req and builds a user with the given status in
+// tenantID, ready to be inserted. Pending users are created without a
+// usable password; they choose one when accepting an invitation.
+func (s *UserService) newUser(ctx context.Context, tenantID uuid.UUID, req *CreateUserRequest, status string) (*models.User, error) {
+	email := normalizeEmail(req.Email)
+	existing, _ := s.userRepo.GetByEmail(ctx, tenantID, email)
+	if existing != nil {
+		return nil, ErrEmailExists
+	}
+
 	user := &models.User{
-		ID:           uuid.New(),
-		TenantID:     tenantID,
-		Email:        req.Email,
-		PasswordHash: passwordHash,
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		Status:       "active",
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Email:     email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Status:    status,
 	}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, err
+	if status != UserStatusPending {
+		if err := s.passwordPolicy.Check(ctx, user, req.Password); err != nil {
+			return nil, err
+		}
+		passwordHash, err := utils.HashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = passwordHash
+	}
+	return user, nil
+}
+
+// finishCreate records the password of a just-inserted user, assigns its
+// roles and audits its creation.
+func (s *UserService) finishCreate(ctx context.Context, actor AuditContext, user *models.User, roleIDs []string) {
+	if user.PasswordHash != "" {
+		s.passwordPolicy.RecordChange(ctx, user.ID, user.PasswordHash)
 	}
 
-	for _, roleIDStr := range req.RoleIDs {
+	for _, roleIDStr := range roleIDs {
 		roleID, err := uuid.Parse(roleIDStr)
 		if err != nil {
 			continue
 		}
-		s.roleRepo.AssignRoleToUser(ctx, user.ID, roleID)
+		s.roleRepo.AssignRoleToUser(ctx, user.TenantID, user.ID, roleID)
 	}
 
-	return user, nil
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "create",
+		Resource:   "user",
+		ResourceID: &user.ID,
+		After:      s.snapshot(ctx, user),
+	})
 }
 
-func (s *UserService) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	return s.userRepo.GetByID(ctx, id)
+func (s *UserService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
 
 func (s *UserService) List(ctx context.Context, params *models.ListParams) (*models.PaginatedResponse, error) {
@@ -113,18 +171,23 @@ func (s *UserService) List(ctx context.Context, params *models.ListParams) (*mod
 	}, nil
 }
 
-func (s *UserService) Update(ctx context.Context, id uuid.UUID, req *UpdateUserRequest) (*models.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
+func (s *UserService) Update(ctx context.Context, actor AuditContext, id uuid.UUID, req *UpdateUserRequest) (*models.User, error) {
+	tenantID := actor.TenantID
+	user, err := s.GetByID(ctx, tenantID, id)
 	if err != nil {
 		return nil, err
 	}
+	before := s.snapshot(ctx, user)
 
-	if req.Email != nil && *req.Email != user.Email {
-		existing, _ := s.userRepo.GetByEmail(ctx, *req.Email)
-		if existing != nil {
-			return nil, ErrEmailExists
+	if req.Email != nil {
+		email := normalizeEmail(*req.Email)
+		if email != user.Email {
+			existing, _ := s.userRepo.GetByEmail(ctx, user.TenantID, email)
+			if existing != nil {
+				return nil, ErrEmailExists
+			}
+			user.Email = email
 		}
-		user.Email = *req.Email
 	}
 
 	if req.FirstName != nil {
@@ -142,32 +205,174 @@ func (s *UserService) Update(ctx context.Context, id uuid.UUID, req *UpdateUserR
 	}
 
 	if req.RoleIDs != nil {
-		s.roleRepo.RemoveAllRolesFromUser(ctx, id)
+		s.roleRepo.RemoveAllRolesFromUser(ctx, tenantID, id)
 		for _, roleIDStr := range req.RoleIDs {
 			roleID, err := uuid.Parse(roleIDStr)
 			if err != nil {
 				continue
 			}
-			s.roleRepo.AssignRoleToUser(ctx, id, roleID)
+			s.roleRepo.AssignRoleToUser(ctx, tenantID, id, roleID)
 		}
 	}
 
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "update",
+		Resource:   "user",
+		ResourceID: &user.ID,
+		Before:     before,
+		After:      s.snapshot(ctx, user),
+	})
 	return user, nil
 }
 
-func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.userRepo.Delete(ctx, id)
+func (s *UserService) Delete(ctx context.Context, actor AuditContext, id uuid.UUID) error {
+	user, err := s.GetByID(ctx, actor.TenantID, id)
+	if err != nil {
+		return err
+	}
+	before := s.snapshot(ctx, user)
+
+	if err := s.userRepo.Delete(ctx, actor.TenantID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "delete",
+		Resource:   "user",
+		ResourceID: &id,
+		Before:     before,
+	})
+	return nil
 }
 
-func (s *UserService) ResetPassword(ctx context.Context, id uuid.UUID, req *ResetPasswordRequest) error {
-	passwordHash, err := utils.HashPassword(req.NewPassword)
+func (s *UserService) ResetPassword(ctx context.Context, actor AuditContext, id uuid.UUID, req *ResetPasswordRequest) error {
+	user, err := s.GetByID(ctx, actor.TenantID, id)
 	if err != nil {
 		return err
 	}
 
-	return s.userRepo.UpdatePassword(ctx, id, passwordHash)
+	if err := s.passwordPolicy.SetPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "reset_password",
+		Resource:   "user",
+		ResourceID: &id,
+	})
+	return nil
+}
+
+// PasswordHashScheme counts users whose password hash uses one algorithm
+// and set of parameters.
+type PasswordHashScheme struct {
+	Algorithm string `json:"algorithm"`
+	Params    string `json:"params,omitempty"`
+	Users     int64  `json:"users"`
+	Current   bool   `json:"current"`
+}
+
+// PasswordHashReport shows how far a tenant's users have been migrated to
+// the configured hashing scheme. Legacy hashes are upgraded as their owners
+// sign in.
+type PasswordHashReport struct {
+	Algorithm string                `json:"algorithm"`
+	Params    string                `json:"params"`
+	Total     int64                 `json:"total"`
+	Current   int64                 `json:"current"`
+	Legacy    int64                 `json:"legacy"`
+	Schemes   []*PasswordHashScheme `json:"schemes"`
+}
+
+func (s *UserService) PasswordHashReport(ctx context.Context, tenantID uuid.UUID) (*PasswordHashReport, error) {
+	counts, err := s.userRepo.CountByPasswordHashScheme(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PasswordHashReport{Schemes: []*PasswordHashScheme{}}
+	report.Algorithm, report.Params = utils.CurrentPasswordHashScheme()
+
+	schemes := make(map[string]*PasswordHashScheme)
+	for header, count := range counts {
+		algorithm, params := utils.DescribePasswordHash(header)
+		key := algorithm + ":" + params
+		scheme, ok := schemes[key]
+		if !ok {
+			scheme = &PasswordHashScheme{
+				Algorithm: algorithm,
+				Params:    params,
+				Current:   algorithm == report.Algorithm && params == report.Params,
+			}
+			schemes[key] = scheme
+			report.Schemes = append(report.Schemes, scheme)
+		}
+		scheme.Users += count
+
+		report.Total += count
+		if scheme.Current {
+			report.Current += count
+		} else {
+			report.Legacy += count
+		}
+	}
+
+	sort.Slice(report.Schemes, func(i, j int) bool {
+		return report.Schemes[i].Users > report.Schemes[j].Users
+	})
+	return report, nil
+}
+
+// Unlock lifts a login lockout and clears the user's failed attempts and
+// lockout backoff.
+func (s *UserService) Unlock(ctx context.Context, actor AuditContext, id uuid.UUID) error {
+	user, err := s.GetByID(ctx, actor.TenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.ResetFailedLogins(ctx, actor.TenantID, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     "user_unlocked",
+		Resource:   "user",
+		ResourceID: &id,
+		Before:     map[string]interface{}{"locked_until": user.LockedUntil},
+		After:      map[string]interface{}{"locked_until": nil},
+	})
+	return nil
+}
+
+func (s *UserService) GetUserRoles(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.Role, error) {
+	if _, err := s.GetByID(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetUserRoles(ctx, tenantID, userID)
+}
+
+// snapshot captures a user and its role names for the audit log. The user
+// is copied so later changes to it do not alter the snapshot.
+func (s *UserService) snapshot(ctx context.Context, user *models.User) *userAuditSnapshot {
+	copied := *user
+	snapshot := &userAuditSnapshot{User: &copied, Roles: []string{}}
+
+	roles, err := s.roleRepo.GetUserRoles(ctx, user.TenantID, user.ID)
+	if err == nil {
+		for _, role := range roles {
+			snapshot.Roles = append(snapshot.Roles, role.Name)
+		}
+		sort.Strings(snapshot.Roles)
+	}
+	return snapshot
 }
 
-func (s *UserService) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*models.Role, error) {
-	return s.roleRepo.GetUserRoles(ctx, userID)
+// normalizeEmail lowercases and trims an email so lookups match the form
+// used at login.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
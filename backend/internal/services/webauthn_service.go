@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"admin-panel/internal/config"
+	"admin-panel/internal/models"
+	"admin-panel/internal/repository"
+	"admin-panel/internal/webauthn"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebAuthnNotRegistered      = errors.New("no passkeys are registered")
+	ErrWebAuthnCredentialNotFound = errors.New("passkey not found")
+	ErrWebAuthnSessionInvalid     = errors.New("passkey ceremony has expired or is unknown")
+	ErrWebAuthnVerification       = errors.New("passkey verification failed")
+)
+
+const (
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+	webAuthnCeremonyPasswordless = "passwordless"
+)
+
+type WebAuthnService struct {
+	webauthnRepo *repository.WebAuthnRepository
+	userRepo     *repository.UserRepository
+	audit        *AuditRecorder
+	rp           *webauthn.Config
+	timeout      time.Duration
+}
+
+func NewWebAuthnService(
+	webauthnRepo *repository.WebAuthnRepository,
+	userRepo *repository.UserRepository,
+	audit *AuditRecorder,
+	cfg config.WebAuthnConfig,
+) *WebAuthnService {
+	return &WebAuthnService{
+		webauthnRepo: webauthnRepo,
+		userRepo:     userRepo,
+		audit:        audit,
+		rp: &webauthn.Config{
+			RPID:    cfg.RPID,
+			RPName:  cfg.RPName,
+			Origins: cfg.Origins,
+			Timeout: int(cfg.Timeout.Milliseconds()),
+		},
+		timeout: cfg.Timeout,
+	}
+}
+
+// WebAuthnRegistrationOptions is returned when a registration ceremony
+// starts. The session ID must be sent back with the authenticator response.
+type WebAuthnRegistrationOptions struct {
+	SessionID uuid.UUID                 `json:"session_id"`
+	PublicKey *webauthn.CreationOptions `json:"publicKey"`
+}
+
+type WebAuthnAssertionOptions struct {
+	SessionID uuid.UUID                `json:"session_id"`
+	PublicKey *webauthn.RequestOptions `json:"publicKey"`
+}
+
+type WebAuthnRegistrationRequest struct {
+	SessionID  uuid.UUID                    `json:"session_id" validate:"required"`
+	Name       string                       `json:"name" validate:"max=100"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type WebAuthnAssertionRequest struct {
+	SessionID  uuid.UUID                  `json:"session_id" validate:"required"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+// HasCredentials reports whether the user can use a passkey as a second
+// factor.
+func (s *WebAuthnService) HasCredentials(ctx context.Context, userID uuid.UUID) bool {
+	exists, err := s.webauthnRepo.HasCredentials(ctx, userID)
+	return err == nil && exists
+}
+
+// BeginRegistration starts registering a new passkey for the acting user.
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, actor AuditContext) (*WebAuthnRegistrationOptions, error) {
+	user, err := s.userRepo.GetByID(ctx, actor.TenantID, actor.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	creds, err := s.webauthnRepo.ListByUser(ctx, user.TenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.newSession(ctx, user.TenantID, &user.ID, webAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := webauthn.UserEntity{
+		ID:          webauthn.EncodeBase64URL(user.ID[:]),
+		Name:        user.Email,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}
+	if entity.DisplayName == "" {
+		entity.DisplayName = user.Email
+	}
+
+	return &WebAuthnRegistrationOptions{
+		SessionID: session.ID,
+		PublicKey: s.rp.CreationOptions(session.Challenge, entity, descriptors(creds)),
+	}, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation and stores
+// the new credential.
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, actor AuditContext, req *WebAuthnRegistrationRequest) (*models.WebAuthnCredential, error) {
+	session, err := s.webauthnRepo.ConsumeSession(ctx, req.SessionID, webAuthnCeremonyRegistration)
+	if err != nil || session.TenantID != actor.TenantID || session.UserID == nil || *session.UserID != actor.UserID {
+		return nil, ErrWebAuthnSessionInvalid
+	}
+
+	verified, err := s.rp.VerifyRegistration(&req.Credential, session.Challenge, false)
+	if err != nil {
+		s.logEvent(ctx, actor, "webauthn_failed", actor.UserID)
+		return nil, ErrWebAuthnVerification
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	transports := req.Credential.Response.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	cred := &models.WebAuthnCredential{
+		ID:           uuid.New(),
+		UserID:       actor.UserID,
+		CredentialID: verified.ID,
+		PublicKey:    verified.PublicKey,
+		Algorithm:    verified.Algorithm,
+		SignCount:    verified.SignCount,
+		Transports:   transports,
+		AAGUID:       verified.AAGUID,
+		Name:         name,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.webauthnRepo.CreateCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	s.logEvent(ctx, actor, "webauthn_registered", actor.UserID)
+
+	return cred, nil
+}
+
+func (s *WebAuthnService) ListCredentials(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	creds, err := s.webauthnRepo.ListByUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = []*models.WebAuthnCredential{}
+	}
+	return creds, nil
+}
+
+// RevokeCredential removes one of userID's passkeys. Users revoke their own;
+// administrators may revoke any user's in their tenant.
+func (s *WebAuthnService) RevokeCredential(ctx context.Context, actor AuditContext, userID, credentialID uuid.UUID) error {
+	if err := s.webauthnRepo.DeleteCredential(ctx, actor.TenantID, userID, credentialID); err != nil {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	s.logEvent(ctx, actor, "webauthn_revoked", userID)
+	return nil
+}
+
+// BeginLogin starts an assertion restricted to the user's registered
+// passkeys, for use as a second factor after the password step.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, user *models.User) (*WebAuthnAssertionOptions, error) {
+	creds, err := s.webauthnRepo.ListByUser(ctx, user.TenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds) == 0 {
+		return nil, ErrWebAuthnNotRegistered
+	}
+
+	session, err := s.newSession(ctx, user.TenantID, &user.ID, webAuthnCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnAssertionOptions{
+		SessionID: session.ID,
+		PublicKey: s.rp.RequestOptions(session.Challenge, descriptors(creds), "preferred"),
+	}, nil
+}
+
+// FinishLogin verifies a second-factor assertion for user.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, user *models.User, req *WebAuthnAssertionRequest, ipAddress, userAgent string) error {
+	session, err := s.webauthnRepo.ConsumeSession(ctx, req.SessionID, webAuthnCeremonyLogin)
+	if err != nil || session.UserID == nil || *session.UserID != user.ID {
+		return ErrWebAuthnSessionInvalid
+	}
+
+	actor := AuditContext{TenantID: user.TenantID, UserID: user.ID, IPAddress: ipAddress, UserAgent: userAgent}
+	if _, err := s.verifyAssertion(ctx, actor, session, &req.Credential, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BeginPasswordless starts a discoverable-credential assertion for the
+// tenant. The browser lets the user pick any passkey they hold for it.
+func (s *WebAuthnService) BeginPasswordless(ctx context.Context, tenantID uuid.UUID) (*WebAuthnAssertionOptions, error) {
+	session, err := s.newSession(ctx, tenantID, nil, webAuthnCeremonyPasswordless)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnAssertionOptions{
+		SessionID: session.ID,
+		PublicKey: s.rp.RequestOptions(session.Challenge, nil, "required"),
+	}, nil
+}
+
+// FinishPasswordless verifies a passwordless assertion and returns the user
+// it identifies. User verification is required, so the passkey stands in
+// for both the password and the second factor.
+func (s *WebAuthnService) FinishPasswordless(ctx context.Context, req *WebAuthnAssertionRequest, ipAddress, userAgent string) (*models.User, error) {
+	session, err := s.webauthnRepo.ConsumeSession(ctx, req.SessionID, webAuthnCeremonyPasswordless)
+	if err != nil {
+		return nil, ErrWebAuthnSessionInvalid
+	}
+
+	actor := AuditContext{TenantID: session.TenantID, IPAddress: ipAddress, UserAgent: userAgent}
+	cred, err := s.verifyAssertion(ctx, actor, session, &req.Credential, true)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.TenantID, cred.UserID)
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+	return user, nil
+}
+
+func (s *WebAuthnService) verifyAssertion(ctx context.Context, actor AuditContext, session *models.WebAuthnSession, resp *webauthn.AssertionResponse, requireUV bool) (*models.WebAuthnCredential, error) {
+	credentialID, err := resp.CredentialID()
+	if err != nil {
+		return nil, ErrWebAuthnVerification
+	}
+
+	cred, err := s.webauthnRepo.GetByCredentialID(ctx, session.TenantID, credentialID)
+	if err != nil || (session.UserID != nil && cred.UserID != *session.UserID) {
+		return nil, ErrWebAuthnVerification
+	}
+	actor.UserID = cred.UserID
+
+	if session.UserID == nil {
+		// Discoverable credentials must name the account they belong to.
+		handle, err := webauthn.DecodeBase64URL(resp.Response.UserHandle)
+		if err != nil || string(handle) != string(cred.UserID[:]) {
+			s.logEvent(ctx, actor, "webauthn_failed", cred.UserID)
+			return nil, ErrWebAuthnVerification
+		}
+	}
+
+	signCount, err := s.rp.VerifyAssertion(resp, session.Challenge, cred.PublicKey, cred.SignCount, requireUV)
+	if err != nil {
+		s.logEvent(ctx, actor, "webauthn_failed", cred.UserID)
+		return nil, ErrWebAuthnVerification
+	}
+
+	if err := s.webauthnRepo.UpdateSignCount(ctx, cred.ID, signCount, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+func (s *WebAuthnService) newSession(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, ceremony string) (*models.WebAuthnSession, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.WebAuthnSession{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Challenge: challenge,
+		ExpiresAt: time.Now().Add(s.timeout),
+	}
+	if err := s.webauthnRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *WebAuthnService) logEvent(ctx context.Context, actor AuditContext, action string, userID uuid.UUID) {
+	s.audit.Record(ctx, actor, AuditChange{
+		Action:     action,
+		Resource:   "user",
+		ResourceID: &userID,
+	})
+}
+
+func descriptors(creds []*models.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	out := make([]webauthn.CredentialDescriptor, len(creds))
+	for i, cred := range creds {
+		out[i] = webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         webauthn.EncodeBase64URL(cred.CredentialID),
+			Transports: cred.Transports,
+		}
+	}
+	return out
+}
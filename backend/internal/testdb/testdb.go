@@ -0,0 +1,70 @@
+// Package testdb provides a migrated PostgreSQL database for tests that
+// need one. Tests using it are skipped unless TEST_DATABASE_URL is set.
+package testdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"admin-panel/internal/database"
+	"admin-panel/internal/models"
+	"admin-panel/migrations"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Open connects to TEST_DATABASE_URL and applies the pending migrations.
+// The pool is closed when the test finishes.
+func Open(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	return db
+}
+
+// Tenant inserts an active tenant with a unique slug.
+func Tenant(t testing.TB, db *pgxpool.Pool) *models.Tenant {
+	t.Helper()
+
+	now := time.Now()
+	tenant := &models.Tenant{
+		ID:        uuid.New(),
+		Status:    "active",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	tenant.Slug = "test-" + tenant.ID.String()[:8]
+	tenant.Name = tenant.Slug
+
+	_, err := db.Exec(context.Background(),
+		"INSERT INTO tenants (id, name, slug, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		tenant.ID, tenant.Name, tenant.Slug, tenant.Status, tenant.CreatedAt, tenant.UpdatedAt,
+	)
+	if err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	return tenant
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswords checks passwords against a local copy of a breached
+// password corpus laid out for k-anonymity lookups: one file per five
+// character SHA-1 prefix (for example "5BAA6" or "5BAA6.txt"), each line
+// holding the remaining 35 hex characters of a hash, optionally followed by
+// ":count". This is the layout produced by the Pwned Passwords range
+// downloader, so the check never needs network access.
+type BreachedPasswords struct {
+	dir string
+}
+
+// NewBreachedPasswords returns a checker reading from dir. A nil checker,
+// returned for an empty dir, reports every password as not breached.
+func NewBreachedPasswords(dir string) *BreachedPasswords {
+	if dir == "" {
+		return nil
+	}
+	return &BreachedPasswords{dir: dir}
+}
+
+// Contains reports whether password appears in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		found, err := scanRangeFile(filepath.Join(b.dir, name), suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return found, err
+	}
+	return false, nil
+}
+
+func scanRangeFile(path, suffix string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
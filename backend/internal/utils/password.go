@@ -5,13 +5,21 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-const bcryptCost = 12
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	passwordAlgorithmUnknown  = "unknown"
+
+	bcryptCost = 12
+)
 
 type Argon2Params struct {
 	Memory      uint32
@@ -29,9 +37,62 @@ var DefaultArgon2Params = &Argon2Params{
 	KeyLength:   32,
 }
 
+// PasswordHasher selects the algorithm and cost parameters used for newly
+// hashed passwords. Hashes made with other algorithms or parameters still
+// verify, but NeedsRehash reports them so they can be upgraded the next
+// time the plaintext is known.
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+var (
+	hasherMu sync.RWMutex
+	hasher   = PasswordHasher{
+		Algorithm:  PasswordAlgorithmArgon2id,
+		BcryptCost: bcryptCost,
+		Argon2:     *DefaultArgon2Params,
+	}
+)
+
+// SetPasswordHasher replaces the process-wide password hasher. It is meant
+// to be called once at startup from configuration.
+func SetPasswordHasher(h PasswordHasher) error {
+	switch h.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		p := h.Argon2
+		if p.Memory < 8*uint32(p.Parallelism) || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength < 8 || p.KeyLength < 16 {
+			return fmt.Errorf("invalid argon2id parameters m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+		}
+	case PasswordAlgorithmBcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q", h.Algorithm)
+	}
+
+	hasherMu.Lock()
+	hasher = h
+	hasherMu.Unlock()
+	return nil
+}
+
+func currentHasher() PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	return hasher
+}
+
+// HashPassword hashes password with the configured algorithm.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
-	return string(bytes), err
+	h := currentHasher()
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(bytes), err
+	}
+	return hashArgon2id(password, &h.Argon2)
 }
 
 func CheckPasswordHash(password, hash string) bool {
@@ -42,9 +103,14 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// HashPasswordArgon2id hashes password with Argon2id using the configured
+// Argon2 parameters, whatever the configured algorithm.
 func HashPasswordArgon2id(password string) (string, error) {
-	p := DefaultArgon2Params
+	h := currentHasher()
+	return hashArgon2id(password, &h.Argon2)
+}
 
+func hashArgon2id(password string, p *Argon2Params) (string, error) {
 	salt := make([]byte, p.SaltLength)
 	if _, err := rand.Read(salt); err != nil {
 		return "", err
@@ -61,6 +127,72 @@ func HashPasswordArgon2id(password string) (string, error) {
 	return encodedHash, nil
 }
 
+// DescribePasswordHash returns the algorithm of an encoded hash and its cost
+// parameters in a canonical form, such as "m=65536,t=3,p=2" for Argon2id or
+// "cost=12" for bcrypt. Only the hash header is read, so a bare prefix like
+// "$2a$12$" is enough.
+func DescribePasswordHash(hash string) (algorithm, params string) {
+	vals := strings.Split(hash, "$")
+	if len(vals) < 3 || vals[0] != "" {
+		return passwordAlgorithmUnknown, ""
+	}
+
+	switch {
+	case vals[1] == "argon2id" && len(vals) >= 4:
+		var m, t uint32
+		var p uint8
+		if _, err := fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil {
+			return passwordAlgorithmUnknown, ""
+		}
+		return PasswordAlgorithmArgon2id, argon2Params(m, t, p)
+	case strings.HasPrefix(vals[1], "2"):
+		cost, err := strconv.Atoi(vals[2])
+		if err != nil {
+			return passwordAlgorithmUnknown, ""
+		}
+		return PasswordAlgorithmBcrypt, bcryptParams(cost)
+	}
+	return passwordAlgorithmUnknown, ""
+}
+
+// CurrentPasswordHashScheme returns the algorithm and parameters, in the
+// form used by DescribePasswordHash, that new hashes are made with.
+func CurrentPasswordHashScheme() (algorithm, params string) {
+	h := currentHasher()
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		return PasswordAlgorithmBcrypt, bcryptParams(h.BcryptCost)
+	}
+	return PasswordAlgorithmArgon2id, argon2Params(h.Argon2.Memory, h.Argon2.Iterations, h.Argon2.Parallelism)
+}
+
+// NeedsRehash reports whether hash was made with a different algorithm or
+// different parameters than the configured hasher would use now.
+func NeedsRehash(hash string) bool {
+	algorithm, params := DescribePasswordHash(hash)
+	currentAlgorithm, currentParams := CurrentPasswordHashScheme()
+	if algorithm != currentAlgorithm || params != currentParams {
+		return true
+	}
+
+	if algorithm == PasswordAlgorithmArgon2id {
+		p, _, _, err := decodeArgon2idHash(hash)
+		if err != nil {
+			return true
+		}
+		h := currentHasher()
+		return p.SaltLength != h.Argon2.SaltLength || p.KeyLength != h.Argon2.KeyLength
+	}
+	return false
+}
+
+func argon2Params(memory, iterations uint32, parallelism uint8) string {
+	return fmt.Sprintf("m=%d,t=%d,p=%d", memory, iterations, parallelism)
+}
+
+func bcryptParams(cost int) string {
+	return "cost=" + strconv.Itoa(cost)
+}
+
 func VerifyArgon2idHash(password, encodedHash string) bool {
 	p, salt, hash, err := decodeArgon2idHash(encodedHash)
 	if err != nil {
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters follow the RFC 6238 defaults that authenticator apps
+// assume when the otpauth URI does not override them.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30
+	// totpSkew is the number of periods either side of now that are
+	// accepted, to tolerate clock drift on the user's device.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit secret, base32 encoded.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI builds the otpauth:// URI that authenticator apps import, usually
+// through a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	params.Set("period", fmt.Sprintf("%d", TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// ValidateTOTP checks code against secret at time t. It returns the time
+// step that matched so callers can reject replays of the same code.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	step := t.Unix() / TOTPPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		candidate := totpCode(key, step+offset)
+		if hmac.Equal([]byte(candidate), []byte(code)) {
+			return step + offset, true
+		}
+	}
+	return 0, false
+}
+
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
+
+// GenerateRecoveryCodes returns n random single-use codes formatted as
+// xxxxx-xxxxx.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := hex.EncodeToString(raw)
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode returns the canonical form of a recovery code:
+// lower case, without the spaces and hyphens it may be typed or pasted
+// with.
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// HashRecoveryCode hashes a recovery code for storage. The codes carry
+// enough entropy that a fast hash is sufficient.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
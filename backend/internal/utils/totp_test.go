@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	want := HashRecoveryCode("abcde12345")
+	for _, code := range []string{
+		"abcde-12345",
+		"ABCDE-12345",
+		" abcde 12345 ",
+		"abc-de-123-45",
+	} {
+		if got := HashRecoveryCode(code); got != want {
+			t.Errorf("HashRecoveryCode(%q) = %s, want the hash of abcde12345", code, got)
+		}
+	}
+}
+
+func TestGeneratedRecoveryCodesHashWithoutHyphen(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+	for _, code := range codes {
+		typed := strings.ReplaceAll(code, "-", "")
+		if HashRecoveryCode(code) != HashRecoveryCode(typed) {
+			t.Errorf("code %q and %q hash differently", code, typed)
+		}
+	}
+}
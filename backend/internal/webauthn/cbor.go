@@ -0,0 +1,183 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errCBOR = errors.New("webauthn: malformed CBOR")
+
+// maxCBORDepth bounds nesting so hostile input cannot exhaust the stack.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first CBOR data item in data and returns it with
+// the number of bytes it occupied. It supports the subset of RFC 8949 used
+// by WebAuthn attestation objects and COSE keys: integers, byte and text
+// strings, arrays, maps and simple values. Maps decode to
+// map[interface{}]interface{} with int64 or string keys.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORDepth || d.pos >= len(d.data) {
+		return nil, errCBOR
+	}
+
+	initial := d.data[d.pos]
+	d.pos++
+	major := initial >> 5
+	info := initial & 0x1f
+
+	if major == 7 {
+		return d.simple(info)
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		b, err := d.take(arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		out := make([]byte, len(b))
+		copy(out, b)
+		return out, nil
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errCBOR
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errCBOR
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case 6:
+		// Tags carry no meaning for WebAuthn; return the tagged item.
+		return d.decode(depth + 1)
+	}
+	return nil, errCBOR
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.take(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := d.take(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.take(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.take(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+	// Indefinite lengths are not used by authenticators.
+	return 0, errCBOR
+}
+
+func (d *cborDecoder) simple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		_, err := d.take(2)
+		return nil, err
+	case 26:
+		b, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return nil, errCBOR
+}
+
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBOR
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
@@ -0,0 +1,142 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want interface{}
+	}{
+		{"zero", "00", int64(0)},
+		{"largest immediate", "17", int64(23)},
+		{"one byte uint", "1818", int64(24)},
+		{"two byte uint", "190100", int64(256)},
+		{"four byte uint", "1a000f4240", int64(1000000)},
+		{"eight byte uint", "1b7fffffffffffffff", int64(math.MaxInt64)},
+		{"negative one", "20", int64(-1)},
+		{"ES256 algorithm", "26", int64(AlgES256)},
+		{"RS256 algorithm", "390100", int64(AlgRS256)},
+		{"most negative", "3b7fffffffffffffff", int64(math.MinInt64)},
+		{"byte string", "43010203", []byte{1, 2, 3}},
+		{"empty byte string", "40", []byte{}},
+		{"text string", "6449455446", "IETF"},
+		{"array", "83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"nested array", "8201820203", []interface{}{int64(1), []interface{}{int64(2), int64(3)}}},
+		{"map with int and text keys", "a201026161f5", map[interface{}]interface{}{int64(1): int64(2), "a": true}},
+		{"false", "f4", false},
+		{"true", "f5", true},
+		{"null", "f6", nil},
+		{"tagged item", "c11a514b67b0", int64(1363896240)},
+		{"float32", "fa47c35000", float64(100000)},
+		{"float64", "fb3ff199999999999a", 1.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := mustHex(t, tt.hex)
+			got, n, err := decodeCBOR(data)
+			if err != nil {
+				t.Fatalf("decodeCBOR(%s): %v", tt.hex, err)
+			}
+			if n != len(data) {
+				t.Errorf("decodeCBOR(%s) consumed %d bytes, want %d", tt.hex, n, len(data))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR(%s) = %#v, want %#v", tt.hex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORStopsAfterFirstItem(t *testing.T) {
+	// A COSE key is followed by extensions in authenticator data; only the
+	// first item may be consumed.
+	data := mustHex(t, "a1010218ff")
+	_, n, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("consumed %d bytes, want 3", n)
+	}
+}
+
+func TestDecodeCBORByteStringIsCopied(t *testing.T) {
+	data := mustHex(t, "42aabb")
+	got, _, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	data[1] = 0
+	if !bytes.Equal(got.([]byte), []byte{0xaa, 0xbb}) {
+		t.Errorf("decoded byte string aliases the input: %x", got)
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty input", ""},
+		{"truncated one byte argument", "18"},
+		{"truncated two byte argument", "1901"},
+		{"truncated four byte argument", "1a0001"},
+		{"truncated eight byte argument", "1b00000000"},
+		{"truncated byte string", "4501020304"},
+		{"truncated text string", "646162"},
+		{"truncated array", "830102"},
+		{"truncated map value", "a2010203"},
+		{"map key without value", "a101"},
+		{"truncated tag", "c1"},
+		{"truncated float", "fa4049"},
+		{"uint beyond int64", "1b8000000000000000"},
+		{"negative beyond int64", "3b8000000000000000"},
+		{"oversized byte string length", "5bffffffffffffffff00"},
+		{"oversized text string length", "7a7fffffff61"},
+		{"oversized array length", "9bffffffffffffffff01"},
+		{"oversized map length", "bbffffffffffffffff0102"},
+		{"array longer than input", "9a0000ffff01"},
+		{"indefinite byte string", "5f4101ff"},
+		{"indefinite array", "9f01ff"},
+		{"reserved additional info", "1c"},
+		{"unassigned simple value", "f0"},
+		{"one byte simple value", "f818"},
+		{"break outside indefinite item", "ff"},
+		{"array map key", "a18001"},
+		{"byte string map key", "a1410101"},
+		{"too deeply nested arrays", strings.Repeat("81", maxCBORDepth+1) + "01"},
+		{"too deeply nested maps", strings.Repeat("a101", maxCBORDepth+1) + "01"},
+		{"too deeply nested tags", strings.Repeat("c1", maxCBORDepth+1) + "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v, _, err := decodeCBOR(mustHex(t, tt.hex)); err == nil {
+				t.Errorf("decodeCBOR(%s) = %#v, want error", tt.hex, v)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMaxDepth(t *testing.T) {
+	data := mustHex(t, strings.Repeat("81", maxCBORDepth)+"01")
+	if _, _, err := decodeCBOR(data); err != nil {
+		t.Errorf("decodeCBOR of %d nested arrays: %v", maxCBORDepth, err)
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex fixture %q: %v", s, err)
+	}
+	return b
+}
@@ -0,0 +1,519 @@
+// Package webauthn implements the server side of the WebAuthn registration
+// and authentication ceremonies for passkeys and security keys.
+//
+// Only "none" attestation is requested, so the attestation statement is not
+// verified; authenticators are trusted on first registration, as is usual
+// for passkeys. Supported algorithms are ES256, RS256 and EdDSA.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidResponse     = errors.New("webauthn: invalid authenticator response")
+	ErrChallengeMismatch   = errors.New("webauthn: challenge mismatch")
+	ErrOriginNotAllowed    = errors.New("webauthn: origin not allowed")
+	ErrRPIDMismatch        = errors.New("webauthn: relying party mismatch")
+	ErrUserNotPresent      = errors.New("webauthn: user presence not asserted")
+	ErrUserNotVerified     = errors.New("webauthn: user verification required")
+	ErrUnsupportedKey      = errors.New("webauthn: unsupported public key")
+	ErrInvalidSignature    = errors.New("webauthn: invalid signature")
+	ErrSignCountRegression = errors.New("webauthn: signature counter did not increase")
+)
+
+// COSE algorithm identifiers.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+const challengeLength = 32
+
+// Config identifies the relying party. RPID is the registrable domain the
+// credentials are scoped to; Origins are the exact origins the browser may
+// report in client data.
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout int
+}
+
+// Credential is a verified, newly registered public key credential.
+type Credential struct {
+	ID           []byte
+	PublicKey    []byte
+	Algorithm    int64
+	SignCount    uint32
+	AAGUID       []byte
+	UserVerified bool
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is the publicKey member passed to
+// navigator.credentials.create(). Binary fields are base64url encoded.
+type CreationOptions struct {
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the publicKey member passed to
+// navigator.credentials.get().
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout,omitempty"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create().
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// attested credential data, present during registration
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// NewChallenge returns a random challenge for a ceremony.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func EncodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL accepts base64url with or without padding.
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (c *Config) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return &CreationOptions{
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:      user,
+		Challenge: EncodeBase64URL(challenge),
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds assertion options. An empty allow list asks the
+// browser for a discoverable credential, as used by passwordless login.
+func (c *Config) RequestOptions(challenge []byte, allow []CredentialDescriptor, userVerification string) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        EncodeBase64URL(challenge),
+		RPID:             c.RPID,
+		Timeout:          c.Timeout,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// VerifyRegistration checks an attestation response against the challenge
+// issued for it and returns the credential to store.
+func (c *Config) VerifyRegistration(resp *AttestationResponse, challenge []byte, requireUV bool) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := DecodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	if err := c.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	attestation, err := DecodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	decoded, _, err := decodeCBOR(attestation)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+	rawAuthData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyAuthenticatorData(authData, requireUV); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, ErrInvalidResponse
+	}
+
+	rawID, err := DecodeBase64URL(resp.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, ErrInvalidResponse
+	}
+
+	alg, err := coseAlgorithm(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:           authData.credentialID,
+		PublicKey:    authData.publicKey,
+		Algorithm:    alg,
+		SignCount:    authData.signCount,
+		AAGUID:       authData.aaguid,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an assertion signed by a stored credential and
+// returns the authenticator's new signature counter.
+func (c *Config) VerifyAssertion(resp *AssertionResponse, challenge, publicKey []byte, storedSignCount uint32, requireUV bool) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := DecodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, ErrInvalidResponse
+	}
+	if err := c.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := DecodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalidResponse
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.verifyAuthenticatorData(authData, requireUV); err != nil {
+		return 0, err
+	}
+
+	signature, err := DecodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return 0, ErrInvalidResponse
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := verifySignature(publicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that do not implement a counter always report zero.
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return 0, ErrSignCountRegression
+	}
+
+	return authData.signCount, nil
+}
+
+// CredentialID returns the raw credential ID an assertion claims to be
+// signed by.
+func (r *AssertionResponse) CredentialID() ([]byte, error) {
+	id, err := DecodeBase64URL(r.RawID)
+	if err != nil || len(id) == 0 {
+		return nil, ErrInvalidResponse
+	}
+	return id, nil
+}
+
+func (c *Config) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidResponse
+	}
+	if data.Type != ceremony {
+		return ErrInvalidResponse
+	}
+
+	got, err := DecodeBase64URL(data.Challenge)
+	if err != nil || !bytes.Equal(got, challenge) {
+		return ErrChallengeMismatch
+	}
+
+	for _, origin := range c.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOriginNotAllowed
+}
+
+func (c *Config) verifyAuthenticatorData(data *authenticatorData, requireUV bool) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if data.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	if requireUV && data.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, ErrInvalidResponse
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.flags&flagAttested == 0 {
+		return data, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, ErrInvalidResponse
+	}
+	data.aaguid = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || len(rest) < idLength {
+		return nil, ErrInvalidResponse
+	}
+	data.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	_, n, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	data.publicKey = rest[:n]
+
+	return data, nil
+}
+
+func coseKey(raw []byte) (map[interface{}]interface{}, int64, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, 0, ErrUnsupportedKey
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+	alg, ok := key[int64(3)].(int64)
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+	return key, alg, nil
+}
+
+func coseAlgorithm(raw []byte) (int64, error) {
+	key, alg, err := coseKey(raw)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := publicKeyFromCOSE(key, alg); err != nil {
+		return 0, err
+	}
+	return alg, nil
+}
+
+func publicKeyFromCOSE(key map[interface{}]interface{}, alg int64) (crypto.PublicKey, error) {
+	kty, _ := key[int64(1)].(int64)
+
+	switch alg {
+	case AlgES256:
+		crv, _ := key[int64(-1)].(int64)
+		x, xok := key[int64(-2)].([]byte)
+		y, yok := key[int64(-3)].([]byte)
+		if kty != 2 || crv != 1 || !xok || !yok || len(x) != 32 || len(y) != 32 {
+			return nil, ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, ErrUnsupportedKey
+		}
+		return pub, nil
+
+	case AlgRS256:
+		n, nok := key[int64(-1)].([]byte)
+		e, eok := key[int64(-2)].([]byte)
+		if kty != 3 || !nok || !eok || len(e) == 0 || len(e) > 4 {
+			return nil, ErrUnsupportedKey
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}
+		if pub.N.BitLen() < 2048 {
+			return nil, ErrUnsupportedKey
+		}
+		return pub, nil
+
+	case AlgEdDSA:
+		crv, _ := key[int64(-1)].(int64)
+		x, ok := key[int64(-2)].([]byte)
+		if kty != 1 || crv != 6 || !ok || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, ErrUnsupportedKey
+}
+
+func verifySignature(rawKey, signed, signature []byte) error {
+	key, alg, err := coseKey(rawKey)
+	if err != nil {
+		return err
+	}
+	pub, err := publicKeyFromCOSE(key, alg)
+	if err != nil {
+		return err
+	}
+
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(k, digest[:], signature) {
+			return ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, signed, signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
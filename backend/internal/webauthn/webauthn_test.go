@@ -0,0 +1,548 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var testConfig = &Config{
+	RPID:    "example.com",
+	RPName:  "Example",
+	Origins: []string{"https://example.com"},
+}
+
+// The fixture below is a registration and a later assertion of an Ed25519
+// passkey for example.com, assembled byte by byte from the WebAuthn and
+// COSE specifications rather than with this package.
+const (
+	fixtureChallenge = "oKGio6SlpqeoqaqrrK2ur6ChoqOkpaanqKmqq6ytrq8"
+	// {"type":"webauthn.create","challenge":"<fixtureChallenge>","origin":"https://example.com","crossOrigin":false}
+	fixtureCreateClientData = "eyJ0eXBlIjoid2ViYXV0aG4uY3JlYXRlIiwiY2hhbGxlbmdlIjoib0tHaW82U2xwcWVvcWFxcnJLMnVyNkNob3FPa3BhYW5xS21xcTZ5dHJxOCIsIm9yaWdpbiI6Imh0dHBzOi8vZXhhbXBsZS5jb20iLCJjcm9zc09yaWdpbiI6ZmFsc2V9"
+	// {"type":"webauthn.get","challenge":"<fixtureChallenge>","origin":"https://example.com","crossOrigin":false}
+	fixtureGetClientData = "eyJ0eXBlIjoid2ViYXV0aG4uZ2V0IiwiY2hhbGxlbmdlIjoib0tHaW82U2xwcWVvcWFxcnJLMnVyNkNob3FPa3BhYW5xS21xcTZ5dHJxOCIsIm9yaWdpbiI6Imh0dHBzOi8vZXhhbXBsZS5jb20iLCJjcm9zc09yaWdpbiI6ZmFsc2V9"
+	// Ed25519 public key of the seed 0x01..0x20.
+	fixturePublicKey = "79b5562e8fe654f94078b112e8a98ba7901f853ae695bed7e0e3910bad049664"
+	fixtureRPIDHash  = "a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce1947"
+	fixtureCredID    = "000102030405060708090a0b0c0d0e0f"
+	// COSE_Key {1: 1 (OKP), 3: -8 (EdDSA), -1: 6 (Ed25519), -2: x}
+	fixtureCOSEKey = "a4" + "0101" + "0327" + "2006" + "215820" + fixturePublicKey
+	// Attestation object {"fmt": "none", "attStmt": {}, "authData": ...}
+	// with flags UP|UV|AT, counter 0, a zero AAGUID and a 16 byte ID.
+	fixtureAttestationObject = "a3" +
+		"63666d74" + "646e6f6e65" +
+		"6761747453746d74" + "a0" +
+		"6861757468446174615871" +
+		fixtureRPIDHash + "45" + "00000000" +
+		"00000000000000000000000000000000" + "0010" + fixtureCredID +
+		fixtureCOSEKey
+	// Assertion authenticator data: flags UP|UV, counter 2.
+	fixtureAssertionAuthData = "o3mm9u6vuaVeN4wRgDTidR5oL6ufLTCrE9ISVYbOGUcFAAAAAg"
+	fixtureSignature         = "4pfV80IZjuYBJ6Frp--rZf9SjjX3e9dO49PcN-zlOfur5HOmbkj2w6DRy4stlcZI6vyQqS0L2tpEikutpfH9Cg"
+)
+
+func TestVerifyRegistrationFixture(t *testing.T) {
+	resp := &AttestationResponse{ID: EncodeBase64URL(mustHex(t, fixtureCredID)), RawID: EncodeBase64URL(mustHex(t, fixtureCredID)), Type: "public-key"}
+	resp.Response.ClientDataJSON = fixtureCreateClientData
+	resp.Response.AttestationObject = EncodeBase64URL(mustHex(t, fixtureAttestationObject))
+	challenge, _ := DecodeBase64URL(fixtureChallenge)
+
+	cred, err := testConfig.VerifyRegistration(resp, challenge, true)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if !bytes.Equal(cred.ID, mustHex(t, fixtureCredID)) {
+		t.Errorf("credential ID = %x, want %s", cred.ID, fixtureCredID)
+	}
+	if !bytes.Equal(cred.PublicKey, mustHex(t, fixtureCOSEKey)) {
+		t.Errorf("public key = %x, want %s", cred.PublicKey, fixtureCOSEKey)
+	}
+	if cred.Algorithm != AlgEdDSA || cred.SignCount != 0 || !cred.UserVerified {
+		t.Errorf("credential = %+v, want EdDSA, counter 0, user verified", cred)
+	}
+}
+
+func TestVerifyAssertionFixture(t *testing.T) {
+	resp := &AssertionResponse{RawID: EncodeBase64URL(mustHex(t, fixtureCredID)), Type: "public-key"}
+	resp.Response.ClientDataJSON = fixtureGetClientData
+	resp.Response.AuthenticatorData = fixtureAssertionAuthData
+	resp.Response.Signature = fixtureSignature
+	challenge, _ := DecodeBase64URL(fixtureChallenge)
+
+	count, err := testConfig.VerifyAssertion(resp, challenge, mustHex(t, fixtureCOSEKey), 1, true)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("sign count = %d, want 2", count)
+	}
+
+	// Any change to the signed data, here the counter, breaks the signature.
+	tampered := *resp
+	tampered.Response.AuthenticatorData = EncodeBase64URL(append(mustBase64(t, fixtureAssertionAuthData)[:36:36], 3))
+	if _, err := testConfig.VerifyAssertion(&tampered, challenge, mustHex(t, fixtureCOSEKey), 1, true); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyAssertion with changed counter: got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	for _, key := range testKeys(t) {
+		t.Run(key.name, func(t *testing.T) {
+			a := newAuthenticator(key)
+			challenge := testChallenge(t)
+
+			cred, err := testConfig.VerifyRegistration(a.register(challenge, nil), challenge, false)
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if cred.Algorithm != key.alg || !bytes.Equal(cred.ID, a.credID) || !bytes.Equal(cred.PublicKey, key.cose) {
+				t.Errorf("credential = %+v, want algorithm %d and the authenticator's ID and key", cred, key.alg)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	key := testKeys(t)[0]
+	challenge := testChallenge(t)
+
+	tests := []struct {
+		name      string
+		requireUV bool
+		modify    func(*registration)
+		want      error
+	}{
+		{"wrong credential type", false, func(r *registration) { r.credType = "password" }, ErrInvalidResponse},
+		{"assertion client data", false, func(r *registration) { r.ceremony = "webauthn.get" }, ErrInvalidResponse},
+		{"other challenge", false, func(r *registration) { r.challenge = bytes.Repeat([]byte{1}, challengeLength) }, ErrChallengeMismatch},
+		{"other origin", false, func(r *registration) { r.origin = "https://evil.example" }, ErrOriginNotAllowed},
+		{"other relying party", false, func(r *registration) { r.rpID = "evil.example" }, ErrRPIDMismatch},
+		{"user not present", false, func(r *registration) { r.flags &^= flagUserPresent }, ErrUserNotPresent},
+		{"user not verified", true, func(r *registration) { r.flags &^= flagUserVerified }, ErrUserNotVerified},
+		{"no attested credential", false, func(r *registration) { r.flags &^= flagAttested }, ErrInvalidResponse},
+		{"raw ID mismatch", false, func(r *registration) { r.rawID = []byte("other") }, ErrInvalidResponse},
+		{"unsupported algorithm", false, func(r *registration) {
+			r.publicKey = cborEncode(map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(-36)})
+		}, ErrUnsupportedKey},
+		{"point not on curve", false, func(r *registration) {
+			r.publicKey = cborEncode(map[interface{}]interface{}{
+				int64(1): int64(2), int64(3): int64(AlgES256), int64(-1): int64(1),
+				int64(-2): bytes.Repeat([]byte{1}, 32), int64(-3): bytes.Repeat([]byte{2}, 32),
+			})
+		}, ErrUnsupportedKey},
+		{"short RSA key", false, func(r *registration) {
+			r.publicKey = cborEncode(map[interface{}]interface{}{
+				int64(1): int64(3), int64(3): int64(AlgRS256),
+				int64(-1): bytes.Repeat([]byte{0xff}, 128), int64(-2): []byte{1, 0, 1},
+			})
+		}, ErrUnsupportedKey},
+		{"malformed public key", false, func(r *registration) { r.publicKey = []byte{0xa5, 0x01} }, ErrInvalidResponse},
+		{"truncated attestation object", false, func(r *registration) { r.truncate = 10 }, ErrInvalidResponse},
+		{"attestation object not a map", false, func(r *registration) { r.object = cborEncode([]interface{}{int64(1)}) }, ErrInvalidResponse},
+		{"missing authData", false, func(r *registration) {
+			r.object = cborEncode(map[interface{}]interface{}{"fmt": "none", "attStmt": map[interface{}]interface{}{}})
+		}, ErrInvalidResponse},
+		{"short authData", false, func(r *registration) {
+			r.object = cborEncode(map[interface{}]interface{}{"fmt": "none", "authData": make([]byte, 36)})
+		}, ErrInvalidResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator(key)
+			resp := a.register(challenge, tt.modify)
+			if _, err := testConfig.VerifyRegistration(resp, challenge, tt.requireUV); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyRegistration: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	for _, key := range testKeys(t) {
+		t.Run(key.name, func(t *testing.T) {
+			a := newAuthenticator(key)
+			challenge := testChallenge(t)
+			a.signCount = 7
+
+			count, err := testConfig.VerifyAssertion(a.assert(challenge, nil), challenge, key.cose, 6, true)
+			if err != nil {
+				t.Fatalf("VerifyAssertion: %v", err)
+			}
+			if count != 7 {
+				t.Errorf("sign count = %d, want 7", count)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	keys := testKeys(t)
+	key, other := keys[0], keys[1]
+	challenge := testChallenge(t)
+
+	tests := []struct {
+		name        string
+		storedCount uint32
+		requireUV   bool
+		modify      func(*assertion)
+		want        error
+	}{
+		{"wrong credential type", 0, false, func(a *assertion) { a.credType = "password" }, ErrInvalidResponse},
+		{"registration client data", 0, false, func(a *assertion) { a.ceremony = "webauthn.create" }, ErrInvalidResponse},
+		{"other challenge", 0, false, func(a *assertion) { a.challenge = bytes.Repeat([]byte{1}, challengeLength) }, ErrChallengeMismatch},
+		{"other origin", 0, false, func(a *assertion) { a.origin = "https://example.com.evil.example" }, ErrOriginNotAllowed},
+		{"other relying party", 0, false, func(a *assertion) { a.rpID = "evil.example" }, ErrRPIDMismatch},
+		{"user not present", 0, false, func(a *assertion) { a.flags &^= flagUserPresent }, ErrUserNotPresent},
+		{"user not verified", 0, true, func(a *assertion) { a.flags &^= flagUserVerified }, ErrUserNotVerified},
+		{"signed by another key", 0, false, func(a *assertion) { a.signer = other }, ErrInvalidSignature},
+		{"client data changed after signing", 0, false, func(a *assertion) { a.tamperClientData = true }, ErrInvalidSignature},
+		{"empty signature", 0, false, func(a *assertion) { a.signature = []byte{} }, ErrInvalidSignature},
+		{"counter went back", 9, false, func(a *assertion) { a.signCount = 8 }, ErrSignCountRegression},
+		{"counter repeated", 8, false, func(a *assertion) { a.signCount = 8 }, ErrSignCountRegression},
+		{"counter dropped to zero", 8, false, func(a *assertion) { a.signCount = 0 }, ErrSignCountRegression},
+		{"truncated authenticator data", 0, false, func(a *assertion) { a.authData = make([]byte, 20) }, ErrInvalidResponse},
+		{"invalid base64 signature", 0, false, func(a *assertion) { a.rawSignature = "!!!" }, ErrInvalidResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator(key)
+			resp := a.assert(challenge, tt.modify)
+			if _, err := testConfig.VerifyAssertion(resp, challenge, key.cose, tt.storedCount, tt.requireUV); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyAssertion: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionWithoutCounter(t *testing.T) {
+	key := testKeys(t)[0]
+	a := newAuthenticator(key)
+	challenge := testChallenge(t)
+
+	if _, err := testConfig.VerifyAssertion(a.assert(challenge, nil), challenge, key.cose, 0, false); err != nil {
+		t.Errorf("VerifyAssertion from an authenticator without a counter: %v", err)
+	}
+}
+
+func TestParseAuthenticatorDataMalformed(t *testing.T) {
+	rpIDHash := sha256.Sum256([]byte(testConfig.RPID))
+	header := append(rpIDHash[:], flagUserPresent|flagAttested, 0, 0, 0, 0)
+	aaguid := make([]byte, 16)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"shorter than header", header[:36]},
+		{"attested data missing", header},
+		{"credential ID length missing", concat(header, aaguid)},
+		{"zero length credential ID", concat(header, aaguid, []byte{0, 0})},
+		{"credential ID longer than data", concat(header, aaguid, []byte{0xff, 0xff}, []byte{1, 2, 3})},
+		{"public key missing", concat(header, aaguid, []byte{0, 1}, []byte{9})},
+		{"public key truncated", concat(header, aaguid, []byte{0, 1}, []byte{9}, []byte{0xa2, 0x01, 0x02})},
+		{"public key too deeply nested", concat(header, aaguid, []byte{0, 1}, []byte{9}, bytes.Repeat([]byte{0x81}, maxCBORDepth+1), []byte{0})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseAuthenticatorData(tt.data); !errors.Is(err, ErrInvalidResponse) {
+				t.Errorf("parseAuthenticatorData: got %v, want ErrInvalidResponse", err)
+			}
+		})
+	}
+}
+
+// testKey is a credential key pair with its COSE encoding.
+type testKey struct {
+	name string
+	alg  int64
+	cose []byte
+	sign func(data []byte) []byte
+}
+
+func testKeys(t *testing.T) []testKey {
+	t.Helper()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []testKey{
+		{
+			name: "ES256",
+			alg:  AlgES256,
+			cose: cborEncode(map[interface{}]interface{}{
+				int64(1): int64(2), int64(3): int64(AlgES256), int64(-1): int64(1),
+				int64(-2): ecKey.X.FillBytes(make([]byte, 32)), int64(-3): ecKey.Y.FillBytes(make([]byte, 32)),
+			}),
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				sig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sig
+			},
+		},
+		{
+			name: "EdDSA",
+			alg:  AlgEdDSA,
+			cose: cborEncode(map[interface{}]interface{}{
+				int64(1): int64(1), int64(3): int64(AlgEdDSA), int64(-1): int64(6),
+				int64(-2): []byte(edKey.Public().(ed25519.PublicKey)),
+			}),
+			sign: func(data []byte) []byte { return ed25519.Sign(edKey, data) },
+		},
+		{
+			name: "RS256",
+			alg:  AlgRS256,
+			cose: cborEncode(map[interface{}]interface{}{
+				int64(1): int64(3), int64(3): int64(AlgRS256),
+				int64(-1): rsaKey.N.Bytes(), int64(-2): big.NewInt(int64(rsaKey.E)).Bytes(),
+			}),
+			sign: func(data []byte) []byte {
+				digest := sha256.Sum256(data)
+				sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sig
+			},
+		},
+	}
+}
+
+func testChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// authenticator produces registration and assertion responses for one key,
+// with hooks to corrupt each part of them.
+type authenticator struct {
+	key       testKey
+	credID    []byte
+	signCount uint32
+}
+
+func newAuthenticator(key testKey) *authenticator {
+	credID := make([]byte, 16)
+	rand.Read(credID)
+	return &authenticator{key: key, credID: credID}
+}
+
+type registration struct {
+	credType  string
+	ceremony  string
+	challenge []byte
+	origin    string
+	rpID      string
+	flags     byte
+	rawID     []byte
+	publicKey []byte
+	object    []byte
+	truncate  int
+}
+
+func (a *authenticator) register(challenge []byte, modify func(*registration)) *AttestationResponse {
+	r := &registration{
+		credType:  "public-key",
+		ceremony:  "webauthn.create",
+		challenge: challenge,
+		origin:    testConfig.Origins[0],
+		rpID:      testConfig.RPID,
+		flags:     flagUserPresent | flagUserVerified | flagAttested,
+		rawID:     a.credID,
+		publicKey: a.key.cose,
+	}
+	if modify != nil {
+		modify(r)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(r.rpID))
+	authData := concat(rpIDHash[:], []byte{r.flags}, uint32Bytes(a.signCount))
+	if r.flags&flagAttested != 0 {
+		idLength := make([]byte, 2)
+		binary.BigEndian.PutUint16(idLength, uint16(len(a.credID)))
+		authData = concat(authData, make([]byte, 16), idLength, a.credID, r.publicKey)
+	}
+
+	object := r.object
+	if object == nil {
+		object = cborEncode(map[interface{}]interface{}{
+			"fmt":      "none",
+			"attStmt":  map[interface{}]interface{}{},
+			"authData": authData,
+		})
+	}
+	if r.truncate > 0 {
+		object = object[:r.truncate]
+	}
+
+	resp := &AttestationResponse{ID: EncodeBase64URL(r.rawID), RawID: EncodeBase64URL(r.rawID), Type: r.credType}
+	resp.Response.ClientDataJSON = EncodeBase64URL(clientDataJSON(r.ceremony, r.challenge, r.origin))
+	resp.Response.AttestationObject = EncodeBase64URL(object)
+	return resp
+}
+
+type assertion struct {
+	credType         string
+	ceremony         string
+	challenge        []byte
+	origin           string
+	rpID             string
+	flags            byte
+	signCount        uint32
+	signer           testKey
+	authData         []byte
+	signature        []byte
+	rawSignature     string
+	tamperClientData bool
+}
+
+func (a *authenticator) assert(challenge []byte, modify func(*assertion)) *AssertionResponse {
+	s := &assertion{
+		credType:  "public-key",
+		ceremony:  "webauthn.get",
+		challenge: challenge,
+		origin:    testConfig.Origins[0],
+		rpID:      testConfig.RPID,
+		flags:     flagUserPresent | flagUserVerified,
+		signCount: a.signCount,
+		signer:    a.key,
+	}
+	if modify != nil {
+		modify(s)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(s.rpID))
+	authData := s.authData
+	if authData == nil {
+		authData = concat(rpIDHash[:], []byte{s.flags}, uint32Bytes(s.signCount))
+	}
+	clientData := clientDataJSON(s.ceremony, s.challenge, s.origin)
+	clientDataHash := sha256.Sum256(clientData)
+	signature := s.signature
+	if signature == nil {
+		signature = s.signer.sign(concat(authData, clientDataHash[:]))
+	}
+	if s.tamperClientData {
+		clientData = []byte(strings.Replace(string(clientData), `"origin"`, `"extra":1,"origin"`, 1))
+	}
+
+	resp := &AssertionResponse{ID: EncodeBase64URL(a.credID), RawID: EncodeBase64URL(a.credID), Type: s.credType}
+	resp.Response.ClientDataJSON = EncodeBase64URL(clientData)
+	resp.Response.AuthenticatorData = EncodeBase64URL(authData)
+	resp.Response.Signature = EncodeBase64URL(signature)
+	if s.rawSignature != "" {
+		resp.Response.Signature = s.rawSignature
+	}
+	return resp
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	return []byte(`{"type":"` + ceremony + `","challenge":"` + EncodeBase64URL(challenge) + `","origin":"` + origin + `"}`)
+}
+
+func uint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func mustBase64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := DecodeBase64URL(s)
+	if err != nil {
+		t.Fatalf("bad base64url fixture %q: %v", s, err)
+	}
+	return b
+}
+
+// cborEncode encodes the value types WebAuthn uses in definite-length CBOR.
+// Map keys are written in a fixed order so encodings are reproducible.
+func cborEncode(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(n))
+			return b
+		case n <= 0xffffffff:
+			b := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(b[1:], uint32(n))
+			return b
+		}
+		b := []byte{major<<5 | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(b[1:], n)
+		return b
+	}
+
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := head(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, cborEncode(item)...)
+		}
+		return out
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string][]byte, len(v))
+		for k, item := range v {
+			encoded := cborEncode(k)
+			keys = append(keys, encoded)
+			values[string(encoded)] = cborEncode(item)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(append(out, k...), values[string(k)]...)
+		}
+		return out
+	}
+	panic("cborEncode: unsupported type")
+}
@@ -0,0 +1,9 @@
+-- Revert Admin Authentication and Feature Flags Migration
+
+DROP INDEX IF EXISTS idx_audit_logs_new_value;
+DROP INDEX IF EXISTS idx_audit_logs_metadata;
+
+DELETE FROM permissions WHERE resource IN ('admin', 'feature_flags');
+
+DROP TABLE IF EXISTS feature_flags;
+DROP TABLE IF EXISTS admin_auth;
@@ -0,0 +1,7 @@
+-- Revert Performance Optimization Indexes
+
+DROP INDEX IF EXISTS idx_user_roles_role_user;
+DROP INDEX IF EXISTS idx_roles_tenant_system;
+DROP INDEX IF EXISTS idx_audit_logs_action_resource;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created;
+DROP INDEX IF EXISTS idx_users_tenant_status;
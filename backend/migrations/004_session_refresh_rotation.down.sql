@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_sessions_revoked_at;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS replaced_by_token,
+    DROP COLUMN IF EXISTS rotated_at;
@@ -0,0 +1,3 @@
+-- Revert Tenant Management Migration
+
+DELETE FROM permissions WHERE resource = 'tenants';
@@ -0,0 +1,12 @@
+-- Tenant Management Migration
+
+-- Platform-level permission for managing tenants. It only takes effect for
+-- users of the platform (default) tenant.
+INSERT INTO permissions (id, name, resource, action, description) VALUES
+    (uuid_generate_v4(), 'tenants:manage', 'tenants', 'manage', 'Manage tenants (platform)')
+ON CONFLICT DO NOTHING;
+
+-- Assign tenant management to the platform Super Admin role
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT '00000000-0000-0000-0000-000000000001', id FROM permissions WHERE resource = 'tenants'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Revert Two-Factor Authentication Migration
+
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,4 @@
+-- Revert WebAuthn / Passkey Migration
+
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP TABLE IF EXISTS webauthn_credentials;
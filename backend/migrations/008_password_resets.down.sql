@@ -0,0 +1,3 @@
+-- Revert Password Reset Migration
+
+DROP TABLE IF EXISTS password_reset_tokens;
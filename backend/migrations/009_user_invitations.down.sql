@@ -0,0 +1,9 @@
+-- Revert User Invitations Migration
+
+DROP TABLE IF EXISTS user_invitations;
+
+UPDATE users SET status = 'inactive' WHERE status = 'pending';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('active', 'inactive', 'suspended'));
@@ -0,0 +1,5 @@
+-- Revert Password Policy Migration
+
+DROP TABLE IF EXISTS password_history;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
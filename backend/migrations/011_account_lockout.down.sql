@@ -0,0 +1,8 @@
+-- Revert Account Lockout Migration
+
+DROP INDEX IF EXISTS idx_users_locked_until;
+
+ALTER TABLE users DROP COLUMN IF EXISTS lockout_count;
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS last_failed_login_at;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_login_attempts;
@@ -0,0 +1,11 @@
+-- Account Lockout Migration
+
+-- Failed password attempts are counted per user so lockouts survive
+-- restarts and apply across every server replica. lockout_count drives the
+-- progressive backoff and is cleared by a successful login or an unlock.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lockout_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_users_locked_until ON users(tenant_id, locked_until) WHERE locked_until IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Revert Rate Limits Migration
+
+DROP TABLE IF EXISTS rate_limit_counters;
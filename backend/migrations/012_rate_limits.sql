@@ -0,0 +1,14 @@
+-- Rate Limits Migration
+
+-- Shared sliding window counters for the Postgres rate limiter backend.
+-- count is the current fixed window starting at window_start and
+-- previous_count the one before it.
+CREATE TABLE IF NOT EXISTS rate_limit_counters (
+    key VARCHAR(255) PRIMARY KEY,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    previous_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_counters_expires_at ON rate_limit_counters(expires_at);
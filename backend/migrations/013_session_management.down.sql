@@ -0,0 +1,7 @@
+-- Revert Session Management Migration
+
+DROP INDEX IF EXISTS idx_sessions_user_current;
+DROP INDEX IF EXISTS idx_sessions_family_id;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS authenticated_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,17 @@
+-- Session Management Migration
+
+-- Refresh token rotation stores one row per refresh token. family_id ties
+-- the rows of one sign-in together so the session can be listed and revoked
+-- as a whole, and authenticated_at remembers when that sign-in happened.
+-- created_at of the newest row is when the session was last refreshed.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS family_id UUID;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS authenticated_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE sessions SET family_id = id WHERE family_id IS NULL;
+UPDATE sessions SET authenticated_at = created_at WHERE authenticated_at IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE sessions ALTER COLUMN authenticated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_current ON sessions(user_id) WHERE revoked_at IS NULL AND rotated_at IS NULL;
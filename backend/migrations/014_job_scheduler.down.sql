@@ -0,0 +1,5 @@
+-- Revert Job Scheduler Migration
+
+DELETE FROM permissions WHERE resource = 'jobs';
+
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,31 @@
+-- Job Scheduler Migration
+
+-- History of background job runs. Scheduled runs are keyed by the slot they
+-- were due in, so when several replicas wake up for the same slot only the
+-- first one to claim it runs the job. Manual runs use the time they were
+-- requested.
+CREATE TABLE IF NOT EXISTS job_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_name VARCHAR(100) NOT NULL,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    trigger VARCHAR(20) NOT NULL DEFAULT 'schedule',
+    triggered_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    instance VARCHAR(255) NOT NULL,
+    affected BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (job_name, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs(job_name, started_at DESC);
+
+-- Platform-level permission for inspecting and triggering background jobs.
+INSERT INTO permissions (id, name, resource, action, description) VALUES
+    (uuid_generate_v4(), 'jobs:manage', 'jobs', 'manage', 'Manage background jobs (platform)')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT '00000000-0000-0000-0000-000000000001', id FROM permissions WHERE resource = 'jobs'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Revert Audit Archives Migration
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created_asc;
+DROP TABLE IF EXISTS audit_archives;
@@ -0,0 +1,21 @@
+-- Audit Archives Migration
+
+-- Audit log entries past a tenant's retention period are written to
+-- compressed JSONL files before they are deleted. Each row describes one
+-- archive file; file_name is relative to the configured archive directory.
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    from_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    row_count BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_archives_tenant_created ON audit_archives(tenant_id, created_at DESC);
+
+-- Retention deletes a tenant's oldest entries first.
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created_asc ON audit_logs(tenant_id, created_at, id);
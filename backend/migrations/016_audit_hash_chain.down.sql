@@ -0,0 +1,25 @@
+-- Revert Audit Hash Chain Migration
+
+DROP TRIGGER IF EXISTS audit_checkpoints_append_only ON audit_checkpoints;
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_chain ON audit_logs;
+DROP FUNCTION IF EXISTS audit_append_only();
+DROP FUNCTION IF EXISTS audit_logs_chain();
+DROP FUNCTION IF EXISTS audit_log_digest(audit_logs);
+DROP FUNCTION IF EXISTS audit_chain_field(TEXT);
+
+DROP INDEX IF EXISTS idx_audit_archives_tenant_last_seq;
+ALTER TABLE audit_archives DROP COLUMN IF EXISTS last_hash;
+ALTER TABLE audit_archives DROP COLUMN IF EXISTS last_seq;
+
+DROP TABLE IF EXISTS audit_checkpoints;
+DROP TABLE IF EXISTS audit_chain_heads;
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_seq;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS seq;
+
+UPDATE audit_logs SET user_id = NULL WHERE user_id IS NOT NULL AND user_id NOT IN (SELECT id FROM users);
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,163 @@
+-- Audit Hash Chain Migration
+
+-- Every audit log entry carries the hash of the previous entry of its
+-- tenant, so removing, reordering or editing an entry breaks the chain.
+-- seq numbers a tenant's entries without gaps. The chain is extended by the
+-- audit_logs_chain trigger, which hashes the values as stored.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGINT;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+-- Deleting a user used to null user_id on their entries, which would break
+-- the chain. Entries now keep the ID of deleted users.
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_user_id_fkey;
+
+-- The last entry of each tenant's chain. anchor_seq and anchor_hash are the
+-- last entry removed by the retention job, which the oldest remaining entry
+-- links to; both are zero until entries have been removed.
+CREATE TABLE IF NOT EXISTS audit_chain_heads (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL DEFAULT 0,
+    hash VARCHAR(64) NOT NULL DEFAULT repeat('0', 64),
+    anchor_seq BIGINT NOT NULL DEFAULT 0,
+    anchor_hash VARCHAR(64) NOT NULL DEFAULT repeat('0', 64),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Signed statements that a tenant's chain ended in hash at seq. A valid
+-- checkpoint proves the entries up to seq existed with exactly these hashes
+-- when it was signed.
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    key_id VARCHAR(16) NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_tenant_seq ON audit_checkpoints(tenant_id, seq DESC);
+
+-- Archives record the last entry they hold, which the oldest remaining
+-- entry of the chain links to.
+ALTER TABLE audit_archives ADD COLUMN IF NOT EXISTS last_seq BIGINT;
+ALTER TABLE audit_archives ADD COLUMN IF NOT EXISTS last_hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_audit_archives_tenant_last_seq ON audit_archives(tenant_id, last_seq);
+
+-- audit_chain_field encodes one hashed value unambiguously: NULL as "~",
+-- anything else prefixed with its length in bytes.
+CREATE OR REPLACE FUNCTION audit_chain_field(value TEXT) RETURNS TEXT AS $$
+    SELECT CASE WHEN value IS NULL THEN '~' ELSE octet_length(value) || ':' || value END;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- audit_log_digest is the hash of an entry. The server recomputes it when
+-- verifying the chain, so any change here must be made there as well.
+CREATE OR REPLACE FUNCTION audit_log_digest(entry audit_logs) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(
+        audit_chain_field(entry.prev_hash) ||
+        audit_chain_field(entry.seq::text) ||
+        audit_chain_field(entry.id::text) ||
+        audit_chain_field(entry.tenant_id::text) ||
+        audit_chain_field(entry.user_id::text) ||
+        audit_chain_field(entry.action) ||
+        audit_chain_field(entry.resource) ||
+        audit_chain_field(entry.resource_id::text) ||
+        audit_chain_field(entry.old_value::text) ||
+        audit_chain_field(entry.new_value::text) ||
+        audit_chain_field(entry.ip_address) ||
+        audit_chain_field(entry.user_agent) ||
+        audit_chain_field(to_char(entry.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')),
+        'UTF8')), 'hex');
+$$ LANGUAGE sql STABLE;
+
+-- Chain the existing entries of each tenant in the order they were written.
+DO $$
+DECLARE
+    entry audit_logs;
+    current_tenant UUID;
+    last_seq BIGINT;
+    last_hash TEXT;
+BEGIN
+    FOR entry IN SELECT * FROM audit_logs ORDER BY tenant_id, created_at, id LOOP
+        IF current_tenant IS DISTINCT FROM entry.tenant_id THEN
+            IF current_tenant IS NOT NULL THEN
+                INSERT INTO audit_chain_heads (tenant_id, seq, hash) VALUES (current_tenant, last_seq, last_hash);
+            END IF;
+            current_tenant := entry.tenant_id;
+            last_seq := 0;
+            last_hash := repeat('0', 64);
+        END IF;
+
+        entry.seq := last_seq + 1;
+        entry.prev_hash := last_hash;
+        entry.hash := audit_log_digest(entry);
+        UPDATE audit_logs SET seq = entry.seq, prev_hash = entry.prev_hash, hash = entry.hash WHERE id = entry.id;
+
+        last_seq := entry.seq;
+        last_hash := entry.hash;
+    END LOOP;
+
+    IF current_tenant IS NOT NULL THEN
+        INSERT INTO audit_chain_heads (tenant_id, seq, hash) VALUES (current_tenant, last_seq, last_hash);
+    END IF;
+END $$;
+
+ALTER TABLE audit_logs ALTER COLUMN seq SET NOT NULL;
+ALTER TABLE audit_logs ALTER COLUMN prev_hash SET NOT NULL;
+ALTER TABLE audit_logs ALTER COLUMN hash SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_tenant_seq ON audit_logs(tenant_id, seq);
+
+-- New entries are appended to their tenant's chain. Locking the head row
+-- serializes concurrent writers of the same tenant.
+CREATE OR REPLACE FUNCTION audit_logs_chain() RETURNS TRIGGER AS $$
+DECLARE
+    head audit_chain_heads;
+BEGIN
+    INSERT INTO audit_chain_heads (tenant_id) VALUES (NEW.tenant_id) ON CONFLICT (tenant_id) DO NOTHING;
+    SELECT * INTO head FROM audit_chain_heads WHERE tenant_id = NEW.tenant_id FOR UPDATE;
+
+    NEW.seq := head.seq + 1;
+    NEW.prev_hash := head.hash;
+    NEW.hash := audit_log_digest(NEW);
+
+    UPDATE audit_chain_heads SET seq = NEW.seq, hash = NEW.hash, updated_at = NOW() WHERE tenant_id = NEW.tenant_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_chain ON audit_logs;
+CREATE TRIGGER audit_logs_chain BEFORE INSERT ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_chain();
+
+-- Audit entries and checkpoints are append-only. Only the retention job,
+-- which sets audit.retention for its transaction, may delete them, and
+-- they go with a deleted tenant.
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF current_setting('audit.retention', true) = 'on' THEN
+            RETURN OLD;
+        END IF;
+        IF NOT EXISTS (SELECT 1 FROM tenants WHERE id = OLD.tenant_id) THEN
+            RETURN OLD;
+        END IF;
+    END IF;
+    RAISE EXCEPTION '% on % is not allowed: the table is append-only', TG_OP, TG_TABLE_NAME
+        USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_append_only();
+
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_append_only();
+
+DROP TRIGGER IF EXISTS audit_checkpoints_append_only ON audit_checkpoints;
+CREATE TRIGGER audit_checkpoints_append_only BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW EXECUTE FUNCTION audit_append_only();
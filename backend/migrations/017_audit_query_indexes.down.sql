@@ -0,0 +1,4 @@
+-- Revert Audit Query Indexes Migration
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_resource_id_created;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_user_created;
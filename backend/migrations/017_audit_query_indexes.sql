@@ -0,0 +1,6 @@
+-- Audit Query Indexes Migration
+
+-- An actor's or a record's history is listed newest first and paginated by
+-- (created_at, id), so both filters get an index in that order.
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_user_created ON audit_logs(tenant_id, user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_resource_id_created ON audit_logs(tenant_id, resource_id, created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+-- Revert Audit Exports Migration
+
+DROP TABLE IF EXISTS audit_exports;
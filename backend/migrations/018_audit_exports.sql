@@ -0,0 +1,26 @@
+-- Audit Exports Migration
+
+-- Exports too large to stream in one request are written to a file by the
+-- audit_exports job. filter holds the audit log filters the export was
+-- requested with; file_name is relative to the configured export directory
+-- and the file is deleted once expires_at has passed.
+CREATE TABLE IF NOT EXISTS audit_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    format VARCHAR(20) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    file_name VARCHAR(255),
+    row_count BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_exports_tenant_created ON audit_exports(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_exports_pending ON audit_exports(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_audit_exports_expires ON audit_exports(expires_at) WHERE expires_at IS NOT NULL;
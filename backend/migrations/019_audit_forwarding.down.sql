@@ -0,0 +1,4 @@
+-- Revert Audit Forwarding Migration
+
+DROP TABLE IF EXISTS audit_outbox;
+DROP TABLE IF EXISTS audit_sinks;
@@ -0,0 +1,41 @@
+-- Audit Forwarding Migration
+
+-- Sinks forward a tenant's audit log entries to external systems such as a
+-- SIEM. config holds the settings of the sink's type; secret signs webhook
+-- deliveries. The last_* columns report the sink's delivery health.
+CREATE TABLE IF NOT EXISTS audit_sinks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('syslog', 'webhook', 'file')),
+    config JSONB NOT NULL DEFAULT '{}',
+    secret VARCHAR(255),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_delivered_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    last_error_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+-- The outbox holds one row per audit log entry and enabled sink until the
+-- entry has been delivered. Rows are written by the statement that inserts
+-- the entry, so no entry is recorded without being queued, and payload is
+-- a copy of the entry so delivery does not depend on it still being in
+-- audit_logs. Rows that exhaust their retries are kept as failed.
+CREATE TABLE IF NOT EXISTS audit_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    sink_id UUID NOT NULL REFERENCES audit_sinks(id) ON DELETE CASCADE,
+    audit_log_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_sinks_tenant ON audit_sinks(tenant_id) WHERE enabled;
+CREATE INDEX IF NOT EXISTS idx_audit_outbox_pending ON audit_outbox(sink_id, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_audit_outbox_failed ON audit_outbox(sink_id) WHERE status = 'failed';
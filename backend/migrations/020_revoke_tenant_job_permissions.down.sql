@@ -0,0 +1,4 @@
+-- Revert Revoke Tenant Job Permissions Migration
+
+-- The revoked grants were never intended, so they are not restored.
+SELECT 1;
@@ -0,0 +1,10 @@
+-- Revoke Tenant Job Permissions Migration
+
+-- jobs:manage is platform-level, but the Admin roles of tenants created
+-- after it was added were granted it along with every other permission.
+DELETE FROM role_permissions rp
+USING roles r, permissions p
+WHERE rp.role_id = r.id
+  AND rp.permission_id = p.id
+  AND p.resource = 'jobs'
+  AND r.tenant_id <> '00000000-0000-0000-0000-000000000001';
@@ -0,0 +1,4 @@
+-- Revert Audit Chain Anchors Migration
+
+DROP TRIGGER IF EXISTS audit_chain_anchors_append_only ON audit_chain_anchors;
+DROP TABLE IF EXISTS audit_chain_anchors;
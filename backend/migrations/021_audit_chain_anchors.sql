@@ -0,0 +1,31 @@
+-- Audit Chain Anchors Migration
+
+-- Every prune by the retention job records the entry the remaining chain
+-- now starts after and the archive the removed entries were written to, so
+-- a chain that no longer starts at entry 1 can be explained. Anchors are
+-- written in the same transaction as the prune and are append-only.
+CREATE TABLE IF NOT EXISTS audit_chain_anchors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    archive_id UUID NOT NULL,
+    pruned_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, seq)
+);
+
+-- Chains pruned before anchors existed start after the last entry of one
+-- of their archives.
+INSERT INTO audit_chain_anchors (tenant_id, seq, hash, archive_id, created_at)
+SELECT DISTINCT ON (h.tenant_id) h.tenant_id, h.anchor_seq, h.anchor_hash, a.id, h.updated_at
+FROM audit_chain_heads h
+JOIN audit_archives a
+    ON a.tenant_id = h.tenant_id AND a.last_seq = h.anchor_seq AND a.last_hash = h.anchor_hash
+WHERE h.anchor_seq > 0
+ORDER BY h.tenant_id, a.created_at DESC
+ON CONFLICT (tenant_id, seq) DO NOTHING;
+
+DROP TRIGGER IF EXISTS audit_chain_anchors_append_only ON audit_chain_anchors;
+CREATE TRIGGER audit_chain_anchors_append_only BEFORE UPDATE OR DELETE ON audit_chain_anchors
+    FOR EACH ROW EXECUTE FUNCTION audit_append_only();
@@ -0,0 +1,37 @@
+-- Revert Audit Prune Role Migration
+
+GRANT audit_pruner TO CURRENT_USER;
+
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF current_setting('audit.retention', true) = 'on' THEN
+            RETURN OLD;
+        END IF;
+        IF NOT EXISTS (SELECT 1 FROM tenants WHERE id = OLD.tenant_id) THEN
+            RETURN OLD;
+        END IF;
+    END IF;
+    RAISE EXCEPTION '% on % is not allowed: the table is append-only', TG_OP, TG_TABLE_NAME
+        USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS audit_prune(UUID, UUID, BIGINT, TEXT);
+
+ALTER TABLE audit_logs OWNER TO CURRENT_USER;
+ALTER TABLE audit_checkpoints OWNER TO CURRENT_USER;
+ALTER TABLE audit_chain_anchors OWNER TO CURRENT_USER;
+
+REVOKE ALL ON audit_logs, audit_checkpoints, audit_chain_anchors, audit_chain_heads, audit_archives FROM audit_pruner;
+
+REVOKE audit_pruner FROM CURRENT_USER;
+
+-- The role is shared by every database of the cluster; keep it while
+-- another one still uses it.
+DO $$
+BEGIN
+    DROP ROLE IF EXISTS audit_pruner;
+EXCEPTION WHEN dependent_objects_still_exist THEN
+    NULL;
+END $$;
@@ -0,0 +1,89 @@
+-- Audit Prune Role Migration
+
+-- The append-only trigger used to let deletes through whenever the session
+-- had set audit.retention, which any session can set. Audit entries,
+-- checkpoints and anchors now belong to audit_pruner, a role nobody logs in
+-- as. The application may read and append to them but not delete; pruning
+-- goes through audit_prune, which runs as audit_pruner. Deleting a tenant
+-- still removes its entries, as cascades run as the owner of the table.
+--
+-- The role running migrations needs CREATEROLE (or to be a superuser).
+-- Later migrations that alter these tables must run as a superuser or a
+-- member of audit_pruner.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'audit_pruner') THEN
+        CREATE ROLE audit_pruner NOLOGIN;
+    END IF;
+END $$;
+
+-- Membership is needed to hand objects over and is given back at the end,
+-- so the application cannot SET ROLE to audit_pruner.
+GRANT audit_pruner TO CURRENT_USER;
+
+ALTER TABLE audit_logs OWNER TO audit_pruner;
+ALTER TABLE audit_checkpoints OWNER TO audit_pruner;
+ALTER TABLE audit_chain_anchors OWNER TO audit_pruner;
+
+REVOKE ALL ON audit_logs, audit_checkpoints, audit_chain_anchors FROM PUBLIC;
+REVOKE DELETE, UPDATE, TRUNCATE ON audit_logs, audit_checkpoints, audit_chain_anchors FROM CURRENT_USER;
+GRANT SELECT, INSERT ON audit_logs, audit_checkpoints, audit_chain_anchors TO CURRENT_USER;
+
+GRANT SELECT, UPDATE ON audit_chain_heads TO audit_pruner;
+GRANT SELECT ON audit_archives TO audit_pruner;
+
+-- audit_prune removes a tenant's entries up to and including through_seq,
+-- whose hash is through_hash, and records that entry as the anchor the
+-- remaining chain starts from. Entries can only be pruned once an archive
+-- holding them has been recorded. It returns how many entries were deleted.
+CREATE OR REPLACE FUNCTION audit_prune(
+    p_tenant_id UUID, p_archive_id UUID, p_through_seq BIGINT, p_through_hash TEXT
+) RETURNS BIGINT AS $$
+DECLARE
+    pruned BIGINT;
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM audit_archives
+        WHERE id = p_archive_id AND tenant_id = p_tenant_id AND last_seq >= p_through_seq
+    ) THEN
+        RAISE EXCEPTION 'archive % does not hold entry % of tenant %', p_archive_id, p_through_seq, p_tenant_id
+            USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    IF EXISTS (
+        SELECT 1 FROM audit_logs
+        WHERE tenant_id = p_tenant_id AND seq = p_through_seq AND hash <> p_through_hash
+    ) THEN
+        RAISE EXCEPTION 'entry % of tenant % does not have hash %', p_through_seq, p_tenant_id, p_through_hash
+            USING ERRCODE = 'data_exception';
+    END IF;
+
+    DELETE FROM audit_logs WHERE tenant_id = p_tenant_id AND seq <= p_through_seq;
+    GET DIAGNOSTICS pruned = ROW_COUNT;
+
+    UPDATE audit_chain_heads SET anchor_seq = p_through_seq, anchor_hash = p_through_hash, updated_at = NOW()
+    WHERE tenant_id = p_tenant_id AND anchor_seq < p_through_seq;
+    IF FOUND THEN
+        INSERT INTO audit_chain_anchors (tenant_id, seq, hash, archive_id, pruned_count)
+        VALUES (p_tenant_id, p_through_seq, p_through_hash, p_archive_id, pruned);
+    END IF;
+    RETURN pruned;
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp;
+
+ALTER FUNCTION audit_prune(UUID, UUID, BIGINT, TEXT) OWNER TO audit_pruner;
+REVOKE ALL ON FUNCTION audit_prune(UUID, UUID, BIGINT, TEXT) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION audit_prune(UUID, UUID, BIGINT, TEXT) TO CURRENT_USER;
+
+-- Only audit_pruner may delete: through audit_prune, or when a tenant's
+-- deletion cascades to its entries.
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_user = 'audit_pruner' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION '% on % is not allowed: the table is append-only', TG_OP, TG_TABLE_NAME
+        USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+REVOKE audit_pruner FROM CURRENT_USER;
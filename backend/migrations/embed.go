@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema migrations so the server binary
+// can apply them without the files being present on disk.
+//
+// Files are named NNN_description.sql. An optional NNN_description.down.sql
+// reverts the migration with the same version.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -59,6 +59,7 @@ export default function DashboardPage() {
     active: { en: 'Active', fa: 'فعال' },
     inactive: { en: 'Inactive', fa: 'غیرفعال' },
     suspended: { en: 'Suspended', fa: 'معلق' },
+    pending: { en: 'Pending', fa: 'در انتظار' },
   };
 
   return (
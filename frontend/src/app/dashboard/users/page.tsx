@@ -22,7 +22,7 @@ export default function UsersPage() {
     password: string;
     first_name: string;
     last_name: string;
-    status: 'active' | 'inactive' | 'suspended';
+    status: 'active' | 'inactive' | 'suspended' | 'pending';
   }>({
     email: '',
     password: '',
@@ -123,7 +123,7 @@ export default function UsersPage() {
           variant={
             user.status === 'active'
               ? 'success'
-              : user.status === 'inactive'
+              : user.status === 'inactive' || user.status === 'pending'
               ? 'secondary'
               : 'destructive'
           }
@@ -209,7 +209,7 @@ export default function UsersPage() {
                     <label className="text-sm font-medium">Status</label>
                     <select
                       value={formData.status}
-                      onChange={(e) => setFormData({ ...formData, status: e.target.value as 'active' | 'inactive' | 'suspended' })}
+                      onChange={(e) => setFormData({ ...formData, status: e.target.value as 'active' | 'inactive' | 'suspended' | 'pending' })}
                       className="w-full rounded-md border border-input bg-transparent px-3 py-2 text-sm"
                     >
                       <option value="active">Active</option>
@@ -212,7 +212,7 @@ export default function LoginForm() {
                     setPassword(p);
                     try {
                       setIsLoading(true);
-                      await login(e, p);
+                      await login(e, p, 'demo');
                     } catch (err) {
                       const isUnauthorized =
                         err instanceof ApiError
@@ -116,10 +116,11 @@ export interface User {
   email: string;
   first_name: string;
   last_name: string;
-  status: 'active' | 'inactive' | 'suspended';
+  status: 'active' | 'inactive' | 'suspended' | 'pending';
   created_at: string;
   updated_at: string;
   last_login_at?: string;
+  locked_until?: string;
 }
 
 export interface Role {
@@ -154,6 +155,98 @@ export interface AuditLog {
   ip_address: string;
   user_agent: string;
   created_at: string;
+  seq: number;
+  prev_hash: string;
+  hash: string;
+  changes?: AuditFieldChange[];
+}
+
+export interface AuditFieldChange {
+  field: string;
+  old: unknown;
+  new: unknown;
+  added?: unknown[];
+  removed?: unknown[];
+}
+
+export interface AuditChainReport {
+  tenant_id: string;
+  valid: boolean;
+  from_seq: number;
+  to_seq: number;
+  entries: number;
+  checkpoints_verified: number;
+  checkpoints_skipped: number;
+  anchor?: AuditChainAnchor;
+  break?: {
+    seq: number;
+    id?: string;
+    reason: string;
+  };
+  verified_at: string;
+}
+
+export interface AuditChainAnchor {
+  id: string;
+  tenant_id: string;
+  seq: number;
+  hash: string;
+  archive_id: string;
+  pruned_count: number;
+  created_at: string;
+}
+
+export interface AuditCheckpoint {
+  id: string;
+  tenant_id: string;
+  seq: number;
+  hash: string;
+  key_id: string;
+  signature: string;
+  created_at: string;
+}
+
+export interface AuditRetentionPolicy {
+  retention_days: number;
+  default_retention_days: number;
+}
+
+export interface AuditArchive {
+  id: string;
+  tenant_id: string;
+  from: string;
+  to: string;
+  row_count: number;
+  size_bytes: number;
+  sha256: string;
+  last_seq?: number;
+  last_hash?: string;
+  created_at: string;
+}
+
+export type AuditExportFormat = 'csv' | 'jsonl' | 'ndjson.gz';
+
+export interface AuditExport {
+  id: string;
+  tenant_id: string;
+  requested_by?: string;
+  format: AuditExportFormat;
+  filter: Omit<AuditLogFilter, 'action'> & { actions?: string[] };
+  status: 'pending' | 'running' | 'completed' | 'failed';
+  row_count: number;
+  size_bytes: number;
+  error?: string;
+  download_url?: string;
+  created_at: string;
+  started_at?: string;
+  finished_at?: string;
+  expires_at?: string;
+}
+
+export interface CursorPage<T> {
+  data: T[];
+  per_page: number;
+  next_cursor?: string;
 }
 
 export interface PaginatedResponse<T> {
@@ -187,9 +280,19 @@ export interface DashboardStats {
   }>;
 }
 
+export interface Session {
+  id: string;
+  ip_address: string;
+  user_agent: string;
+  created_at: string;
+  last_used_at: string;
+  expires_at: string;
+  current: boolean;
+}
+
 export const authApi = {
-  login: (email: string, password: string) =>
-    api.post<LoginResponse>('/api/v1/auth/login', { email, password }),
+  login: (email: string, password: string, tenant?: string) =>
+    api.post<LoginResponse>('/api/v1/auth/login', tenant ? { tenant, email, password } : { email, password }),
   logout: () => api.post('/api/v1/auth/logout'),
   me: () => api.get<{ user_id: string; tenant_id: string; email: string }>('/api/v1/auth/me'),
   refresh: (refreshToken?: string) =>
@@ -197,6 +300,9 @@ export const authApi = {
       '/api/v1/auth/refresh',
       refreshToken ? { refresh_token: refreshToken } : undefined
     ),
+  sessions: () => api.get<Session[]>('/api/v1/auth/sessions'),
+  revokeSession: (id: string) => api.delete(`/api/v1/auth/sessions/${id}`),
+  revokeOtherSessions: () => api.post<{ revoked: number }>('/api/v1/auth/sessions/revoke-others'),
 };
 
 export const usersApi = {
@@ -216,6 +322,10 @@ export const usersApi = {
   delete: (id: string) => api.delete(`/api/v1/users/${id}`),
   resetPassword: (id: string, newPassword: string) =>
     api.post(`/api/v1/users/${id}/reset-password`, { new_password: newPassword }),
+  unlock: (id: string) => api.post(`/api/v1/users/${id}/unlock`),
+  getSessions: (id: string) => api.get<Session[]>(`/api/v1/users/${id}/sessions`),
+  revokeSession: (id: string, sessionId: string) => api.delete(`/api/v1/users/${id}/sessions/${sessionId}`),
+  revokeAllSessions: (id: string) => api.delete(`/api/v1/users/${id}/sessions`),
   getRoles: (id: string) => api.get<Role[]>(`/api/v1/users/${id}/roles`),
 };
 
@@ -240,16 +350,107 @@ export const permissionsApi = {
   list: () => api.get<Permission[]>('/api/v1/permissions'),
 };
 
+export interface AuditLogFilter {
+  search?: string;
+  action?: string[];
+  resource?: string;
+  user_id?: string;
+  resource_id?: string;
+  ip?: string;
+  from?: string;
+  to?: string;
+  old_path?: string;
+  new_path?: string;
+  old?: Record<string, string>;
+  new?: Record<string, string>;
+  sort?: 'created_at' | 'action' | 'resource';
+  order?: 'asc' | 'desc';
+}
+
+function auditFilterParams(filter?: AuditLogFilter): URLSearchParams {
+  const searchParams = new URLSearchParams();
+  if (!filter) return searchParams;
+  const { action, old: oldFields, new: newFields, ...rest } = filter;
+  for (const [key, value] of Object.entries(rest)) {
+    if (typeof value === 'string' && value) searchParams.set(key, value);
+  }
+  action?.forEach((a) => searchParams.append('action', a));
+  Object.entries(oldFields ?? {}).forEach(([field, value]) => searchParams.set(`old.${field}`, value));
+  Object.entries(newFields ?? {}).forEach(([field, value]) => searchParams.set(`new.${field}`, value));
+  return searchParams;
+}
+
 export const auditApi = {
-  list: (params?: { page?: number; per_page?: number; search?: string; action?: string; resource?: string }) => {
-    const searchParams = new URLSearchParams();
+  list: (params?: { page?: number; per_page?: number } & AuditLogFilter) => {
+    const searchParams = auditFilterParams(params);
     if (params?.page) searchParams.set('page', params.page.toString());
     if (params?.per_page) searchParams.set('per_page', params.per_page.toString());
-    if (params?.search) searchParams.set('search', params.search);
-    if (params?.action) searchParams.set('action', params.action);
-    if (params?.resource) searchParams.set('resource', params.resource);
     return api.get<PaginatedResponse<AuditLog>>(`/api/v1/audit-logs?${searchParams}`);
   },
+  listPage: (params?: { cursor?: string; per_page?: number } & AuditLogFilter) => {
+    const searchParams = auditFilterParams(params);
+    searchParams.set('cursor', params?.cursor ?? '');
+    if (params?.per_page) searchParams.set('per_page', params.per_page.toString());
+    return api.get<CursorPage<AuditLog>>(`/api/v1/audit-logs?${searchParams}`);
+  },
+  get: (id: string) => api.get<AuditLog>(`/api/v1/audit-logs/${id}`),
+  verify: () => api.get<AuditChainReport>('/api/v1/audit-logs/verify'),
+  getCheckpoints: () =>
+    api.get<{ key_id: string; public_key: string; checkpoints: AuditCheckpoint[] }>('/api/v1/audit-logs/checkpoints'),
+  getRetention: () => api.get<AuditRetentionPolicy>('/api/v1/audit-logs/retention'),
+  getArchives: () => api.get<AuditArchive[]>('/api/v1/audit-logs/archives'),
+  archiveDownloadUrl: (id: string) => `${API_URL}/api/v1/audit-logs/archives/${id}/download`,
+  exportUrl: (format: AuditExportFormat, filter?: AuditLogFilter) => {
+    const searchParams = auditFilterParams(filter);
+    searchParams.set('format', format);
+    return `${API_URL}/api/v1/audit-logs/export?${searchParams}`;
+  },
+  createExport: (format: AuditExportFormat, filter?: AuditLogFilter) => {
+    const searchParams = auditFilterParams(filter);
+    searchParams.set('format', format);
+    return api.post<AuditExport>(`/api/v1/audit-logs/exports?${searchParams}`);
+  },
+  getExports: () => api.get<AuditExport[]>('/api/v1/audit-logs/exports'),
+  getExport: (id: string) => api.get<AuditExport>(`/api/v1/audit-logs/exports/${id}`),
+  exportDownloadUrl: (id: string) => `${API_URL}/api/v1/audit-logs/exports/${id}/download`,
+};
+
+export type AuditSinkType = 'syslog' | 'webhook' | 'file';
+
+export interface AuditSinkConfig {
+  network?: 'tcp' | 'udp';
+  address?: string;
+  facility?: number;
+  app_name?: string;
+  url?: string;
+  file_name?: string;
+}
+
+export interface AuditSink {
+  id: string;
+  tenant_id: string;
+  name: string;
+  type: AuditSinkType;
+  config: AuditSinkConfig;
+  enabled: boolean;
+  pending: number;
+  failed: number;
+  last_delivered_at?: string;
+  last_error?: string;
+  last_error_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export const auditSinksApi = {
+  list: () => api.get<AuditSink[]>('/api/v1/audit-sinks'),
+  get: (id: string) => api.get<AuditSink>(`/api/v1/audit-sinks/${id}`),
+  create: (data: { name: string; type: AuditSinkType; config: AuditSinkConfig; secret?: string; enabled?: boolean }) =>
+    api.post<AuditSink>('/api/v1/audit-sinks', data),
+  update: (id: string, data: { name?: string; config?: AuditSinkConfig; secret?: string; enabled?: boolean }) =>
+    api.put<AuditSink>(`/api/v1/audit-sinks/${id}`, data),
+  delete: (id: string) => api.delete(`/api/v1/audit-sinks/${id}`),
+  retry: (id: string) => api.post<{ requeued: number }>(`/api/v1/audit-sinks/${id}/retry`),
 };
 
 export const dashboardApi = {
@@ -78,7 +78,7 @@ interface AuthContextType {
   user: User | null;
   isLoading: boolean;
   isAuthenticated: boolean;
-  login: (email: string, password: string) => Promise<void>;
+  login: (email: string, password: string, tenant?: string) => Promise<void>;
   logout: () => Promise<void>;
 }
 
@@ -148,8 +148,8 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     initAuth();
   }, []);
 
-  const login = async (email: string, password: string) => {
-    const response: LoginResponse = await authApi.login(email, password);
+  const login = async (email: string, password: string, tenant?: string) => {
+    const response: LoginResponse = await authApi.login(email, password, tenant);
 
     persistTokenMetadata(response.tokens);
     localStorage.setItem('user', JSON.stringify(response.user));